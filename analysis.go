@@ -0,0 +1,279 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity represents the severity of a diagnostic
+type Severity string
+
+const (
+	// SeverityError marks a definition defect that prevents correct execution
+	SeverityError Severity = "error"
+	// SeverityWarning marks a likely modeling mistake
+	SeverityWarning Severity = "warning"
+	// SeverityInfo marks an observation that may be intentional
+	SeverityInfo Severity = "info"
+)
+
+// DiagnosticCode identifies the kind of problem reported by a diagnostic
+type DiagnosticCode string
+
+const (
+	// DiagnosticMissingInitialMarking is reported when no initial places are known
+	DiagnosticMissingInitialMarking DiagnosticCode = "missing_initial_marking"
+	// DiagnosticUnreachablePlace is reported for places that can never be marked
+	DiagnosticUnreachablePlace DiagnosticCode = "unreachable_place"
+	// DiagnosticDeadTransition is reported for transitions that can never fire
+	DiagnosticDeadTransition DiagnosticCode = "dead_transition"
+	// DiagnosticSinkPlace is reported for non-final places without outgoing transitions
+	DiagnosticSinkPlace DiagnosticCode = "sink_place"
+	// DiagnosticNoIncomingArcs is reported for non-initial places no transition leads to
+	DiagnosticNoIncomingArcs DiagnosticCode = "no_incoming_arcs"
+	// DiagnosticDuplicateTransition is reported when several transitions share a name
+	DiagnosticDuplicateTransition DiagnosticCode = "duplicate_transition"
+	// DiagnosticInvariantViolation is reported when a reachable marking violates an invariant
+	DiagnosticInvariantViolation DiagnosticCode = "invariant_violation"
+	// DiagnosticIncompleteAnalysis is reported when the state space was too large to explore fully
+	DiagnosticIncompleteAnalysis DiagnosticCode = "incomplete_analysis"
+)
+
+// Diagnostic is a single finding of the definition analyzer
+type Diagnostic struct {
+	Code        DiagnosticCode
+	Severity    Severity
+	Message     string
+	Places      []Place
+	Transitions []string
+}
+
+// String returns a human readable representation of the diagnostic
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s [%s]: %s", d.Severity, d.Code, d.Message)
+}
+
+// Analyze inspects a definition and reports structural problems. The initial
+// marking defaults to the definition's initial places.
+//
+// Reachability is computed on the net structure alone, ignoring guards and
+// token conflicts: a place reported unreachable can never be marked and a
+// transition reported dead can never fire, but the converse is not guaranteed.
+// Use CheckSoundness for an exact analysis of the reachable markings.
+func Analyze(definition *Definition, initialPlaces ...Place) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	if len(initialPlaces) == 0 {
+		initialPlaces = definition.InitialPlaces()
+	}
+
+	diagnostics = append(diagnostics, analyzeDuplicateTransitions(definition)...)
+	diagnostics = append(diagnostics, analyzeArcs(definition, initialPlaces)...)
+
+	if len(initialPlaces) == 0 {
+		diagnostics = append(diagnostics, Diagnostic{
+			Code:     DiagnosticMissingInitialMarking,
+			Severity: SeverityWarning,
+			Message:  "no initial places declared, reachability was not analyzed",
+		})
+		return diagnostics
+	}
+
+	diagnostics = append(diagnostics, analyzeReachability(definition, initialPlaces)...)
+	diagnostics = append(diagnostics, analyzeInvariants(definition, initialPlaces)...)
+	return diagnostics
+}
+
+// HasErrors reports whether any diagnostic has error severity
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeDuplicateTransitions reports transition names used more than once
+func analyzeDuplicateTransitions(definition *Definition) []Diagnostic {
+	var diagnostics []Diagnostic
+	counts := make(map[string]int)
+	var order []string
+	for _, t := range definition.transitions {
+		if counts[t.Name()] == 0 {
+			order = append(order, t.Name())
+		}
+		counts[t.Name()]++
+	}
+	for _, name := range order {
+		if counts[name] > 1 {
+			diagnostics = append(diagnostics, Diagnostic{
+				Code:        DiagnosticDuplicateTransition,
+				Severity:    SeverityWarning,
+				Message:     fmt.Sprintf("transition name '%s' is used by %d transitions", name, counts[name]),
+				Transitions: []string{name},
+			})
+		}
+	}
+	return diagnostics
+}
+
+// analyzeArcs reports places without incoming arcs and non-final sink places
+func analyzeArcs(definition *Definition, initialPlaces []Place) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	incoming := make(map[Place]bool)
+	outgoing := make(map[Place]bool)
+	for _, t := range definition.transitions {
+		for _, place := range t.To() {
+			incoming[place] = true
+		}
+		for _, place := range t.From() {
+			outgoing[place] = true
+		}
+	}
+	initial := placeSet(initialPlaces)
+	final := placeSet(definition.FinalPlaces())
+
+	// Without an initial marking every place could be a starting point
+	for _, place := range definition.places {
+		if len(initial) > 0 && !incoming[place] && !initial[place] {
+			diagnostics = append(diagnostics, Diagnostic{
+				Code:     DiagnosticNoIncomingArcs,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("place '%s' has no incoming transitions and is not an initial place", place),
+				Places:   []Place{place},
+			})
+		}
+	}
+
+	// Without declared final places every sink is assumed to be final
+	if len(final) > 0 {
+		for _, place := range definition.places {
+			if !outgoing[place] && !final[place] {
+				diagnostics = append(diagnostics, Diagnostic{
+					Code:     DiagnosticSinkPlace,
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("place '%s' has no outgoing transitions but is not a final place", place),
+					Places:   []Place{place},
+				})
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+// analyzeReachability reports unreachable places and dead transitions using a
+// structural fixed point over the places that may ever be marked
+func analyzeReachability(definition *Definition, initialPlaces []Place) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	marked := placeSet(initialPlaces)
+	fired := make([]bool, len(definition.transitions))
+	for changed := true; changed; {
+		changed = false
+		for i, t := range definition.transitions {
+			if fired[i] || !containsAll(marked, t.From()) {
+				continue
+			}
+			fired[i] = true
+			changed = true
+			for _, place := range t.To() {
+				marked[place] = true
+			}
+		}
+	}
+
+	var unreachable []Place
+	for _, place := range definition.places {
+		if !marked[place] {
+			unreachable = append(unreachable, place)
+			diagnostics = append(diagnostics, Diagnostic{
+				Code:     DiagnosticUnreachablePlace,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("place '%s' is unreachable from the initial marking", place),
+				Places:   []Place{place},
+			})
+		}
+	}
+	for i, t := range definition.transitions {
+		if !fired[i] {
+			diagnostics = append(diagnostics, Diagnostic{
+				Code:        DiagnosticDeadTransition,
+				Severity:    SeverityWarning,
+				Message:     fmt.Sprintf("transition '%s' can never fire, it requires [%s]", t.Name(), joinPlaces(t.From())),
+				Places:      t.From(),
+				Transitions: []string{t.Name()},
+			})
+		}
+	}
+	return diagnostics
+}
+
+// analyzeInvariants checks the declared invariants on every reachable marking
+// and reports the first violation of each invariant with a firing sequence
+func analyzeInvariants(definition *Definition, initialPlaces []Place) []Diagnostic {
+	if len(definition.invariants) == 0 {
+		return nil
+	}
+
+	var diagnostics []Diagnostic
+	g, err := BuildReachabilityGraph(definition, ReachabilityOptions{InitialPlaces: initialPlaces})
+	if g == nil {
+		return nil
+	}
+	if err != nil {
+		diagnostics = append(diagnostics, Diagnostic{
+			Code:     DiagnosticIncompleteAnalysis,
+			Severity: SeverityInfo,
+			Message:  fmt.Sprintf("invariants were only checked on the first %d reachable markings", len(g.Nodes)),
+		})
+	}
+
+	for _, invariant := range definition.invariants {
+		for _, node := range g.Nodes {
+			marking := node.Marking.Places()
+			if err := invariant.Check(marking); err != nil {
+				diagnostics = append(diagnostics, Diagnostic{
+					Code:     DiagnosticInvariantViolation,
+					Severity: SeverityError,
+					Message: fmt.Sprintf("invariant '%s' is violated by marking %s after [%s]: %v",
+						invariant.Name(), node.Marking, strings.Join(g.Path(node.ID), ", "), err),
+					Places:      marking,
+					Transitions: g.Path(node.ID),
+				})
+				break
+			}
+		}
+	}
+	return diagnostics
+}
+
+// placeSet returns a set of the given places
+func placeSet(places []Place) map[Place]bool {
+	set := make(map[Place]bool, len(places))
+	for _, place := range places {
+		set[place] = true
+	}
+	return set
+}
+
+// containsAll reports whether all places are in the set
+func containsAll(set map[Place]bool, places []Place) bool {
+	for _, place := range places {
+		if !set[place] {
+			return false
+		}
+	}
+	return true
+}
+
+// joinPlaces joins places with commas
+func joinPlaces(places []Place) string {
+	names := make([]string, len(places))
+	for i, place := range places {
+		names[i] = string(place)
+	}
+	return strings.Join(names, ", ")
+}
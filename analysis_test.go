@@ -0,0 +1,92 @@
+package workflow_test
+
+import (
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+func TestAnalyze(t *testing.T) {
+	def, err := workflow.NewDefinition(
+		[]workflow.Place{"draft", "review", "approved", "orphan", "stuck", "archived"},
+		[]workflow.Transition{
+			*workflow.MustNewTransition("submit", []workflow.Place{"draft"}, []workflow.Place{"review"}),
+			*workflow.MustNewTransition("approve", []workflow.Place{"review"}, []workflow.Place{"approved"}),
+			*workflow.MustNewTransition("approve", []workflow.Place{"review"}, []workflow.Place{"stuck"}),
+			*workflow.MustNewTransition("archive", []workflow.Place{"orphan"}, []workflow.Place{"archived"}),
+		},
+		workflow.WithInitialPlaces("draft"),
+		workflow.WithFinalPlaces("approved", "archived"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create definition: %v", err)
+	}
+
+	got := make(map[workflow.DiagnosticCode][]string)
+	for _, d := range workflow.Analyze(def) {
+		subject := ""
+		if len(d.Transitions) > 0 {
+			subject = d.Transitions[0]
+		} else if len(d.Places) > 0 {
+			subject = string(d.Places[0])
+		}
+		got[d.Code] = append(got[d.Code], subject)
+	}
+
+	want := map[workflow.DiagnosticCode][]string{
+		workflow.DiagnosticDuplicateTransition: {"approve"},
+		workflow.DiagnosticNoIncomingArcs:      {"orphan"},
+		workflow.DiagnosticSinkPlace:           {"stuck"},
+		workflow.DiagnosticUnreachablePlace:    {"orphan", "archived"},
+		workflow.DiagnosticDeadTransition:      {"archive"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Analyze() codes = %v, want %v", got, want)
+	}
+	for code, subjects := range want {
+		if len(got[code]) != len(subjects) {
+			t.Errorf("Analyze() %s = %v, want %v", code, got[code], subjects)
+			continue
+		}
+		for i := range subjects {
+			if got[code][i] != subjects[i] {
+				t.Errorf("Analyze() %s = %v, want %v", code, got[code], subjects)
+			}
+		}
+	}
+}
+
+func TestAnalyze_JoinRequiresAllInputs(t *testing.T) {
+	def, err := workflow.NewDefinition(
+		[]workflow.Place{"start", "a", "b", "end"},
+		[]workflow.Transition{
+			*workflow.MustNewTransition("to-a", []workflow.Place{"start"}, []workflow.Place{"a"}),
+			*workflow.MustNewTransition("join", []workflow.Place{"a", "b"}, []workflow.Place{"end"}),
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to create definition: %v", err)
+	}
+
+	diagnostics := workflow.Analyze(def, "start")
+	dead := false
+	for _, d := range diagnostics {
+		if d.Code == workflow.DiagnosticDeadTransition && d.Transitions[0] == "join" {
+			dead = true
+		}
+	}
+	if !dead {
+		t.Errorf("Analyze() = %v, want join reported as dead", diagnostics)
+	}
+}
+
+func TestAnalyze_MissingInitialMarking(t *testing.T) {
+	def, err := workflow.NewDefinition([]workflow.Place{"start"}, []workflow.Transition{})
+	if err != nil {
+		t.Fatalf("failed to create definition: %v", err)
+	}
+	diagnostics := workflow.Analyze(def)
+	if len(diagnostics) != 1 || diagnostics[0].Code != workflow.DiagnosticMissingInitialMarking {
+		t.Errorf("Analyze() = %v, want a single missing initial marking diagnostic", diagnostics)
+	}
+}
@@ -0,0 +1,731 @@
+package workflow
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// MetadataBPMNType is the place metadata key holding the BPMN element a place
+// is imported from or exported to, such as "userTask" or "serviceTask"
+const MetadataBPMNType = "bpmn_type"
+
+// BPMN elements supported by the importer and exporter
+const (
+	bpmnStartEvent       = "startEvent"
+	bpmnEndEvent         = "endEvent"
+	bpmnTask             = "task"
+	bpmnUserTask         = "userTask"
+	bpmnServiceTask      = "serviceTask"
+	bpmnExclusiveGateway = "exclusiveGateway"
+	bpmnParallelGateway  = "parallelGateway"
+	bpmnSequenceFlow     = "sequenceFlow"
+)
+
+// bpmnIgnored lists the process elements that have no effect on the definition
+var bpmnIgnored = map[string]bool{
+	"documentation":     true,
+	"extensionElements": true,
+	"laneSet":           true,
+	"textAnnotation":    true,
+	"association":       true,
+}
+
+// bpmnElement is a flow element of a BPMN process
+type bpmnElement struct {
+	node *xmlNode
+	kind string
+
+	ID        string
+	Name      string
+	SourceRef string
+	TargetRef string
+	Default   string
+}
+
+// newBPMNElement reads the attributes of a flow element
+func newBPMNElement(node *xmlNode) *bpmnElement {
+	return &bpmnElement{
+		node:      node,
+		kind:      node.name,
+		ID:        node.attrs["id"],
+		Name:      node.attrs["name"],
+		SourceRef: node.attrs["sourceRef"],
+		TargetRef: node.attrs["targetRef"],
+		Default:   node.attrs["default"],
+	}
+}
+
+// errorf returns a *LoadError located at the element
+func (e *bpmnElement) errorf(format string, args ...interface{}) error {
+	return e.node.errorf(format, args...)
+}
+
+// isPlace reports whether the element is imported as a place
+func (e *bpmnElement) isPlace() bool {
+	switch e.kind {
+	case bpmnStartEvent, bpmnEndEvent, bpmnTask, bpmnUserTask, bpmnServiceTask:
+		return true
+	}
+	return false
+}
+
+// isGateway reports whether the element is a supported gateway
+func (e *bpmnElement) isGateway() bool {
+	return e.kind == bpmnExclusiveGateway || e.kind == bpmnParallelGateway
+}
+
+// label returns the name of the element, or its ID when it has none
+func (e *bpmnElement) label() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.ID
+}
+
+// bpmnProcess is a parsed BPMN process
+type bpmnProcess struct {
+	id       string
+	name     string
+	elements []*bpmnElement
+	byID     map[string]*bpmnElement
+	incoming map[string][]*bpmnElement
+	outgoing map[string][]*bpmnElement
+}
+
+// ImportBPMN imports the process of a BPMN 2.0 XML document. Start events, end
+// events and tasks become places, and sequence flows between them become
+// transitions. A path through an exclusive gateway becomes a transition, and a
+// parallel gateway becomes a single transition from all its incoming places to
+// all its outgoing places. Flow conditions are parsed as guard expressions,
+// optionally wrapped in ${...}. Other elements are rejected with a *LoadError
+// wrapping ErrUnsupportedElement.
+func ImportBPMN(data []byte) (*Definition, error) {
+	process, err := parseBPMN(data)
+	if err != nil {
+		return nil, err
+	}
+	return process.definition()
+}
+
+// parseBPMN reads the single process of a BPMN document
+func parseBPMN(data []byte) (*bpmnProcess, error) {
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	if root.name != "definitions" {
+		return nil, root.errorf("expected a BPMN definitions element, got %q", root.name)
+	}
+
+	var process *bpmnProcess
+	for _, child := range root.children {
+		// Collaborations, messages and diagrams do not affect the definition
+		if child.name != "process" {
+			continue
+		}
+		if process != nil {
+			return nil, child.errorf("only one process is supported")
+		}
+		if process, err = parseBPMNProcess(child); err != nil {
+			return nil, err
+		}
+	}
+	if process == nil {
+		return nil, &LoadError{Err: fmt.Errorf("no BPMN process found")}
+	}
+	return process, nil
+}
+
+// parseBPMNProcess reads the flow elements of a process
+func parseBPMNProcess(node *xmlNode) (*bpmnProcess, error) {
+	process := &bpmnProcess{
+		id:       node.attrs["id"],
+		name:     node.attrs["name"],
+		byID:     make(map[string]*bpmnElement),
+		incoming: make(map[string][]*bpmnElement),
+		outgoing: make(map[string][]*bpmnElement),
+	}
+	for _, child := range node.children {
+		if bpmnIgnored[child.name] {
+			continue
+		}
+		if err := process.add(newBPMNElement(child)); err != nil {
+			return nil, err
+		}
+	}
+	return process, nil
+}
+
+// add validates a flow element and adds it to the process
+func (p *bpmnProcess) add(e *bpmnElement) error {
+	if !e.isPlace() && !e.isGateway() && e.kind != bpmnSequenceFlow {
+		return e.errorf("%w %q", ErrUnsupportedElement, e.kind)
+	}
+	for _, child := range e.node.children {
+		if strings.HasSuffix(child.name, "EventDefinition") {
+			return e.errorf("%w %q in %s '%s'", ErrUnsupportedElement, child.name, e.kind, e.ID)
+		}
+	}
+	if e.ID == "" {
+		return e.errorf("%s has no id", e.kind)
+	}
+	if _, ok := p.byID[e.ID]; ok {
+		return e.errorf("duplicate id '%s'", e.ID)
+	}
+	p.byID[e.ID] = e
+	p.elements = append(p.elements, e)
+	return nil
+}
+
+// definition converts the process to a definition
+func (p *bpmnProcess) definition() (*Definition, error) {
+	name := p.name
+	if name == "" {
+		name = p.id
+	}
+	opts := []DefinitionOption{WithName(name)}
+
+	var places, initial, final []Place
+	for _, e := range p.elements {
+		if !e.isPlace() {
+			continue
+		}
+		place := Place(e.ID)
+		places = append(places, place)
+		metadata := map[string]interface{}{MetadataBPMNType: e.kind}
+		if e.Name != "" && e.Name != e.ID {
+			metadata[MetadataLabel] = e.Name
+		}
+		opts = append(opts, WithPlaceMetadata(place, metadata))
+		switch e.kind {
+		case bpmnStartEvent:
+			initial = append(initial, place)
+		case bpmnEndEvent:
+			final = append(final, place)
+		}
+	}
+	opts = append(opts, WithInitialPlaces(initial...), WithFinalPlaces(final...))
+
+	// Index the flows by their source and target
+	var flows []*bpmnElement
+	for _, e := range p.elements {
+		if e.kind != bpmnSequenceFlow {
+			continue
+		}
+		for _, ref := range []string{e.SourceRef, e.TargetRef} {
+			if target, ok := p.byID[ref]; !ok || target.kind == bpmnSequenceFlow {
+				return nil, e.errorf("sequence flow '%s' refers to unknown element '%s'", e.ID, ref)
+			}
+		}
+		flows = append(flows, e)
+		p.outgoing[e.SourceRef] = append(p.outgoing[e.SourceRef], e)
+		p.incoming[e.TargetRef] = append(p.incoming[e.TargetRef], e)
+	}
+	for _, e := range p.elements {
+		if e.isGateway() && (len(p.incoming[e.ID]) == 0 || len(p.outgoing[e.ID]) == 0) {
+			return nil, e.errorf("gateway '%s' needs incoming and outgoing flows", e.ID)
+		}
+	}
+	guards, err := p.guards()
+	if err != nil {
+		return nil, err
+	}
+
+	var transitions []Transition
+	addTransition := func(at *bpmnElement, name string, from, to []Place, constraints []Constraint) error {
+		t, err := NewTransition(name, from, to)
+		if err != nil {
+			return at.errorf("%v", err)
+		}
+		for _, c := range constraints {
+			t.AddConstraint(c)
+		}
+		transitions = append(transitions, *t)
+		return nil
+	}
+
+	// Transitions are added in the order of the flows, flows into gateways
+	// being handled with the outgoing flows of the gateway
+	parallel := make(map[string]bool)
+	for _, f := range flows {
+		source, target := p.byID[f.SourceRef], p.byID[f.TargetRef]
+		switch {
+		case source.isPlace() && target.isPlace():
+			err = addTransition(f, f.label(), []Place{Place(source.ID)}, []Place{Place(target.ID)}, guards[f])
+		case source.kind == bpmnExclusiveGateway:
+			if !target.isPlace() {
+				return nil, f.errorf("chained gateways are not supported")
+			}
+			for _, in := range p.incoming[source.ID] {
+				from := p.byID[in.SourceRef]
+				if !from.isPlace() {
+					return nil, in.errorf("chained gateways are not supported")
+				}
+				constraints := append(append([]Constraint{}, guards[in]...), guards[f]...)
+				if err = addTransition(f, f.label(), []Place{Place(from.ID)}, []Place{Place(target.ID)}, constraints); err != nil {
+					break
+				}
+			}
+		case source.kind == bpmnParallelGateway && !parallel[source.ID]:
+			parallel[source.ID] = true
+			var from, to []Place
+			for _, in := range p.incoming[source.ID] {
+				from = append(from, Place(in.SourceRef))
+			}
+			for _, out := range p.outgoing[source.ID] {
+				to = append(to, Place(out.TargetRef))
+			}
+			for _, flow := range append(append([]*bpmnElement{}, p.incoming[source.ID]...), p.outgoing[source.ID]...) {
+				if !p.byID[flow.SourceRef].isPlace() && !p.byID[flow.TargetRef].isPlace() {
+					return nil, flow.errorf("chained gateways are not supported")
+				}
+				if len(guards[flow]) > 0 {
+					return nil, flow.errorf("conditions on parallel gateway flows are not supported")
+				}
+			}
+			err = addTransition(source, source.label(), from, to, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	definition, err := NewDefinition(places, transitions, opts...)
+	if err != nil {
+		return nil, &LoadError{Err: err}
+	}
+	return definition, nil
+}
+
+// guards parses the flow conditions. The default flow of an element is
+// guarded by the negation of the conditions of the other outgoing flows.
+func (p *bpmnProcess) guards() (map[*bpmnElement][]Constraint, error) {
+	guards := make(map[*bpmnElement][]Constraint)
+	for _, e := range p.elements {
+		var conditions []string
+		var defaultFlow *bpmnElement
+		for _, f := range p.outgoing[e.ID] {
+			if f.ID == e.Default {
+				defaultFlow = f
+				continue
+			}
+			condition := f.node.child("conditionExpression")
+			if condition == nil {
+				continue
+			}
+			source := bpmnCondition(condition.text)
+			if source == "" {
+				continue
+			}
+			guard, err := NewExpressionConstraint(source)
+			if err != nil {
+				return nil, f.errorf("invalid condition: %v", err)
+			}
+			guards[f] = []Constraint{guard}
+			conditions = append(conditions, source)
+		}
+		if e.Default == "" {
+			continue
+		}
+		if defaultFlow == nil {
+			return nil, e.errorf("default flow '%s' is not an outgoing flow of '%s'", e.Default, e.ID)
+		}
+		if len(conditions) > 0 {
+			guard, err := NewExpressionConstraint("not ((" + strings.Join(conditions, ") or (") + "))")
+			if err != nil {
+				return nil, defaultFlow.errorf("invalid condition: %v", err)
+			}
+			guards[defaultFlow] = []Constraint{guard}
+		}
+	}
+	return guards, nil
+}
+
+// bpmnCondition returns the expression of a flow condition, without the ${...}
+// or #{...} wrapper used by process engines
+func bpmnCondition(text string) string {
+	text = strings.TrimSpace(text)
+	if (strings.HasPrefix(text, "${") || strings.HasPrefix(text, "#{")) && strings.HasSuffix(text, "}") {
+		text = strings.TrimSpace(text[2 : len(text)-1])
+	}
+	return text
+}
+
+// BPMN namespaces written by ExportBPMN
+const (
+	bpmnModelNamespace = "http://www.omg.org/spec/BPMN/20100524/MODEL"
+	bpmnDINamespace    = "http://www.omg.org/spec/BPMN/20100524/DI"
+	bpmnDCNamespace    = "http://www.omg.org/spec/DD/20100524/DC"
+	bpmnDDNamespace    = "http://www.omg.org/spec/DD/20100524/DI"
+	xsiNamespace       = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// bpmnDocumentXML is the exported form of a BPMN document
+type bpmnDocumentXML struct {
+	XMLName         xml.Name       `xml:"bpmn:definitions"`
+	BPMN            string         `xml:"xmlns:bpmn,attr"`
+	BPMNDI          string         `xml:"xmlns:bpmndi,attr"`
+	DC              string         `xml:"xmlns:dc,attr"`
+	DI              string         `xml:"xmlns:di,attr"`
+	XSI             string         `xml:"xmlns:xsi,attr"`
+	ID              string         `xml:"id,attr"`
+	TargetNamespace string         `xml:"targetNamespace,attr"`
+	Process         bpmnProcessXML `xml:"bpmn:process"`
+	Diagram         bpmnDiagramXML `xml:"bpmndi:BPMNDiagram"`
+}
+
+// bpmnProcessXML is the exported form of a process
+type bpmnProcessXML struct {
+	ID           string `xml:"id,attr"`
+	Name         string `xml:"name,attr,omitempty"`
+	IsExecutable string `xml:"isExecutable,attr"`
+	Elements     []bpmnElementXML
+}
+
+// bpmnElementXML is the exported form of a flow element
+type bpmnElementXML struct {
+	XMLName   xml.Name
+	ID        string            `xml:"id,attr"`
+	Name      string            `xml:"name,attr,omitempty"`
+	SourceRef string            `xml:"sourceRef,attr,omitempty"`
+	TargetRef string            `xml:"targetRef,attr,omitempty"`
+	Condition *bpmnConditionXML `xml:"bpmn:conditionExpression"`
+}
+
+// bpmnConditionXML is the exported form of a flow condition
+type bpmnConditionXML struct {
+	Type string `xml:"xsi:type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// bpmnDiagramXML holds the diagram interchange coordinates
+type bpmnDiagramXML struct {
+	ID    string       `xml:"id,attr"`
+	Plane bpmnPlaneXML `xml:"bpmndi:BPMNPlane"`
+}
+
+// bpmnPlaneXML holds the shapes and edges of a process
+type bpmnPlaneXML struct {
+	ID      string         `xml:"id,attr"`
+	Element string         `xml:"bpmnElement,attr"`
+	Shapes  []bpmnShapeXML `xml:"bpmndi:BPMNShape"`
+	Edges   []bpmnEdgeXML  `xml:"bpmndi:BPMNEdge"`
+}
+
+// bpmnShapeXML is the position of a flow node
+type bpmnShapeXML struct {
+	ID      string        `xml:"id,attr"`
+	Element string        `xml:"bpmnElement,attr"`
+	Bounds  bpmnBoundsXML `xml:"dc:Bounds"`
+}
+
+// bpmnBoundsXML is the bounding box of a shape
+type bpmnBoundsXML struct {
+	X      int `xml:"x,attr"`
+	Y      int `xml:"y,attr"`
+	Width  int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+}
+
+// bpmnEdgeXML is the route of a sequence flow
+type bpmnEdgeXML struct {
+	ID        string         `xml:"id,attr"`
+	Element   string         `xml:"bpmnElement,attr"`
+	Waypoints []bpmnPointXML `xml:"di:waypoint"`
+}
+
+// bpmnPointXML is a waypoint of an edge
+type bpmnPointXML struct {
+	X int `xml:"x,attr"`
+	Y int `xml:"y,attr"`
+}
+
+// ExportBPMN exports a definition as a BPMN 2.0 process with diagram
+// interchange coordinates. Places become user tasks, or the element named by
+// their MetadataBPMNType metadata. Initial places without incoming transitions
+// become start events, and final places without outgoing transitions end
+// events. Transitions between several places become parallel gateways.
+// Exporting fails for constraints other than guard expressions, and for guards
+// on transitions that need a gateway.
+func ExportBPMN(definition *Definition) ([]byte, error) {
+	ids := make(xmlIDs)
+	placeIDs := make(map[Place]string)
+	for _, place := range definition.places {
+		placeIDs[place] = ids.unique(string(place))
+	}
+	hasIncoming := make(map[Place]bool)
+	hasOutgoing := make(map[Place]bool)
+	for _, t := range definition.transitions {
+		for _, place := range t.From() {
+			hasOutgoing[place] = true
+		}
+		for _, place := range t.To() {
+			hasIncoming[place] = true
+		}
+	}
+	initial := placeSet(definition.initialPlaces)
+	final := placeSet(definition.finalPlaces)
+
+	processID := definition.name
+	if processID == "" {
+		processID = "Process_1"
+	}
+	process := bpmnProcessXML{ID: ids.unique(processID), Name: definition.name, IsExecutable: "false"}
+	var nodes, flows []bpmnElementXML
+	layout := newBPMNLayout()
+
+	for _, place := range definition.places {
+		kind, _ := metadataString(definition.placeMetadata[place], MetadataBPMNType)
+		switch {
+		case kind != "":
+		case initial[place] && !hasIncoming[place]:
+			kind = bpmnStartEvent
+		case final[place] && !hasOutgoing[place]:
+			kind = bpmnEndEvent
+		default:
+			kind = bpmnUserTask
+		}
+		name := string(place)
+		if label, ok := metadataString(definition.placeMetadata[place], MetadataLabel); ok {
+			name = label
+		}
+		nodes = append(nodes, bpmnElementXML{XMLName: bpmnName(kind), ID: placeIDs[place], Name: name})
+		layout.node(placeIDs[place], kind, initial[place])
+	}
+
+	flow := func(id, name, source, target string, condition *bpmnConditionXML) {
+		flows = append(flows, bpmnElementXML{
+			XMLName:   bpmnName(bpmnSequenceFlow),
+			ID:        id,
+			Name:      name,
+			SourceRef: source,
+			TargetRef: target,
+			Condition: condition,
+		})
+		layout.edge(id, source, target)
+	}
+	for _, t := range definition.transitions {
+		guard, ok := guardExpression(&t)
+		if !ok {
+			return nil, fmt.Errorf("transition '%s' has a constraint that cannot be exported to BPMN", t.Name())
+		}
+		if len(t.From()) == 1 && len(t.To()) == 1 {
+			var condition *bpmnConditionXML
+			if guard != "" {
+				condition = &bpmnConditionXML{Type: "bpmn:tFormalExpression", Text: guard}
+			}
+			flow(ids.unique(t.Name()), t.Name(), placeIDs[t.From()[0]], placeIDs[t.To()[0]], condition)
+			continue
+		}
+		if guard != "" {
+			return nil, fmt.Errorf("transition '%s' joins or forks places and cannot have a guard in BPMN", t.Name())
+		}
+		gateway := ids.unique("Gateway_" + t.Name())
+		nodes = append(nodes, bpmnElementXML{XMLName: bpmnName(bpmnParallelGateway), ID: gateway, Name: t.Name()})
+		layout.node(gateway, bpmnParallelGateway, false)
+		for _, place := range t.From() {
+			flow(ids.unique(t.Name()+"_in"), "", placeIDs[place], gateway, nil)
+		}
+		for _, place := range t.To() {
+			flow(ids.unique(t.Name()+"_out"), "", gateway, placeIDs[place], nil)
+		}
+	}
+	process.Elements = append(nodes, flows...)
+
+	shapes, edges := layout.diagram()
+	doc := bpmnDocumentXML{
+		BPMN:            bpmnModelNamespace,
+		BPMNDI:          bpmnDINamespace,
+		DC:              bpmnDCNamespace,
+		DI:              bpmnDDNamespace,
+		XSI:             xsiNamespace,
+		ID:              ids.unique("Definitions_1"),
+		TargetNamespace: "http://bpmn.io/schema/bpmn",
+		Process:         process,
+		Diagram: bpmnDiagramXML{
+			ID: ids.unique("BPMNDiagram_1"),
+			Plane: bpmnPlaneXML{
+				ID:      ids.unique("BPMNPlane_1"),
+				Element: process.ID,
+				Shapes:  shapes,
+				Edges:   edges,
+			},
+		},
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(xml.Header), data...), '\n'), nil
+}
+
+// bpmnName returns the prefixed XML name of a BPMN element
+func bpmnName(kind string) xml.Name {
+	return xml.Name{Local: "bpmn:" + kind}
+}
+
+// xmlIDs allocates unique XML IDs
+type xmlIDs map[string]bool
+
+// unique returns s as a valid XML ID, adding a numeric suffix when it is taken
+func (ids xmlIDs) unique(s string) string {
+	base := []rune(s)
+	for i, r := range base {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.' {
+			base[i] = '_'
+		}
+	}
+	id := string(base)
+	if id == "" || !unicode.IsLetter(base[0]) && base[0] != '_' {
+		id = "_" + id
+	}
+	candidate := id
+	for i := 2; ids[candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", id, i)
+	}
+	ids[candidate] = true
+	return candidate
+}
+
+// bpmnLayout places the nodes of an exported process in columns, by distance
+// from the start nodes
+type bpmnLayout struct {
+	ids   []string
+	kinds map[string]string
+	start map[string]bool
+	edges []bpmnLayoutEdge
+}
+
+// bpmnLayoutEdge is a sequence flow to route
+type bpmnLayoutEdge struct {
+	id, source, target string
+}
+
+func newBPMNLayout() *bpmnLayout {
+	return &bpmnLayout{kinds: make(map[string]string), start: make(map[string]bool)}
+}
+
+// node adds a flow node
+func (l *bpmnLayout) node(id, kind string, start bool) {
+	l.ids = append(l.ids, id)
+	l.kinds[id] = kind
+	l.start[id] = start
+}
+
+// edge adds a sequence flow
+func (l *bpmnLayout) edge(id, source, target string) {
+	l.edges = append(l.edges, bpmnLayoutEdge{id: id, source: source, target: target})
+}
+
+// Layout grid, in diagram units
+const (
+	bpmnColumnWidth = 160
+	bpmnRowHeight   = 120
+	bpmnMargin      = 100
+)
+
+// diagram computes the shapes and edges of the process
+func (l *bpmnLayout) diagram() ([]bpmnShapeXML, []bpmnEdgeXML) {
+	successors := make(map[string][]string)
+	hasIncoming := make(map[string]bool)
+	for _, e := range l.edges {
+		successors[e.source] = append(successors[e.source], e.target)
+		hasIncoming[e.target] = true
+	}
+
+	// Breadth-first search from the start nodes, or from the nodes without
+	// incoming flows when no place is initial
+	column := make(map[string]int)
+	var queue []string
+	for _, id := range l.ids {
+		if l.start[id] {
+			column[id] = 0
+			queue = append(queue, id)
+		}
+	}
+	if len(queue) == 0 {
+		for _, id := range l.ids {
+			if !hasIncoming[id] {
+				column[id] = 0
+				queue = append(queue, id)
+			}
+		}
+	}
+	last := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range successors[id] {
+			if _, ok := column[next]; !ok {
+				column[next] = column[id] + 1
+				if column[next] > last {
+					last = column[next]
+				}
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	centers := make(map[string]bpmnPointXML)
+	rows := make(map[int]int)
+	shapes := make([]bpmnShapeXML, 0, len(l.ids))
+	for _, id := range l.ids {
+		c, ok := column[id]
+		if !ok {
+			// Unreachable nodes go after the reachable ones
+			last++
+			c = last
+		}
+		center := bpmnPointXML{X: bpmnMargin + c*bpmnColumnWidth, Y: bpmnMargin + rows[c]*bpmnRowHeight}
+		rows[c]++
+		centers[id] = center
+		width, height := bpmnShapeSize(l.kinds[id])
+		shapes = append(shapes, bpmnShapeXML{
+			ID:      id + "_di",
+			Element: id,
+			Bounds:  bpmnBoundsXML{X: center.X - width/2, Y: center.Y - height/2, Width: width, Height: height},
+		})
+	}
+
+	edges := make([]bpmnEdgeXML, 0, len(l.edges))
+	for _, e := range l.edges {
+		source, target := centers[e.source], centers[e.target]
+		sourceWidth, sourceHeight := bpmnShapeSize(l.kinds[e.source])
+		targetWidth, targetHeight := bpmnShapeSize(l.kinds[e.target])
+		var waypoints []bpmnPointXML
+		if target.X > source.X {
+			waypoints = []bpmnPointXML{
+				{X: source.X + sourceWidth/2, Y: source.Y},
+				{X: target.X - targetWidth/2, Y: target.Y},
+			}
+		} else {
+			// Route backward flows below both nodes
+			below := source.Y + sourceHeight/2
+			if bottom := target.Y + targetHeight/2; bottom > below {
+				below = bottom
+			}
+			below += bpmnRowHeight / 3
+			waypoints = []bpmnPointXML{
+				{X: source.X, Y: source.Y + sourceHeight/2},
+				{X: source.X, Y: below},
+				{X: target.X, Y: below},
+				{X: target.X, Y: target.Y + targetHeight/2},
+			}
+		}
+		edges = append(edges, bpmnEdgeXML{ID: e.id + "_di", Element: e.id, Waypoints: waypoints})
+	}
+	return shapes, edges
+}
+
+// bpmnShapeSize returns the usual size of a BPMN element
+func bpmnShapeSize(kind string) (width, height int) {
+	switch kind {
+	case bpmnStartEvent, bpmnEndEvent:
+		return 36, 36
+	case bpmnExclusiveGateway, bpmnParallelGateway:
+		return 50, 50
+	}
+	return 100, 80
+}
@@ -0,0 +1,203 @@
+package workflow_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+const orderBPMN = `<?xml version="1.0" encoding="UTF-8"?>
+<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" id="Definitions_1">
+  <bpmn:process id="Process_order" name="order" isExecutable="true">
+    <bpmn:documentation>Order handling</bpmn:documentation>
+    <bpmn:startEvent id="received" name="Order received" />
+    <bpmn:userTask id="review" name="Review order" />
+    <bpmn:exclusiveGateway id="decision" default="reject" />
+    <bpmn:serviceTask id="charge" name="Charge card" />
+    <bpmn:task id="pack" />
+    <bpmn:parallelGateway id="fulfil" name="fulfil" />
+    <bpmn:parallelGateway id="complete" name="complete" />
+    <bpmn:endEvent id="done" />
+    <bpmn:endEvent id="rejected" />
+    <bpmn:sequenceFlow id="Flow_1" name="start" sourceRef="received" targetRef="review" />
+    <bpmn:sequenceFlow id="Flow_2" sourceRef="review" targetRef="decision" />
+    <bpmn:sequenceFlow id="approve" sourceRef="decision" targetRef="fulfil_start">
+      <bpmn:conditionExpression xsi:type="bpmn:tFormalExpression">${context.amount &lt;= 1000}</bpmn:conditionExpression>
+    </bpmn:sequenceFlow>
+    <bpmn:sequenceFlow id="reject" sourceRef="decision" targetRef="rejected" />
+    <bpmn:userTask id="fulfil_start" />
+    <bpmn:sequenceFlow id="Flow_3" sourceRef="fulfil_start" targetRef="fulfil" />
+    <bpmn:sequenceFlow id="Flow_4" sourceRef="fulfil" targetRef="charge" />
+    <bpmn:sequenceFlow id="Flow_5" sourceRef="fulfil" targetRef="pack" />
+    <bpmn:sequenceFlow id="Flow_6" sourceRef="charge" targetRef="complete" />
+    <bpmn:sequenceFlow id="Flow_7" sourceRef="pack" targetRef="complete" />
+    <bpmn:sequenceFlow id="Flow_8" sourceRef="complete" targetRef="done" />
+  </bpmn:process>
+</bpmn:definitions>
+`
+
+func TestImportBPMN(t *testing.T) {
+	def, err := workflow.ImportBPMN([]byte(orderBPMN))
+	if err != nil {
+		t.Fatalf("ImportBPMN() error = %v", err)
+	}
+	if def.Name() != "order" {
+		t.Errorf("name = %q", def.Name())
+	}
+	if got := def.InitialPlaces(); len(got) != 1 || got[0] != "received" {
+		t.Errorf("initial places = %v", got)
+	}
+	if got := def.FinalPlaces(); len(got) != 2 {
+		t.Errorf("final places = %v", got)
+	}
+	if label, _ := def.PlaceMetadata("review", workflow.MetadataLabel); label != "Review order" {
+		t.Errorf("review label = %v", label)
+	}
+	if kind, _ := def.PlaceMetadata("charge", workflow.MetadataBPMNType); kind != "serviceTask" {
+		t.Errorf("charge type = %v", kind)
+	}
+
+	var names []string
+	for _, tr := range def.AllTransitions() {
+		names = append(names, tr.Name())
+	}
+	if got := strings.Join(names, ","); got != "start,approve,reject,fulfil,complete" {
+		t.Errorf("transitions = %v", got)
+	}
+	if fulfil := def.Transition("fulfil"); len(fulfil.To()) != 2 {
+		t.Errorf("fulfil should fork, got %v", fulfil.To())
+	}
+
+	wf, err := workflow.NewWorkflow("o1", def, "received")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	if err := wf.Apply([]workflow.Place{"review"}); err != nil {
+		t.Fatalf("Apply(review) error = %v", err)
+	}
+	wf.SetContext("amount", 5000)
+	if err := wf.Can([]workflow.Place{"fulfil_start"}); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("expected the condition to block, got %v", err)
+	}
+	// The default flow is taken when no other condition holds
+	if err := wf.Can([]workflow.Place{"rejected"}); err != nil {
+		t.Errorf("Can(rejected) error = %v", err)
+	}
+	wf.SetContext("amount", 10)
+	if err := wf.Can([]workflow.Place{"rejected"}); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("expected the default flow to be blocked, got %v", err)
+	}
+}
+
+func TestImportBPMN_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		process string
+		line    int
+		message string
+	}{
+		{
+			name:    "unsupported element",
+			process: `<inclusiveGateway id="g" />`,
+			line:    3,
+			message: `unsupported element "inclusiveGateway"`,
+		},
+		{
+			name:    "event definition",
+			process: "<startEvent id=\"s\">\n<timerEventDefinition />\n</startEvent>",
+			line:    3,
+			message: `unsupported element "timerEventDefinition" in startEvent 's'`,
+		},
+		{
+			name:    "unknown reference",
+			process: "<task id=\"a\" />\n<sequenceFlow id=\"f\" sourceRef=\"a\" targetRef=\"b\" />",
+			line:    4,
+			message: "sequence flow 'f' refers to unknown element 'b'",
+		},
+		{
+			name:    "invalid condition",
+			process: "<task id=\"a\" />\n<task id=\"b\" />\n<sequenceFlow id=\"f\" sourceRef=\"a\" targetRef=\"b\">\n<conditionExpression>amount &gt;</conditionExpression>\n</sequenceFlow>",
+			line:    5,
+			message: "invalid condition",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "<?xml version=\"1.0\"?>\n<definitions>\n<process id=\"p\">" + tt.process + "</process>\n</definitions>\n"
+			_, err := workflow.ImportBPMN([]byte(data))
+			var loadErr *workflow.LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("expected a *LoadError, got %v", err)
+			}
+			if loadErr.Line != tt.line || !strings.Contains(loadErr.Error(), tt.message) {
+				t.Errorf("error = %v, want line %d containing %q", loadErr, tt.line, tt.message)
+			}
+		})
+	}
+}
+
+func TestExportBPMN(t *testing.T) {
+	guard, err := workflow.NewExpressionConstraint("context.unpaid_days > 30")
+	if err != nil {
+		t.Fatalf("NewExpressionConstraint() error = %v", err)
+	}
+	def, err := workflow.NewBuilder("order").
+		Place("new", "paid", "packed", "shipped", "cancelled").
+		InitialPlace("new").
+		FinalPlace("shipped", "cancelled").
+		PlaceMetadata("new", workflow.MetadataLabel, "New order").
+		PlaceMetadata("paid", workflow.MetadataBPMNType, "serviceTask").
+		Transition("pay").From("new").To("paid", "packed").
+		Transition("ship").From("paid", "packed").To("shipped").
+		Transition("cancel").From("new").To("cancelled").
+		Constraint(guard).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	data, err := workflow.ExportBPMN(def)
+	if err != nil {
+		t.Fatalf("ExportBPMN() error = %v", err)
+	}
+	for _, want := range []string{
+		`<bpmn:startEvent id="new" name="New order">`,
+		`<bpmn:endEvent id="cancelled" name="cancelled">`,
+		`<bpmn:serviceTask id="paid" name="paid">`,
+		`<bpmn:parallelGateway id="Gateway_pay" name="pay">`,
+		`<bpmn:conditionExpression xsi:type="bpmn:tFormalExpression">context.unpaid_days &gt; 30</bpmn:conditionExpression>`,
+		`<bpmndi:BPMNShape id="new_di" bpmnElement="new">`,
+		`<dc:Bounds x="82" y="82" width="36" height="36"></dc:Bounds>`,
+		`<di:waypoint x="118" y="100"></di:waypoint>`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("export does not contain %s:\n%s", want, data)
+		}
+	}
+
+	imported, err := workflow.ImportBPMN(data)
+	if err != nil {
+		t.Fatalf("ImportBPMN() of exported data error = %v\n%s", err, data)
+	}
+	again, err := workflow.ExportBPMN(imported)
+	if err != nil {
+		t.Fatalf("ExportBPMN() error = %v", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("export is not stable:\n%s\n---\n%s", data, again)
+	}
+
+	guarded, err := workflow.NewBuilder("order").
+		Place("new", "paid").
+		Transition("pay").From("new").To("paid").
+		Guard(func(workflow.Event) error { return nil }).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if _, err := workflow.ExportBPMN(guarded); err == nil {
+		t.Error("expected an error for a Go constraint")
+	}
+}
@@ -0,0 +1,266 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Builder assembles a Definition with a fluent API. Validation errors are
+// accumulated and reported together by Build.
+type Builder struct {
+	name          string
+	places        []Place
+	placeSet      map[Place]bool
+	placeMetadata map[Place]map[string]interface{}
+	metadata      map[string]interface{}
+	initialPlaces []Place
+	finalPlaces   []Place
+	transitions   []*TransitionBuilder
+	options       []DefinitionOption
+	errs          []error
+}
+
+// TransitionBuilder configures a single transition of a Builder
+type TransitionBuilder struct {
+	builder     *Builder
+	name        string
+	from        []Place
+	to          []Place
+	metadata    map[string]interface{}
+	constraints []Constraint
+	inputSchema *InputSchema
+}
+
+// NewBuilder creates a new definition builder
+func NewBuilder(name string) *Builder {
+	b := &Builder{
+		name:          name,
+		placeSet:      make(map[Place]bool),
+		placeMetadata: make(map[Place]map[string]interface{}),
+		metadata:      make(map[string]interface{}),
+	}
+	if name == "" {
+		b.errs = append(b.errs, fmt.Errorf("definition name cannot be empty"))
+	}
+	return b
+}
+
+// Place declares one or more places
+func (b *Builder) Place(places ...Place) *Builder {
+	for _, place := range places {
+		if place == "" {
+			b.errs = append(b.errs, fmt.Errorf("place name cannot be empty"))
+			continue
+		}
+		if b.placeSet[place] {
+			b.errs = append(b.errs, fmt.Errorf("duplicate place '%s'", place))
+			continue
+		}
+		b.placeSet[place] = true
+		b.places = append(b.places, place)
+	}
+	return b
+}
+
+// PlaceMetadata sets a metadata value on a place
+func (b *Builder) PlaceMetadata(place Place, key string, value interface{}) *Builder {
+	if b.placeMetadata[place] == nil {
+		b.placeMetadata[place] = make(map[string]interface{})
+	}
+	b.placeMetadata[place][key] = value
+	return b
+}
+
+// Metadata sets a metadata value on the definition
+func (b *Builder) Metadata(key string, value interface{}) *Builder {
+	b.metadata[key] = value
+	return b
+}
+
+// InitialPlace sets the initial places. Defaults to the first declared place.
+func (b *Builder) InitialPlace(places ...Place) *Builder {
+	b.initialPlaces = append(b.initialPlaces, places...)
+	return b
+}
+
+// FinalPlace sets the final places. Defaults to the places without outgoing transitions.
+func (b *Builder) FinalPlace(places ...Place) *Builder {
+	b.finalPlaces = append(b.finalPlaces, places...)
+	return b
+}
+
+// Option applies additional definition options, such as context requirements
+func (b *Builder) Option(opts ...DefinitionOption) *Builder {
+	b.options = append(b.options, opts...)
+	return b
+}
+
+// Transition starts the declaration of a new transition
+func (b *Builder) Transition(name string) *TransitionBuilder {
+	t := &TransitionBuilder{
+		builder:  b,
+		name:     name,
+		metadata: make(map[string]interface{}),
+	}
+	b.transitions = append(b.transitions, t)
+	return t
+}
+
+// Build validates the declarations and creates the definition.
+// All validation errors are returned joined together.
+func (b *Builder) Build() (*Definition, error) {
+	errs := append([]error(nil), b.errs...)
+
+	transitions := make([]Transition, 0, len(b.transitions))
+	hasOutgoing := make(map[Place]bool)
+	for _, tb := range b.transitions {
+		t, err := tb.transition()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, place := range append(t.From(), t.To()...) {
+			if !b.placeSet[place] {
+				errs = append(errs, fmt.Errorf("place '%s' in transition '%s' is not defined in workflow places", place, t.Name()))
+			}
+		}
+		for _, place := range t.From() {
+			hasOutgoing[place] = true
+		}
+		transitions = append(transitions, *t)
+	}
+
+	for place := range b.placeMetadata {
+		if !b.placeSet[place] {
+			errs = append(errs, fmt.Errorf("place '%s' in place metadata is not defined in workflow places", place))
+		}
+	}
+
+	initialPlaces := b.initialPlaces
+	if len(initialPlaces) == 0 && len(b.places) > 0 {
+		initialPlaces = []Place{b.places[0]}
+	}
+	finalPlaces := b.finalPlaces
+	if len(finalPlaces) == 0 {
+		for _, place := range b.places {
+			if !hasOutgoing[place] {
+				finalPlaces = append(finalPlaces, place)
+			}
+		}
+	}
+	for _, place := range initialPlaces {
+		if !b.placeSet[place] {
+			errs = append(errs, fmt.Errorf("initial place '%s' is not defined in workflow places", place))
+		}
+	}
+	for _, place := range finalPlaces {
+		if !b.placeSet[place] {
+			errs = append(errs, fmt.Errorf("final place '%s' is not defined in workflow places", place))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	opts := []DefinitionOption{
+		WithName(b.name),
+		WithMetadata(b.metadata),
+		WithInitialPlaces(initialPlaces...),
+		WithFinalPlaces(finalPlaces...),
+	}
+	for _, place := range b.places {
+		if len(b.placeMetadata[place]) > 0 {
+			opts = append(opts, WithPlaceMetadata(place, b.placeMetadata[place]))
+		}
+	}
+	opts = append(opts, b.options...)
+
+	places := make([]Place, len(b.places))
+	copy(places, b.places)
+	return NewDefinition(places, transitions, opts...)
+}
+
+// MustBuild is like Build but panics on error
+func (b *Builder) MustBuild() *Definition {
+	d, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// From sets the source places of the transition
+func (t *TransitionBuilder) From(places ...Place) *TransitionBuilder {
+	t.from = append(t.from, places...)
+	return t
+}
+
+// To sets the target places of the transition
+func (t *TransitionBuilder) To(places ...Place) *TransitionBuilder {
+	t.to = append(t.to, places...)
+	return t
+}
+
+// Guard adds a guard function validated before the transition is applied
+func (t *TransitionBuilder) Guard(guard ConstraintFunc) *TransitionBuilder {
+	t.constraints = append(t.constraints, guard)
+	return t
+}
+
+// Constraint adds a constraint to the transition
+func (t *TransitionBuilder) Constraint(constraint Constraint) *TransitionBuilder {
+	t.constraints = append(t.constraints, constraint)
+	return t
+}
+
+// Metadata sets a metadata value on the transition
+func (t *TransitionBuilder) Metadata(key string, value interface{}) *TransitionBuilder {
+	t.metadata[key] = value
+	return t
+}
+
+// Input sets the input schema of the transition
+func (t *TransitionBuilder) Input(fields ...InputField) *TransitionBuilder {
+	t.inputSchema = NewInputSchema(fields...)
+	return t
+}
+
+// Transition ends this transition and starts the declaration of a new one
+func (t *TransitionBuilder) Transition(name string) *TransitionBuilder {
+	return t.builder.Transition(name)
+}
+
+// Place ends this transition and declares one or more places
+func (t *TransitionBuilder) Place(places ...Place) *Builder {
+	return t.builder.Place(places...)
+}
+
+// Build ends this transition and builds the definition
+func (t *TransitionBuilder) Build() (*Definition, error) {
+	return t.builder.Build()
+}
+
+// MustBuild ends this transition and builds the definition, panicking on error
+func (t *TransitionBuilder) MustBuild() *Definition {
+	return t.builder.MustBuild()
+}
+
+// transition creates the transition from the declaration
+func (t *TransitionBuilder) transition() (*Transition, error) {
+	tr, err := NewTransition(t.name, t.from, t.to)
+	if err != nil {
+		if t.name == "" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("transition '%s': %w", t.name, err)
+	}
+	for key, value := range t.metadata {
+		tr.SetMetadata(key, value)
+	}
+	for _, constraint := range t.constraints {
+		tr.AddConstraint(constraint)
+	}
+	tr.SetInputSchema(t.inputSchema)
+	return tr, nil
+}
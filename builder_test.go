@@ -0,0 +1,96 @@
+package workflow_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	errNotPaid := errors.New("not paid")
+	def, err := workflow.NewBuilder("order").
+		Place("new", "paid", "shipped").
+		PlaceMetadata("paid", workflow.MetadataLabel, "Paid").
+		Metadata(workflow.MetadataOwner, "checkout").
+		Transition("pay").From("new").To("paid").
+		Transition("ship").From("paid").To("shipped").
+		Guard(func(event workflow.Event) error {
+			if paid, _ := event.Workflow().Context("paid"); paid != true {
+				return errNotPaid
+			}
+			return nil
+		}).
+		Metadata(workflow.MetadataLabel, "Ship order").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if def.Name() != "order" {
+		t.Errorf("Name() = %q, want %q", def.Name(), "order")
+	}
+	if got := def.InitialPlaces(); len(got) != 1 || got[0] != "new" {
+		t.Errorf("InitialPlaces() = %v, want [new]", got)
+	}
+	if got := def.FinalPlaces(); len(got) != 1 || got[0] != "shipped" {
+		t.Errorf("FinalPlaces() = %v, want [shipped]", got)
+	}
+	if label, _ := def.PlaceMetadata("paid", workflow.MetadataLabel); label != "Paid" {
+		t.Errorf("PlaceMetadata(paid, label) = %v, want Paid", label)
+	}
+	if label, _ := def.Transition("ship").Metadata(workflow.MetadataLabel); label != "Ship order" {
+		t.Errorf("transition label = %v, want Ship order", label)
+	}
+
+	wf, err := workflow.NewWorkflow("order-1", def, "new")
+	if err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	if err := wf.Apply([]workflow.Place{"paid"}); err != nil {
+		t.Fatalf("Apply(paid) error = %v", err)
+	}
+	if err := wf.Apply([]workflow.Place{"shipped"}); !errors.Is(err, errNotPaid) {
+		t.Errorf("Apply(shipped) error = %v, want %v", err, errNotPaid)
+	}
+}
+
+func TestBuilder_AccumulatesErrors(t *testing.T) {
+	_, err := workflow.NewBuilder("order").
+		Place("new", "paid", "new").
+		InitialPlace("missing").
+		Transition("pay").From("new").To("unknown").
+		Transition("refund").From("paid").
+		Build()
+	if err == nil {
+		t.Fatal("Build() error = nil, want error")
+	}
+
+	for _, want := range []string{
+		"duplicate place 'new'",
+		"place 'unknown' in transition 'pay' is not defined in workflow places",
+		"transition 'refund': transition must have at least one 'to' place",
+		"initial place 'missing' is not defined in workflow places",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Build() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestBuilder_ExplicitInitialAndFinalPlaces(t *testing.T) {
+	def := workflow.NewBuilder("review").
+		Place("draft", "review", "approved", "rejected").
+		InitialPlace("draft").
+		FinalPlace("approved", "rejected").
+		Transition("submit").From("draft").To("review").
+		Transition("approve").From("review").To("approved").
+		Transition("reject").From("review").To("rejected").
+		Transition("reopen").From("rejected").To("draft").
+		MustBuild()
+
+	if got := def.FinalPlaces(); len(got) != 2 || got[0] != "approved" || got[1] != "rejected" {
+		t.Errorf("FinalPlaces() = %v, want [approved rejected]", got)
+	}
+}
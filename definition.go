@@ -1,20 +1,85 @@
 package workflow
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 )
 
-// Definition represents a workflow definition with places and transitions
+// Definition represents a workflow definition with places and transitions.
+// A definition is immutable once created: accessors return copies, so a
+// definition can be shared safely between running workflows.
 type Definition struct {
-	Places      []Place
-	Transitions []Transition
+	places      []Place
+	transitions []Transition
 
 	// Default listeners for this workflow type
-	Listeners map[EventType][]interface{}
+	listeners map[EventType][]interface{}
+
+	fingerprint string
+	version     string
+
+	name          string
+	initialPlaces []Place
+	finalPlaces   []Place
+	requirements  map[Place][]PlaceRequirement
+	metadata      map[string]interface{}
+	placeMetadata map[Place]map[string]interface{}
+	invariants    []Invariant
+	listenerRefs  []listenerJSON
+}
+
+// DefinitionOption is a function that configures a Definition
+type DefinitionOption func(*Definition)
+
+// WithName sets the name of the definition
+func WithName(name string) DefinitionOption {
+	return func(d *Definition) {
+		d.name = name
+	}
+}
+
+// WithInitialPlaces sets the places marked when a workflow starts
+func WithInitialPlaces(places ...Place) DefinitionOption {
+	return func(d *Definition) {
+		d.initialPlaces = append([]Place(nil), places...)
+	}
+}
+
+// WithFinalPlaces sets the places in which a workflow is considered complete
+func WithFinalPlaces(places ...Place) DefinitionOption {
+	return func(d *Definition) {
+		d.finalPlaces = append([]Place(nil), places...)
+	}
+}
+
+// WithEventListener adds a default event listener for a specific event type,
+// called for every workflow using the definition
+func WithEventListener(eventType EventType, listener EventListener) DefinitionOption {
+	return func(d *Definition) {
+		d.addListener(eventType, listener)
+	}
+}
+
+// WithGuardEventListener adds a default guard event listener, called for every
+// workflow using the definition
+func WithGuardEventListener(listener GuardEventListener) DefinitionOption {
+	return func(d *Definition) {
+		d.addListener(EventGuard, listener)
+	}
+}
+
+// addListener adds a default listener while the definition is constructed
+func (d *Definition) addListener(eventType EventType, listener interface{}) {
+	if d.listeners == nil {
+		d.listeners = make(map[EventType][]interface{})
+	}
+	d.listeners[eventType] = append(d.listeners[eventType], listener)
 }
 
 // NewDefinition creates a new workflow definition
-func NewDefinition(places []Place, transitions []Transition) (*Definition, error) {
+func NewDefinition(places []Place, transitions []Transition, opts ...DefinitionOption) (*Definition, error) {
 	// Create a map of valid places for quick lookup
 	validPlaces := make(map[Place]bool)
 	for _, place := range places {
@@ -38,30 +103,90 @@ func NewDefinition(places []Place, transitions []Transition) (*Definition, error
 		}
 	}
 
-	return &Definition{
-		Places:      places,
-		Transitions: transitions,
-	}, nil
+	d := &Definition{
+		places:      append([]Place(nil), places...),
+		transitions: make([]Transition, len(transitions)),
+	}
+	for i := range transitions {
+		d.transitions[i] = transitions[i].clone()
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	// Validate places referenced by options
+	for place := range d.requirements {
+		if !validPlaces[place] {
+			return nil, fmt.Errorf("place '%s' in context requirements is not defined in workflow places", place)
+		}
+	}
+	for _, place := range d.initialPlaces {
+		if !validPlaces[place] {
+			return nil, fmt.Errorf("initial place '%s' is not defined in workflow places", place)
+		}
+	}
+	for _, place := range d.finalPlaces {
+		if !validPlaces[place] {
+			return nil, fmt.Errorf("final place '%s' is not defined in workflow places", place)
+		}
+	}
+	for _, invariant := range d.invariants {
+		for _, place := range invariant.Places() {
+			if !validPlaces[place] {
+				return nil, fmt.Errorf("place '%s' in invariant '%s' is not defined in workflow places", place, invariant.Name())
+			}
+		}
+	}
+	for place := range d.placeMetadata {
+		if !validPlaces[place] {
+			return nil, fmt.Errorf("place '%s' in place metadata is not defined in workflow places", place)
+		}
+	}
+
+	d.fingerprint = d.computeFingerprint()
+	return d, nil
+}
+
+// Name returns the name of the definition
+func (d *Definition) Name() string {
+	return d.name
+}
+
+// InitialPlaces returns the places marked when a workflow starts, if declared
+func (d *Definition) InitialPlaces() []Place {
+	places := make([]Place, len(d.initialPlaces))
+	copy(places, d.initialPlaces)
+	return places
+}
+
+// FinalPlaces returns the places in which a workflow is considered complete, if declared
+func (d *Definition) FinalPlaces() []Place {
+	places := make([]Place, len(d.finalPlaces))
+	copy(places, d.finalPlaces)
+	return places
 }
 
 // AllPlaces returns all places (places) in the definition
 func (d *Definition) AllPlaces() []Place {
-	places := make([]Place, len(d.Places))
-	copy(places, d.Places)
+	places := make([]Place, len(d.places))
+	copy(places, d.places)
 	return places
 }
 
-// AllTransitions returns all transitions in the definition
+// AllTransitions returns copies of all transitions in the definition
 func (d *Definition) AllTransitions() []Transition {
-	transitions := make([]Transition, len(d.Transitions))
-	copy(transitions, d.Transitions)
+	transitions := make([]Transition, len(d.transitions))
+	for i := range d.transitions {
+		transitions[i] = d.transitions[i].clone()
+	}
 	return transitions
 }
 
-// Transition returns a transition by name
+// Transition returns a copy of the first transition with the given name
 func (d *Definition) Transition(name string) *Transition {
-	for _, t := range d.Transitions {
-		if t.Name() == name {
+	for i := range d.transitions {
+		if d.transitions[i].Name() == name {
+			t := d.transitions[i].clone()
 			return &t
 		}
 	}
@@ -70,7 +195,7 @@ func (d *Definition) Transition(name string) *Transition {
 
 // Place checks if a place exists in the definition
 func (d *Definition) Place(place Place) bool {
-	for _, p := range d.Places {
+	for _, p := range d.places {
 		if p == place {
 			return true
 		}
@@ -78,32 +203,123 @@ func (d *Definition) Place(place Place) bool {
 	return false
 }
 
-// AddEventListener adds a default event listener for a specific event type
-func (d *Definition) AddEventListener(eventType EventType, listener EventListener) {
-	if d.Listeners == nil {
-		d.Listeners = make(map[EventType][]interface{})
+// eventListeners returns a copy of the default listeners for an event type
+func (d *Definition) eventListeners(eventType EventType) []interface{} {
+	listeners := make([]interface{}, len(d.listeners[eventType]))
+	copy(listeners, d.listeners[eventType])
+	return listeners
+}
+
+// MarshalJSON implements json.Marshaler. The output uses the definition file
+// format read by LoadDefinition; parts that cannot be serialized, such as Go
+// constraints, are left out.
+func (d *Definition) MarshalJSON() ([]byte, error) {
+	doc, err := d.document(false)
+	if err != nil {
+		return nil, err
 	}
-	d.Listeners[eventType] = append(d.Listeners[eventType], listener)
+	return json.Marshal(doc)
+}
+
+// Fingerprint returns a stable hash of the definition content: its name and
+// version, places, transitions with their guard expressions and catalog
+// constraints, metadata, initial and final places, context requirements, input
+// schemas, invariant names and catalog listeners. Go constraints, listeners and
+// validators are not part of the fingerprint. It can be used as a version
+// identifier for the definition.
+func (d *Definition) Fingerprint() string {
+	return d.fingerprint
+}
+
+// fingerprintRequirement is the hashed form of a place requirement
+type fingerprintRequirement struct {
+	Place     Place  `json:"place"`
+	Key       string `json:"key"`
+	Validated bool   `json:"validated"`
 }
 
-// AddGuardEventListener adds a default guard event listener
-func (d *Definition) AddGuardEventListener(listener GuardEventListener) {
-	if d.Listeners == nil {
-		d.Listeners = make(map[EventType][]interface{})
+// fingerprintInput is the hashed form of a transition input schema
+type fingerprintInput struct {
+	Transition string       `json:"transition"`
+	Fields     []InputField `json:"fields"`
+}
+
+// computeFingerprint hashes the canonical JSON form of the definition
+func (d *Definition) computeFingerprint() string {
+	content := struct {
+		Definition   json.RawMessage          `json:"definition"`
+		Requirements []fingerprintRequirement `json:"requirements,omitempty"`
+		Inputs       []fingerprintInput       `json:"inputs,omitempty"`
+		Invariants   []string                 `json:"invariants,omitempty"`
+	}{}
+
+	doc, _ := d.document(false)
+	definition, err := json.Marshal(doc)
+	if err != nil {
+		// Metadata values that cannot be encoded as JSON, such as funcs, are
+		// hashed by their type
+		doc.Metadata = fingerprintMetadata(doc.Metadata)
+		for i := range doc.Places {
+			doc.Places[i].Metadata = fingerprintMetadata(doc.Places[i].Metadata)
+		}
+		for i := range doc.Transitions {
+			doc.Transitions[i].Metadata = fingerprintMetadata(doc.Transitions[i].Metadata)
+		}
+		definition, _ = json.Marshal(doc)
+	}
+	content.Definition = definition
+
+	for _, place := range d.places {
+		for _, req := range d.requirements[place] {
+			content.Requirements = append(content.Requirements, fingerprintRequirement{
+				Place: place, Key: req.Key, Validated: req.Validator != nil,
+			})
+		}
+	}
+	for _, t := range d.transitions {
+		if t.inputSchema != nil {
+			content.Inputs = append(content.Inputs, fingerprintInput{Transition: t.name, Fields: t.inputSchema.Fields})
+		}
+	}
+	for _, invariant := range d.invariants {
+		content.Invariants = append(content.Invariants, invariant.Name())
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", content))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprintMetadata replaces the metadata values that cannot be encoded as
+// JSON by their type
+func fingerprintMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		result[key] = fingerprintValue(value)
 	}
-	d.Listeners[EventGuard] = append(d.Listeners[EventGuard], listener)
+	return result
 }
 
-// RemoveEventListener removes a default event listener
-func (d *Definition) RemoveEventListener(eventType EventType, listener interface{}) {
-	if d.Listeners == nil {
-		return
+// fingerprintValue returns the value, or its type when it cannot be encoded as JSON
+func fingerprintValue(value interface{}) interface{} {
+	if _, err := json.Marshal(value); err == nil {
+		return value
 	}
-	listeners := d.Listeners[eventType]
-	for i, l := range listeners {
-		if &l == &listener {
-			d.Listeners[eventType] = append(listeners[:i], listeners[i+1:]...)
-			break
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return fingerprintMetadata(v)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = fingerprintValue(item)
 		}
+		return values
 	}
+	return fmt.Sprintf("%T", value)
 }
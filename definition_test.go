@@ -92,3 +92,102 @@ func TestNewDefinition(t *testing.T) {
 		})
 	}
 }
+
+func TestDefinition_Immutable(t *testing.T) {
+	places := []workflow.Place{"draft", "review"}
+	submit := workflow.MustNewTransition("submit", []workflow.Place{"draft"}, []workflow.Place{"review"})
+	submit.SetMetadata("label", "Submit")
+	transitions := []workflow.Transition{*submit}
+
+	def, err := workflow.NewDefinition(places, transitions)
+	if err != nil {
+		t.Fatalf("NewDefinition() error = %v", err)
+	}
+	fingerprint := def.Fingerprint()
+
+	// Mutating the inputs does not affect the definition
+	places[0] = "changed"
+	transitions[0].SetMetadata("label", "Changed")
+	submit.From()[0] = "changed"
+
+	// Mutating returned values does not affect the definition
+	def.AllPlaces()[0] = "changed"
+	def.AllTransitions()[0].SetMetadata("label", "Changed")
+	def.Transition("submit").SetMetadata("label", "Changed")
+	def.Transition("submit").From()[0] = "changed"
+
+	if !def.Place("draft") || def.Place("changed") {
+		t.Errorf("places changed: %v", def.AllPlaces())
+	}
+	got := def.Transition("submit")
+	if label, _ := got.Metadata("label"); label != "Submit" {
+		t.Errorf("transition label = %v, want Submit", label)
+	}
+	if got.From()[0] != "draft" {
+		t.Errorf("transition from = %v, want [draft]", got.From())
+	}
+	if def.Fingerprint() != fingerprint {
+		t.Error("fingerprint changed after mutating copies")
+	}
+}
+
+func TestDefinition_Fingerprint(t *testing.T) {
+	build := func(opts ...workflow.DefinitionOption) *workflow.Definition {
+		submit := workflow.MustNewTransition("submit", []workflow.Place{"draft"}, []workflow.Place{"review"})
+		def, err := workflow.NewDefinition([]workflow.Place{"draft", "review"}, []workflow.Transition{*submit}, opts...)
+		if err != nil {
+			t.Fatalf("NewDefinition() error = %v", err)
+		}
+		return def
+	}
+
+	base := build()
+	if len(base.Fingerprint()) != 64 {
+		t.Errorf("fingerprint %q is not a hex encoded sha256", base.Fingerprint())
+	}
+	if build().Fingerprint() != base.Fingerprint() {
+		t.Error("equal definitions have different fingerprints")
+	}
+	if build(workflow.WithEventListener(workflow.EventAfterTransition, func(workflow.Event) error { return nil })).Fingerprint() != base.Fingerprint() {
+		t.Error("listeners should not change the fingerprint")
+	}
+
+	// Metadata that cannot be encoded as JSON is hashed by its type
+	hook := func(f func()) *workflow.Definition {
+		return build(workflow.WithMetadata(map[string]interface{}{"hook": f}))
+	}
+	if hook(func() {}).Fingerprint() != hook(func() { println() }).Fingerprint() {
+		t.Error("func metadata should be hashed by its type")
+	}
+
+	tests := []struct {
+		name string
+		def  *workflow.Definition
+	}{
+		{"name", build(workflow.WithName("article"))},
+		{"metadata", build(workflow.WithMetadata(map[string]interface{}{"owner": "team"}))},
+		{"place metadata", build(workflow.WithPlaceMetadata("review", map[string]interface{}{"color": "#fc0"}))},
+		{"final places", build(workflow.WithFinalPlaces("review"))},
+		{"requirements", build(workflow.WithRequiredContext("review", "reviewer"))},
+		{"version", build(workflow.WithVersion("2"))},
+		{"guard", func() *workflow.Definition {
+			submit := workflow.MustNewTransition("submit", []workflow.Place{"draft"}, []workflow.Place{"review"})
+			guard, _ := workflow.NewExpressionConstraint("ready == true")
+			submit.AddConstraint(guard)
+			def, _ := workflow.NewDefinition([]workflow.Place{"draft", "review"}, []workflow.Transition{*submit})
+			return def
+		}()},
+		{"arcs", func() *workflow.Definition {
+			back := workflow.MustNewTransition("submit", []workflow.Place{"review"}, []workflow.Place{"draft"})
+			def, _ := workflow.NewDefinition([]workflow.Place{"draft", "review"}, []workflow.Transition{*back})
+			return def
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.def.Fingerprint() == base.Fingerprint() {
+				t.Error("fingerprint did not change")
+			}
+		})
+	}
+}
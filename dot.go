@@ -0,0 +1,142 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DotStyle selects how a workflow is drawn in a Graphviz DOT graph
+type DotStyle int
+
+const (
+	// DotPetriNet draws places as circles and transitions as boxes
+	DotPetriNet DotStyle = iota
+	// DotStateMachine draws places as nodes and transitions as labeled edges
+	DotStateMachine
+)
+
+// DotOptions configures DOT export
+type DotOptions struct {
+	Style DotStyle
+	// RenderOptions sets the places to highlight and the instance counts,
+	// shown as external labels. Metadata adds place and transition metadata
+	// to the labels.
+	RenderOptions
+	// RankDir is the Graphviz layout direction. Defaults to "LR".
+	RankDir string
+}
+
+// DOT generates a Graphviz DOT graph of the definition
+func (d *Definition) DOT(opts DotOptions) string {
+	return d.dot(opts, d.initialPlaces, nil)
+}
+
+// DOT generates a Graphviz DOT graph of the workflow, highlighting its current places
+func (w *Workflow) DOT(opts DotOptions) string {
+	return w.Definition().dot(opts, []Place{w.InitialPlace()}, w.CurrentPlaces())
+}
+
+// dot renders the definition with the given initial and current places
+func (d *Definition) dot(opts DotOptions, initial, current []Place) string {
+	rankDir := opts.RankDir
+	if rankDir == "" {
+		rankDir = "LR"
+	}
+	highlighted := opts.highlighted(current)
+	final := placeSet(d.finalPlaces)
+
+	var graph strings.Builder
+	graph.WriteString(fmt.Sprintf("digraph %s {\n", dotQuote(d.name)))
+	if title, ok := metadataString(d.metadata, MetadataLabel); ok {
+		graph.WriteString(fmt.Sprintf("    label=%s;\n    labelloc=t;\n", dotQuote(title)))
+	}
+	graph.WriteString(fmt.Sprintf("    rankdir=%s;\n", rankDir))
+	graph.WriteString("    node [fontsize=10];\n    edge [fontsize=9];\n\n")
+
+	// Add all places
+	for _, place := range d.places {
+		metadata := d.placeMetadata[place]
+		shape := "circle"
+		if opts.Style == DotStateMachine {
+			shape = "ellipse"
+		}
+		if final[place] {
+			shape = "double" + shape
+		}
+		attrs := []string{
+			"shape=" + shape,
+			"label=" + dotQuote(dotLabel(string(place), metadata, opts.Metadata)),
+		}
+		if color, ok := metadataString(metadata, MetadataColor); ok {
+			attrs = append(attrs, "style=filled", "fillcolor="+dotQuote(color))
+		}
+		if highlighted[place] {
+			attrs = append(attrs, "penwidth=3")
+		}
+		if count, ok := opts.instanceCount(place); ok {
+			attrs = append(attrs, "xlabel="+dotQuote(count))
+		}
+		graph.WriteString(fmt.Sprintf("    %s [%s];\n", dotPlaceID(place), strings.Join(attrs, ", ")))
+	}
+
+	// Point the initial places to a start node
+	if len(initial) > 0 {
+		graph.WriteString("    start [shape=point, width=0.15];\n")
+		for _, place := range initial {
+			graph.WriteString(fmt.Sprintf("    start -> %s;\n", dotPlaceID(place)))
+		}
+	}
+	graph.WriteString("\n")
+
+	// Add all transitions
+	for i, trans := range d.transitions {
+		label := dotLabel(trans.Name(), trans.metadata, opts.Metadata)
+		if opts.Style == DotStateMachine {
+			for _, from := range trans.From() {
+				for _, to := range trans.To() {
+					graph.WriteString(fmt.Sprintf("    %s -> %s [label=%s];\n", dotPlaceID(from), dotPlaceID(to), dotQuote(label)))
+				}
+			}
+			continue
+		}
+		id := fmt.Sprintf("transition_%d", i)
+		graph.WriteString(fmt.Sprintf("    %s [shape=box, label=%s];\n", id, dotQuote(label)))
+		for _, from := range trans.From() {
+			graph.WriteString(fmt.Sprintf("    %s -> %s;\n", dotPlaceID(from), id))
+		}
+		for _, to := range trans.To() {
+			graph.WriteString(fmt.Sprintf("    %s -> %s;\n", id, dotPlaceID(to)))
+		}
+	}
+
+	graph.WriteString("}\n")
+	return graph.String()
+}
+
+// dotLabel returns the label of a node, using the label metadata when set and
+// listing the remaining metadata when requested
+func dotLabel(name string, metadata map[string]interface{}, withMetadata bool) string {
+	label := name
+	if l, ok := metadataString(metadata, MetadataLabel); ok {
+		label = l
+	}
+	if withMetadata {
+		for _, line := range metadataLines(metadata) {
+			label += "\n" + line
+		}
+	}
+	return label
+}
+
+// dotPlaceID returns the node ID of a place
+func dotPlaceID(place Place) string {
+	return dotQuote("place_" + string(place))
+}
+
+// dotQuote returns s as a quoted DOT string
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
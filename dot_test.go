@@ -0,0 +1,81 @@
+package workflow_test
+
+import (
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+var orderDiagramDefinition = workflow.NewBuilder("order").
+	Place("new", "paid", "packed", "shipped").
+	InitialPlace("new").
+	FinalPlace("shipped").
+	PlaceMetadata("paid", workflow.MetadataColor, "#c8e6c9").
+	PlaceMetadata("paid", "sla", "1h").
+	Transition("pay").From("new").To("paid", "packed").Metadata("cost", 2).
+	Transition("ship").From("paid", "packed").To("shipped").
+	MustBuild()
+
+func TestDefinition_DOT(t *testing.T) {
+	tests := []struct {
+		name string
+		opts workflow.DotOptions
+		want string
+	}{
+		{
+			name: "petri net",
+			opts: workflow.DotOptions{RenderOptions: workflow.RenderOptions{Highlight: []workflow.Place{"paid"}, Counts: map[workflow.Place]int{"paid": 12}}},
+			want: `digraph "order" {
+    rankdir=LR;
+    node [fontsize=10];
+    edge [fontsize=9];
+
+    "place_new" [shape=circle, label="new"];
+    "place_paid" [shape=circle, label="paid", style=filled, fillcolor="#c8e6c9", penwidth=3, xlabel="12 instances"];
+    "place_packed" [shape=circle, label="packed"];
+    "place_shipped" [shape=doublecircle, label="shipped"];
+    start [shape=point, width=0.15];
+    start -> "place_new";
+
+    transition_0 [shape=box, label="pay"];
+    "place_new" -> transition_0;
+    transition_0 -> "place_paid";
+    transition_0 -> "place_packed";
+    transition_1 [shape=box, label="ship"];
+    "place_paid" -> transition_1;
+    "place_packed" -> transition_1;
+    transition_1 -> "place_shipped";
+}
+`,
+		},
+		{
+			name: "state machine with metadata",
+			opts: workflow.DotOptions{Style: workflow.DotStateMachine, RankDir: "TB", RenderOptions: workflow.RenderOptions{Metadata: true}},
+			want: `digraph "order" {
+    rankdir=TB;
+    node [fontsize=10];
+    edge [fontsize=9];
+
+    "place_new" [shape=ellipse, label="new"];
+    "place_paid" [shape=ellipse, label="paid\nsla: 1h", style=filled, fillcolor="#c8e6c9"];
+    "place_packed" [shape=ellipse, label="packed"];
+    "place_shipped" [shape=doubleellipse, label="shipped"];
+    start [shape=point, width=0.15];
+    start -> "place_new";
+
+    "place_new" -> "place_paid" [label="pay\ncost: 2"];
+    "place_new" -> "place_packed" [label="pay\ncost: 2"];
+    "place_paid" -> "place_shipped" [label="ship"];
+    "place_packed" -> "place_shipped" [label="ship"];
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderDiagramDefinition.DOT(tt.opts); got != tt.want {
+				t.Errorf("DOT() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
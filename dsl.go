@@ -0,0 +1,537 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dslName matches the names written without quotes in the DSL
+var dslName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// dslTokenKind is the kind of a DSL token
+type dslTokenKind int
+
+const (
+	dslIdent dslTokenKind = iota
+	dslColon
+	dslComma
+	dslArrow
+	dslGuard
+	dslBlocked
+	dslMeta
+)
+
+// dslToken is a token of a DSL line
+type dslToken struct {
+	kind dslTokenKind
+	// text is the name, the guard expression or the metadata block
+	text   string
+	quoted bool
+	column int
+}
+
+// dslParser builds a definition from DSL statements
+type dslParser struct {
+	line        int
+	name        string
+	metadata    map[string]interface{}
+	places      []Place
+	seen        map[Place]bool
+	placeMeta   map[Place]map[string]interface{}
+	initial     []Place
+	final       []Place
+	transitions []Transition
+}
+
+// ParseDSL parses a definition written in the compact text DSL:
+//
+//	# comments start with a hash
+//	workflow order {label: "Order"}
+//	place review {label: "In review", color: "#ffcc00"}
+//	initial draft
+//	final done
+//	submit: draft -> review
+//	fork: review -> legal, technical [guard: context.amount > 100]
+//	join: legal, technical -> done {label: "Approve"}
+//
+// Each line holds one statement. Transitions are written as name: from -> to,
+// with comma separated places, followed by an optional [guard: expression],
+// an optional [blocked] marker and an optional {metadata} block in YAML flow
+// style. A blocked transition gets a constraint that always blocks it. Places are declared by
+// place statements or by their first use. Names that are not made of letters,
+// digits, '_', '.' and '-' are written as double quoted strings. Errors are
+// *LoadError values carrying the line and column of the offending token.
+func ParseDSL(data []byte) (*Definition, error) {
+	p := &dslParser{
+		seen:      make(map[Place]bool),
+		placeMeta: make(map[Place]map[string]interface{}),
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		p.line = i + 1
+		tokens, err := p.tokenize(line)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		if err := p.statement(tokens, len(line)+1); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []DefinitionOption{WithName(p.name)}
+	if len(p.metadata) > 0 {
+		opts = append(opts, WithMetadata(p.metadata))
+	}
+	if len(p.initial) > 0 {
+		opts = append(opts, WithInitialPlaces(p.initial...))
+	}
+	if len(p.final) > 0 {
+		opts = append(opts, WithFinalPlaces(p.final...))
+	}
+	for _, place := range p.places {
+		if metadata := p.placeMeta[place]; len(metadata) > 0 {
+			opts = append(opts, WithPlaceMetadata(place, metadata))
+		}
+	}
+	definition, err := NewDefinition(p.places, p.transitions, opts...)
+	if err != nil {
+		return nil, &LoadError{Err: err}
+	}
+	return definition, nil
+}
+
+// errorf returns a *LoadError located at a column of the current line
+func (p *dslParser) errorf(column int, format string, args ...interface{}) error {
+	return &LoadError{Line: p.line, Column: column, Err: fmt.Errorf(format, args...)}
+}
+
+// tokenize splits a line into tokens, dropping comments
+func (p *dslParser) tokenize(line string) ([]dslToken, error) {
+	var tokens []dslToken
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '#':
+			return tokens, nil
+		case c == ':':
+			tokens = append(tokens, dslToken{kind: dslColon, text: ":", column: i + 1})
+			i++
+		case c == ',':
+			tokens = append(tokens, dslToken{kind: dslComma, text: ",", column: i + 1})
+			i++
+		case strings.HasPrefix(line[i:], "->"):
+			tokens = append(tokens, dslToken{kind: dslArrow, text: "->", column: i + 1})
+			i += 2
+		case c == '"':
+			end := dslQuoteEnd(line, i)
+			if end < 0 {
+				return nil, p.errorf(i+1, "unterminated string")
+			}
+			name, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, p.errorf(i+1, "invalid string %s", line[i:end+1])
+			}
+			tokens = append(tokens, dslToken{kind: dslIdent, text: name, quoted: true, column: i + 1})
+			i = end + 1
+		case c == '[' || c == '{':
+			end := dslBlockEnd(line, i)
+			if end < 0 {
+				return nil, p.errorf(i+1, "unterminated %c block", c)
+			}
+			if c == '{' {
+				tokens = append(tokens, dslToken{kind: dslMeta, text: line[i : end+1], column: i + 1})
+				i = end + 1
+				continue
+			}
+			content := line[i+1 : end]
+			if strings.TrimSpace(content) == "blocked" {
+				tokens = append(tokens, dslToken{kind: dslBlocked, column: i + 1})
+				i = end + 1
+				continue
+			}
+			key, expression, ok := strings.Cut(content, ":")
+			if !ok || strings.TrimSpace(key) != "guard" {
+				return nil, p.errorf(i+1, "expected [guard: expression] or [blocked]")
+			}
+			// The column of the expression, for errors reported by the parser
+			offset := i + 1 + len(key) + 1
+			trimmed := strings.TrimLeft(expression, " \t")
+			offset += len(expression) - len(trimmed)
+			tokens = append(tokens, dslToken{kind: dslGuard, text: strings.TrimSpace(trimmed), column: offset + 1})
+			i = end + 1
+		default:
+			start := i
+			for i < len(line) && strings.IndexByte(" \t#:,[{\"", line[i]) < 0 && !strings.HasPrefix(line[i:], "->") {
+				i++
+			}
+			tokens = append(tokens, dslToken{kind: dslIdent, text: line[start:i], column: start + 1})
+		}
+	}
+	return tokens, nil
+}
+
+// statement parses the tokens of a line
+func (p *dslParser) statement(tokens []dslToken, end int) error {
+	first := tokens[0]
+	if first.kind != dslIdent {
+		return p.errorf(first.column, "expected a statement, got %q", first.text)
+	}
+	if len(tokens) > 1 && tokens[1].kind == dslColon {
+		return p.transition(tokens, end)
+	}
+
+	s := &dslStream{tokens: tokens[1:], end: end}
+	switch first.text {
+	case "workflow":
+		if tok := s.peek(); tok != nil && tok.kind == dslIdent {
+			if err := p.checkName(*tok); err != nil {
+				return err
+			}
+			p.name = tok.text
+			s.next()
+		}
+		metadata, err := p.metadataBlock(s)
+		if err != nil {
+			return err
+		}
+		if p.metadata == nil {
+			p.metadata = make(map[string]interface{})
+		}
+		for key, value := range metadata {
+			p.metadata[key] = value
+		}
+		return p.expectEnd(s)
+	case "place":
+		places, err := p.placeList(s)
+		if err != nil {
+			return err
+		}
+		metadata, err := p.metadataBlock(s)
+		if err != nil {
+			return err
+		}
+		for _, place := range places {
+			if p.placeMeta[place] == nil {
+				p.placeMeta[place] = make(map[string]interface{})
+			}
+			for key, value := range metadata {
+				p.placeMeta[place][key] = value
+			}
+		}
+		return p.expectEnd(s)
+	case "initial", "final":
+		places, err := p.placeList(s)
+		if err != nil {
+			return err
+		}
+		if first.text == "initial" {
+			p.initial = append(p.initial, places...)
+		} else {
+			p.final = append(p.final, places...)
+		}
+		return p.expectEnd(s)
+	}
+	return p.errorf(first.column, "unknown statement %q: expected workflow, place, initial, final or name: from -> to", first.text)
+}
+
+// transition parses a transition statement
+func (p *dslParser) transition(tokens []dslToken, end int) error {
+	name := tokens[0]
+	if err := p.checkName(name); err != nil {
+		return err
+	}
+	s := &dslStream{tokens: tokens[2:], end: end}
+	from, err := p.placeList(s)
+	if err != nil {
+		return err
+	}
+	if tok := s.next(); tok == nil || tok.kind != dslArrow {
+		return p.errorf(s.column(tok), "expected ->")
+	}
+	to, err := p.placeList(s)
+	if err != nil {
+		return err
+	}
+	t, err := NewTransition(name.text, from, to)
+	if err != nil {
+		return p.errorf(name.column, "%v", err)
+	}
+
+	if tok := s.peek(); tok != nil && tok.kind == dslGuard {
+		s.next()
+		guard, err := NewExpressionConstraint(tok.text)
+		if err != nil {
+			column := tok.column
+			var exprErr *ExpressionError
+			if errors.As(err, &exprErr) {
+				column += exprErr.Pos - 1
+				err = errors.New(exprErr.Msg)
+			}
+			return p.errorf(column, "invalid guard: %v", err)
+		}
+		t.AddConstraint(guard)
+	}
+	if tok := s.peek(); tok != nil && tok.kind == dslBlocked {
+		s.next()
+		t.AddConstraint(blockedConstraint{})
+	}
+	metadata, err := p.metadataBlock(s)
+	if err != nil {
+		return err
+	}
+	for key, value := range metadata {
+		t.SetMetadata(key, value)
+	}
+	if err := p.expectEnd(s); err != nil {
+		return err
+	}
+	p.transitions = append(p.transitions, *t)
+	return nil
+}
+
+// placeList parses comma separated places, declaring them
+func (p *dslParser) placeList(s *dslStream) ([]Place, error) {
+	var places []Place
+	for {
+		tok := s.next()
+		if tok == nil || tok.kind != dslIdent {
+			return nil, p.errorf(s.column(tok), "expected a place")
+		}
+		if err := p.checkName(*tok); err != nil {
+			return nil, err
+		}
+		place := Place(tok.text)
+		if !p.seen[place] {
+			p.seen[place] = true
+			p.places = append(p.places, place)
+		}
+		places = append(places, place)
+		if next := s.peek(); next == nil || next.kind != dslComma {
+			return places, nil
+		}
+		s.next()
+	}
+}
+
+// metadataBlock parses an optional metadata block
+func (p *dslParser) metadataBlock(s *dslStream) (map[string]interface{}, error) {
+	tok := s.peek()
+	if tok == nil || tok.kind != dslMeta {
+		return nil, nil
+	}
+	s.next()
+	var metadata map[string]interface{}
+	if err := yaml.Unmarshal([]byte(tok.text), &metadata); err != nil {
+		return nil, p.errorf(tok.column, "invalid metadata: %v", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	return metadata, nil
+}
+
+// checkName rejects empty names and unquoted names with special characters
+func (p *dslParser) checkName(tok dslToken) error {
+	if tok.text == "" {
+		return p.errorf(tok.column, "empty name")
+	}
+	if !tok.quoted && !dslName.MatchString(tok.text) {
+		return p.errorf(tok.column, "invalid name %q: use a quoted string", tok.text)
+	}
+	return nil
+}
+
+// expectEnd reports unexpected trailing tokens
+func (p *dslParser) expectEnd(s *dslStream) error {
+	if tok := s.peek(); tok != nil {
+		return p.errorf(tok.column, "unexpected %q", tok.text)
+	}
+	return nil
+}
+
+// dslStream iterates over the tokens of a statement
+type dslStream struct {
+	tokens []dslToken
+	pos    int
+	// end is the column after the end of the line
+	end int
+}
+
+// peek returns the next token without consuming it, or nil at the end
+func (s *dslStream) peek() *dslToken {
+	if s.pos < len(s.tokens) {
+		return &s.tokens[s.pos]
+	}
+	return nil
+}
+
+// next consumes the next token, or returns nil at the end
+func (s *dslStream) next() *dslToken {
+	tok := s.peek()
+	if tok != nil {
+		s.pos++
+	}
+	return tok
+}
+
+// column returns the column of a token, or the end of the line for nil
+func (s *dslStream) column(tok *dslToken) int {
+	if tok == nil {
+		return s.end
+	}
+	return tok.column
+}
+
+// dslQuoteEnd returns the index of the quote closing the string at start
+func dslQuoteEnd(line string, start int) int {
+	for i := start + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// dslBlockEnd returns the index of the bracket closing the block at start,
+// skipping quoted strings and nested blocks
+func dslBlockEnd(line string, start int) int {
+	depth := 0
+	for i := start; i < len(line); i++ {
+		switch line[i] {
+		case '"', '\'':
+			quote := line[i]
+			for i++; i < len(line) && line[i] != quote; i++ {
+				if line[i] == '\\' && quote == '"' {
+					i++
+				}
+			}
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// FormatDSL writes a definition in the compact text DSL read by ParseDSL.
+// Every place is declared by a place statement, so that the order of places
+// is kept. Expression guards are written as [guard: ...] blocks, and metadata
+// as {...} blocks with sorted keys. Other constraints cannot be written in the
+// DSL, so their transitions are marked [blocked] and stay blocked when parsed
+// back. Context requirements, input schemas and invariants are omitted.
+func FormatDSL(definition *Definition) ([]byte, error) {
+	var buf bytes.Buffer
+	line := func(parts ...string) {
+		var nonEmpty []string
+		for _, part := range parts {
+			if part != "" {
+				nonEmpty = append(nonEmpty, part)
+			}
+		}
+		buf.WriteString(strings.Join(nonEmpty, " "))
+		buf.WriteByte('\n')
+	}
+
+	metadata, err := dslMetadata(definition.metadata)
+	if err != nil {
+		return nil, err
+	}
+	if definition.name != "" || metadata != "" {
+		name := ""
+		if definition.name != "" {
+			name = dslQuote(definition.name)
+		}
+		line("workflow", name, metadata)
+		buf.WriteByte('\n')
+	}
+
+	for _, place := range definition.places {
+		metadata, err := dslMetadata(definition.placeMetadata[place])
+		if err != nil {
+			return nil, fmt.Errorf("place '%s': %w", place, err)
+		}
+		line("place", dslQuote(string(place)), metadata)
+	}
+	if len(definition.initialPlaces) > 0 {
+		line("initial", dslPlaces(definition.initialPlaces))
+	}
+	if len(definition.finalPlaces) > 0 {
+		line("final", dslPlaces(definition.finalPlaces))
+	}
+
+	if len(definition.transitions) > 0 {
+		buf.WriteByte('\n')
+	}
+	for _, t := range definition.transitions {
+		guard, complete := guardExpression(&t)
+		if guard != "" {
+			guard = "[guard: " + guard + "]"
+		}
+		blocked := ""
+		if !complete {
+			blocked = "[blocked]"
+		}
+		metadata, err := dslMetadata(t.metadata)
+		if err != nil {
+			return nil, fmt.Errorf("transition '%s': %w", t.Name(), err)
+		}
+		line(dslQuote(t.Name())+":", dslPlaces(t.From()), "->", dslPlaces(t.To()), guard, blocked, metadata)
+	}
+	return buf.Bytes(), nil
+}
+
+// dslMetadata formats a metadata block, or returns "" for empty metadata
+func dslMetadata(metadata map[string]interface{}) (string, error) {
+	if len(metadata) == 0 {
+		return "", nil
+	}
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	entries := make([]string, len(keys))
+	for i, key := range keys {
+		// JSON values are valid in YAML flow style
+		value, err := json.Marshal(metadata[key])
+		if err != nil {
+			return "", fmt.Errorf("metadata %q: %w", key, err)
+		}
+		entries[i] = dslQuote(key) + ": " + string(value)
+	}
+	return "{" + strings.Join(entries, ", ") + "}", nil
+}
+
+// dslPlaces formats a comma separated list of places
+func dslPlaces(places []Place) string {
+	names := make([]string, len(places))
+	for i, place := range places {
+		names[i] = dslQuote(string(place))
+	}
+	return strings.Join(names, ", ")
+}
+
+// dslQuote quotes a name unless it can be written as is
+func dslQuote(name string) string {
+	if dslName.MatchString(name) && !strings.Contains(name, "->") {
+		return name
+	}
+	return strconv.Quote(name)
+}
@@ -0,0 +1,245 @@
+package workflow_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+const approvalDSL = `# Document approval
+workflow approval {label: "Approval", owner: docs-team}
+
+place review {label: "In review", color: "#ffcc00"}
+initial draft
+final done
+
+submit: draft -> review
+fork: review -> legal, technical [guard: context.amount > 100]
+join: legal, technical -> done {label: "Approve", priority: 2}
+"send back": review -> draft  # quoted names
+`
+
+func TestParseDSL(t *testing.T) {
+	def, err := workflow.ParseDSL([]byte(approvalDSL))
+	if err != nil {
+		t.Fatalf("ParseDSL() error = %v", err)
+	}
+	if def.Name() != "approval" {
+		t.Errorf("name = %q", def.Name())
+	}
+	if owner, _ := def.Metadata(workflow.MetadataOwner); owner != "docs-team" {
+		t.Errorf("owner = %v", owner)
+	}
+	want := []workflow.Place{"review", "draft", "done", "legal", "technical"}
+	if got := def.AllPlaces(); len(got) != len(want) || got[0] != want[0] || got[4] != want[4] {
+		t.Errorf("places = %v, want %v", got, want)
+	}
+	if color, _ := def.PlaceMetadata("review", workflow.MetadataColor); color != "#ffcc00" {
+		t.Errorf("review color = %v", color)
+	}
+	if got := def.InitialPlaces(); len(got) != 1 || got[0] != "draft" {
+		t.Errorf("initial places = %v", got)
+	}
+	if got := def.FinalPlaces(); len(got) != 1 || got[0] != "done" {
+		t.Errorf("final places = %v", got)
+	}
+	join := def.Transition("join")
+	if join == nil || len(join.From()) != 2 {
+		t.Fatalf("join = %v", join)
+	}
+	if priority, _ := join.Metadata("priority"); priority != 2 {
+		t.Errorf("priority = %v (%T)", priority, priority)
+	}
+	if def.Transition("send back") == nil {
+		t.Error("quoted transition name not parsed")
+	}
+
+	wf, err := workflow.NewWorkflow("doc", def, "draft")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	if err := wf.Apply([]workflow.Place{"review"}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	wf.SetContext("amount", 50)
+	if err := wf.Can([]workflow.Place{"legal", "technical"}); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("expected the guard to block, got %v", err)
+	}
+}
+
+func TestFormatDSL(t *testing.T) {
+	def, err := workflow.ParseDSL([]byte(approvalDSL))
+	if err != nil {
+		t.Fatalf("ParseDSL() error = %v", err)
+	}
+	data, err := workflow.FormatDSL(def)
+	if err != nil {
+		t.Fatalf("FormatDSL() error = %v", err)
+	}
+	want := `workflow approval {label: "Approval", owner: "docs-team"}
+
+place review {color: "#ffcc00", label: "In review"}
+place draft
+place done
+place legal
+place technical
+initial draft
+final done
+
+submit: draft -> review
+fork: review -> legal, technical [guard: context.amount > 100]
+join: legal, technical -> done {label: "Approve", priority: 2}
+"send back": review -> draft
+`
+	if string(data) != want {
+		t.Errorf("FormatDSL() = %s, want %s", data, want)
+	}
+
+	parsed, err := workflow.ParseDSL(data)
+	if err != nil {
+		t.Fatalf("ParseDSL() of formatted data error = %v", err)
+	}
+	if parsed.Fingerprint() != def.Fingerprint() {
+		t.Error("definition changed after formatting")
+	}
+}
+
+func TestFormatDSL_Builder(t *testing.T) {
+	def := workflow.NewBuilder("nested").
+		Place("a", "b c").
+		PlaceMetadata("a", "weights", map[string]interface{}{"x": []interface{}{1, "two"}}).
+		Transition("go").From("a").To("b c").
+		MustBuild()
+	data, err := workflow.FormatDSL(def)
+	if err != nil {
+		t.Fatalf("FormatDSL() error = %v", err)
+	}
+	if !strings.Contains(string(data), `go: a -> "b c"`) {
+		t.Errorf("FormatDSL() = %s", data)
+	}
+	parsed, err := workflow.ParseDSL(data)
+	if err != nil {
+		t.Fatalf("ParseDSL() error = %v\n%s", err, data)
+	}
+	if parsed.Fingerprint() != def.Fingerprint() {
+		t.Errorf("definition changed after formatting:\n%s", data)
+	}
+}
+
+func TestFormatDSL_Blocked(t *testing.T) {
+	ready, err := workflow.NewExpressionConstraint("context.ready == true")
+	if err != nil {
+		t.Fatalf("NewExpressionConstraint() error = %v", err)
+	}
+	def := workflow.NewBuilder("gated").
+		Place("a", "b").
+		Transition("go").From("a").To("b").
+		Constraint(ready).
+		Guard(func(workflow.Event) error { return nil }).
+		MustBuild()
+
+	data, err := workflow.FormatDSL(def)
+	if err != nil {
+		t.Fatalf("FormatDSL() error = %v", err)
+	}
+	if !strings.Contains(string(data), "go: a -> b [guard: context.ready == true] [blocked]\n") {
+		t.Errorf("FormatDSL() = %s", data)
+	}
+	parsed, err := workflow.ParseDSL(data)
+	if err != nil {
+		t.Fatalf("ParseDSL() error = %v\n%s", err, data)
+	}
+	if again, _ := workflow.FormatDSL(parsed); string(again) != string(data) {
+		t.Errorf("formatted output is not stable:\n%s\n---\n%s", data, again)
+	}
+
+	wf, err := workflow.NewWorkflow("g1", parsed, "a")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	wf.SetContext("ready", true)
+	if err := wf.Apply([]workflow.Place{"b"}); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("parsed transition should stay blocked, got %v", err)
+	}
+}
+
+func TestParseDSL_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		line    int
+		column  int
+		message string
+	}{
+		{
+			name:    "unknown statement",
+			source:  "workflow w\nstate a\n",
+			line:    2,
+			column:  1,
+			message: `unknown statement "state"`,
+		},
+		{
+			name:    "missing arrow",
+			source:  "go: a, b\n",
+			line:    1,
+			column:  9,
+			message: "expected ->",
+		},
+		{
+			name:    "missing place",
+			source:  "go: a ->\n",
+			line:    1,
+			column:  9,
+			message: "expected a place",
+		},
+		{
+			name:    "invalid guard",
+			source:  "\ngo: a -> b [guard: context.x >]\n",
+			line:    2,
+			column:  31,
+			message: "invalid guard",
+		},
+		{
+			name:    "unknown block",
+			source:  "go: a -> b [when: x]\n",
+			line:    1,
+			column:  12,
+			message: "expected [guard: expression]",
+		},
+		{
+			name:    "invalid name",
+			source:  "go: a -> b;c\n",
+			line:    1,
+			column:  10,
+			message: `invalid name "b;c"`,
+		},
+		{
+			name:    "unterminated metadata",
+			source:  "place a {label: \"x\"\n",
+			line:    1,
+			column:  9,
+			message: "unterminated { block",
+		},
+		{
+			name:    "trailing token",
+			source:  "initial a b\n",
+			line:    1,
+			column:  11,
+			message: `unexpected "b"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := workflow.ParseDSL([]byte(tt.source))
+			var loadErr *workflow.LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("expected a *LoadError, got %v", err)
+			}
+			if loadErr.Line != tt.line || loadErr.Column != tt.column || !strings.Contains(loadErr.Error(), tt.message) {
+				t.Errorf("error = %v, want %d:%d containing %q", loadErr, tt.line, tt.column, tt.message)
+			}
+		})
+	}
+}
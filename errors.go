@@ -7,4 +7,11 @@ var (
 	ErrTransitionNotAllowed = fmt.Errorf("transition not allowed")
 	ErrInvalidPlace         = fmt.Errorf("invalid place")
 	ErrInvalidTransition    = fmt.Errorf("invalid transition")
+	ErrInvalidPayload       = fmt.Errorf("invalid payload")
+	ErrRequirementsNotMet   = fmt.Errorf("place requirements not met")
+	ErrStateLimitExceeded   = fmt.Errorf("state limit exceeded")
+	ErrNoPath               = fmt.Errorf("no path")
+	ErrInvariantViolated    = fmt.Errorf("invariant violated")
+	ErrDefinitionNotFound   = fmt.Errorf("definition not found")
+	ErrUnsupportedElement   = fmt.Errorf("unsupported element")
 )
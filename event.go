@@ -24,6 +24,7 @@ type Event interface {
 	To() []Place
 	Workflow() *Workflow
 	Context() context.Context
+	Payload() map[string]interface{}
 }
 
 // BaseEvent represents a workflow event
@@ -33,6 +34,7 @@ type BaseEvent struct {
 	from       []Place
 	to         []Place
 	workflow   *Workflow
+	payload    map[string]interface{}
 
 	ctx context.Context
 }
@@ -79,6 +81,11 @@ func (e *BaseEvent) Context() context.Context {
 	return e.ctx
 }
 
+// Payload returns the input payload passed when applying the transition, or nil
+func (e *BaseEvent) Payload() map[string]interface{} {
+	return e.payload
+}
+
 // GuardEvent represents a guard event in the workflow
 type GuardEvent struct {
 	BaseEvent
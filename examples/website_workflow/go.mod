@@ -7,4 +7,6 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.22
 )
 
+require gopkg.in/yaml.v3 v3.0.1 // indirect
+
 replace github.com/euphoria-laxis/workflow => ../../
@@ -110,13 +110,15 @@ func init() {
 		notesVal := e.Context().Value(notesKey)
 		notesStr, _ := notesVal.(string)
 		return historyStore.SaveTransition(&history.TransitionRecord{
-			WorkflowID: e.Workflow().Name(),
-			FromState:  fmt.Sprintf("%v", e.From()),
-			ToState:    fmt.Sprintf("%v", e.To()),
-			Transition: e.Transition().Name(),
-			Notes:      notesStr,
-			Actor:      "", // fill in if you have user info
-			CreatedAt:  time.Now(),
+			WorkflowID:        e.Workflow().Name(),
+			FromState:         fmt.Sprintf("%v", e.From()),
+			ToState:           fmt.Sprintf("%v", e.To()),
+			Transition:        e.Transition().Name(),
+			Notes:             notesStr,
+			Actor:             "", // fill in if you have user info
+			CreatedAt:         time.Now(),
+			Payload:           e.Payload(),
+			DefinitionVersion: e.Workflow().Definition().Fingerprint(),
 		})
 	})
 
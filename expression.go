@@ -0,0 +1,1028 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Expression is a parsed guard expression. The language supports:
+//   - literals: numbers, 'single' or "double" quoted strings, true, false, null and [lists]
+//   - identifiers and member access: context.amount, payload["comment"], items[0]
+//   - operators: || && ! == != < <= > >= in + - * / % and parentheses
+//   - the keywords and, or and not as aliases of &&, || and !
+//   - function calls: len(x) and any function provided in the variables
+//
+// Expressions are evaluated against a set of variables; member access on a
+// missing map key yields null. Strings are indexed and measured by character,
+// so len("café") is 4.
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// ExpressionFunc is a function callable from an expression
+type ExpressionFunc func(args ...interface{}) (interface{}, error)
+
+// ExpressionError describes a syntax or evaluation error in an expression
+type ExpressionError struct {
+	Expression string
+	// Pos is the 1-based position of the error in the expression
+	Pos int
+	Msg string
+}
+
+// Error implements the error interface
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("expression %q: position %d: %s", e.Expression, e.Pos, e.Msg)
+}
+
+// ParseExpression parses an expression
+func ParseExpression(source string) (*Expression, error) {
+	p := &exprParser{source: source}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok.pos, "unexpected %s", tok)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// MustParseExpression parses an expression and panics on error
+func MustParseExpression(source string) *Expression {
+	e, err := ParseExpression(source)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate evaluates the expression with the given variables
+func (e *Expression) Evaluate(vars map[string]interface{}) (interface{}, error) {
+	value, err := e.root.eval(vars)
+	if err != nil {
+		if ee, ok := err.(*ExpressionError); ok {
+			ee.Expression = e.source
+			return nil, ee
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// EvaluateBool evaluates the expression and reports whether the result is truthy.
+// Null, false, zero, empty strings and empty collections are falsy.
+func (e *Expression) EvaluateBool(vars map[string]interface{}) (bool, error) {
+	value, err := e.Evaluate(vars)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// ExpressionConstraint is a transition constraint defined by a guard expression.
+// The expression can use the variables:
+//   - context: the workflow context
+//   - payload: the transition payload
+//   - transition: the transition name
+//   - from, to: the source and target places
+//   - marking: the currently marked places
+//   - marked(place): whether the place is currently marked, also available as
+//     In(place) for SCXML conditions
+type ExpressionConstraint struct {
+	expression *Expression
+}
+
+// NewExpressionConstraint parses a guard expression into a constraint
+func NewExpressionConstraint(source string) (*ExpressionConstraint, error) {
+	expression, err := ParseExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	return &ExpressionConstraint{expression: expression}, nil
+}
+
+// Expression returns the source of the guard expression
+func (c *ExpressionConstraint) Expression() string {
+	return c.expression.String()
+}
+
+// Validate implements Constraint
+func (c *ExpressionConstraint) Validate(event Event) error {
+	ok, err := c.expression.EvaluateBool(GuardVariables(event))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: guard %q is not satisfied", ErrTransitionNotAllowed, c.expression)
+	}
+	return nil
+}
+
+// GuardVariables returns the variables available to guard expressions for an event
+func GuardVariables(event Event) map[string]interface{} {
+	vars := map[string]interface{}{
+		"context":    map[string]interface{}{},
+		"payload":    map[string]interface{}{},
+		"transition": "",
+		"from":       placeValues(event.From()),
+		"to":         placeValues(event.To()),
+		"marking":    []interface{}{},
+	}
+	if t := event.Transition(); t != nil {
+		vars["transition"] = t.Name()
+	}
+	if payload := event.Payload(); payload != nil {
+		vars["payload"] = payload
+	}
+
+	var marking []Place
+	if w := event.Workflow(); w != nil {
+		vars["context"] = w.contextSnapshot()
+		marking = w.CurrentPlaces()
+		vars["marking"] = placeValues(marking)
+	}
+	marked := ExpressionFunc(func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("marked expects 1 argument, got %d", len(args))
+		}
+		name, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("marked expects a place name, got %T", args[0])
+		}
+		return placeSet(marking)[Place(name)], nil
+	})
+	vars["marked"] = marked
+	vars["In"] = marked
+	return vars
+}
+
+// foreignGuard is a guard written in another expression language that could
+// not be translated, such as a Symfony guard or an SCXML condition. It blocks
+// the transition so that an untranslated guard never lets it through, and
+// keeps its source so that exporters can write it back.
+type foreignGuard struct {
+	source string
+	// kind names the guard in errors, such as "Symfony guard"
+	kind string
+}
+
+// Validate implements Constraint
+func (g *foreignGuard) Validate(Event) error {
+	return fmt.Errorf("%w: %s %q is not supported", ErrTransitionNotAllowed, g.kind, g.source)
+}
+
+// blockedConstraint stands for constraints that an export format could not
+// write, such as guard functions or untranslated guards. It keeps the
+// re-imported transition blocked instead of silently enabling it.
+type blockedConstraint struct{}
+
+// Validate implements Constraint
+func (blockedConstraint) Validate(Event) error {
+	return fmt.Errorf("%w: the transition was blocked by constraints that were not exported", ErrTransitionNotAllowed)
+}
+
+// translateGuard translates a guard written in another expression language.
+// It returns an *ExpressionConstraint when the source parses and only uses
+// the variables of GuardVariables and the built-in functions. Otherwise it
+// returns a blocking *foreignGuard of the given kind and the reason.
+func translateGuard(source, kind string) (Constraint, string) {
+	expression, err := ParseExpression(source)
+	if err != nil {
+		return &foreignGuard{source: source, kind: kind}, fmt.Sprintf("cannot be parsed (%v)", err)
+	}
+	variables := GuardVariables(NewEvent(context.Background(), "", nil, nil, nil, nil))
+	var unknown []string
+	for _, name := range expression.identifiers() {
+		if _, ok := variables[name]; ok {
+			continue
+		}
+		if _, ok := builtinFuncs[name]; ok {
+			continue
+		}
+		unknown = append(unknown, name)
+	}
+	if len(unknown) > 0 {
+		return &foreignGuard{source: source, kind: kind}, "uses unsupported names " + strings.Join(unknown, ", ")
+	}
+	return &ExpressionConstraint{expression: expression}, ""
+}
+
+// guardExpression returns the guard expressions of a transition joined with
+// "&&", and false when the transition also has constraints that are not
+// expressions, which are left out
+func guardExpression(t *Transition) (string, bool) {
+	var guards []string
+	complete := true
+	for _, c := range t.constraints {
+		guard, ok := c.(*ExpressionConstraint)
+		if !ok {
+			complete = false
+			continue
+		}
+		guards = append(guards, guard.Expression())
+	}
+	return joinGuards(guards, "&&"), complete
+}
+
+// joinGuards joins guard expressions with a logical operator, parenthesizing
+// them when there are several
+func joinGuards(guards []string, operator string) string {
+	if len(guards) > 1 {
+		return "(" + strings.Join(guards, ") "+operator+" (") + ")"
+	}
+	return strings.Join(guards, "")
+}
+
+// placeValues converts places to expression values
+func placeValues(places []Place) []interface{} {
+	values := make([]interface{}, len(places))
+	for i, place := range places {
+		values[i] = string(place)
+	}
+	return values
+}
+
+// Tokens
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type exprToken struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// String describes the token in error messages
+func (t exprToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return fmt.Sprintf("string %q", t.value)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+type exprParser struct {
+	source string
+	tokens []exprToken
+	index  int
+}
+
+func (p *exprParser) errorf(pos int, format string, args ...interface{}) error {
+	return &ExpressionError{Expression: p.source, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// tokenize splits the source into tokens
+func (p *exprParser) tokenize() error {
+	runes := []rune(p.source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			f, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return p.errorf(pos, "invalid number %q", text)
+			}
+			var value interface{} = f
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				value = int(n)
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokNumber, text: text, value: value, pos: pos})
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(runes) {
+				c := runes[i]
+				if c == '\\' && i+1 < len(runes) {
+					switch runes[i+1] {
+					case 'n':
+						sb.WriteRune('\n')
+					case 't':
+						sb.WriteRune('\t')
+					default:
+						sb.WriteRune(runes[i+1])
+					}
+					i += 2
+					continue
+				}
+				i++
+				if c == r {
+					closed = true
+					break
+				}
+				sb.WriteRune(c)
+			}
+			if !closed {
+				return p.errorf(pos, "unterminated string")
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokString, text: string(runes[pos-1 : i]), value: sb.String(), pos: pos})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokIdent, text: string(runes[start:i]), pos: pos})
+		default:
+			op := ""
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "&&", "||", "==", "!=", "<=", ">=":
+					op = two
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("()[],.!<>+-*/%", r) {
+					return p.errorf(pos, "unexpected character %q", r)
+				}
+				op = string(r)
+			}
+			i += len([]rune(op))
+			p.tokens = append(p.tokens, exprToken{kind: tokOp, text: op, pos: pos})
+		}
+	}
+	p.tokens = append(p.tokens, exprToken{kind: tokEOF, pos: len(runes) + 1})
+	return nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.index]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.index]
+	if tok.kind != tokEOF {
+		p.index++
+	}
+	return tok
+}
+
+// accept consumes the next token when it is one of the given operators or keywords
+func (p *exprParser) accept(ops ...string) (exprToken, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp && tok.kind != tokIdent {
+		return tok, false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return p.next(), true
+		}
+	}
+	return tok, false
+}
+
+func (p *exprParser) expect(op string) (exprToken, error) {
+	tok, ok := p.accept(op)
+	if !ok {
+		return tok, p.errorf(tok.pos, "expected %q, got %s", op, tok)
+	}
+	return tok, nil
+}
+
+// Grammar, from lowest to highest precedence
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept("||", "or")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "||", left: left, right: right, pos: tok.pos}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept("&&", "and")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "&&", left: left, right: right, pos: tok.pos}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if tok, ok := p.accept("not"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "!", operand: operand, pos: tok.pos}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	tok, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "in")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{op: tok.text, left: left, right: right, pos: tok.pos}, nil
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: tok.text, left: left, right: right, pos: tok.pos}
+	}
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: tok.text, left: left, right: right, pos: tok.pos}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if tok, ok := p.accept("-", "!"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: tok.text, operand: operand, pos: tok.pos}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (exprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if tok, ok := p.accept("."); ok {
+			name := p.next()
+			if name.kind != tokIdent {
+				return nil, p.errorf(name.pos, "expected a member name, got %s", name)
+			}
+			node = &indexNode{target: node, index: &literalNode{value: name.text}, pos: tok.pos}
+			continue
+		}
+		if tok, ok := p.accept("["); ok {
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &indexNode{target: node, index: index, pos: tok.pos}
+			continue
+		}
+		if tok, ok := p.accept("("); ok {
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			node = &callNode{fn: node, args: args, pos: tok.pos}
+			continue
+		}
+		return node, nil
+	}
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber, tokString:
+		return &literalNode{value: tok.value}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null", "nil":
+			return &literalNode{value: nil}, nil
+		case "and", "or", "not", "in":
+			return nil, p.errorf(tok.pos, "unexpected %s", tok)
+		}
+		return &identNode{name: tok.text, pos: tok.pos}, nil
+	case tokOp:
+		switch tok.text {
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{items: items}, nil
+		}
+	}
+	return nil, p.errorf(tok.pos, "unexpected %s", tok)
+}
+
+// parseList parses comma separated expressions up to the closing operator
+func (p *exprParser) parseList(closing string) ([]exprNode, error) {
+	var items []exprNode
+	if _, ok := p.accept(closing); ok {
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if _, ok := p.accept(","); ok {
+			continue
+		}
+		if _, err := p.expect(closing); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+}
+
+// Nodes
+
+type exprNode interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type listNode struct {
+	items []exprNode
+}
+
+func (n *listNode) eval(vars map[string]interface{}) (interface{}, error) {
+	values := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+type identNode struct {
+	name string
+	pos  int
+}
+
+func (n *identNode) eval(vars map[string]interface{}) (interface{}, error) {
+	if value, ok := vars[n.name]; ok {
+		return value, nil
+	}
+	if fn, ok := builtinFuncs[n.name]; ok {
+		return fn, nil
+	}
+	return nil, &ExpressionError{Pos: n.pos, Msg: fmt.Sprintf("unknown identifier %q", n.name)}
+}
+
+type indexNode struct {
+	target exprNode
+	index  exprNode
+	pos    int
+}
+
+func (n *indexNode) eval(vars map[string]interface{}) (interface{}, error) {
+	target, err := n.target.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(target)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		key, ok := index.(string)
+		if !ok {
+			return nil, &ExpressionError{Pos: n.pos, Msg: fmt.Sprintf("map key must be a string, got %T", index)}
+		}
+		value := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return nil, nil
+		}
+		return value.Interface(), nil
+	case reflect.Struct:
+		key, ok := index.(string)
+		if !ok {
+			break
+		}
+		field := v.FieldByName(key)
+		if !field.IsValid() || !field.CanInterface() {
+			return nil, nil
+		}
+		return field.Interface(), nil
+	case reflect.Slice, reflect.Array, reflect.String:
+		f, ok := toFloat(index)
+		if !ok || f != math.Trunc(f) {
+			return nil, &ExpressionError{Pos: n.pos, Msg: fmt.Sprintf("index must be an integer, got %v", index)}
+		}
+		i := int(f)
+		if v.Kind() == reflect.String {
+			// Strings are indexed by character, not byte
+			runes := []rune(v.String())
+			if i < 0 || i >= len(runes) {
+				return nil, nil
+			}
+			return string(runes[i]), nil
+		}
+		if i < 0 || i >= v.Len() {
+			return nil, nil
+		}
+		return v.Index(i).Interface(), nil
+	}
+	return nil, &ExpressionError{Pos: n.pos, Msg: fmt.Sprintf("cannot index %T with %v", target, index)}
+}
+
+type callNode struct {
+	fn   exprNode
+	args []exprNode
+	pos  int
+}
+
+func (n *callNode) eval(vars map[string]interface{}) (interface{}, error) {
+	target, err := n.fn.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	var fn ExpressionFunc
+	switch f := target.(type) {
+	case ExpressionFunc:
+		fn = f
+	case func(args ...interface{}) (interface{}, error):
+		fn = f
+	default:
+		return nil, &ExpressionError{Pos: n.pos, Msg: fmt.Sprintf("%T is not a function", target)}
+	}
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		if args[i], err = arg.eval(vars); err != nil {
+			return nil, err
+		}
+	}
+	result, err := fn(args...)
+	if err != nil {
+		return nil, &ExpressionError{Pos: n.pos, Msg: err.Error()}
+	}
+	return result, nil
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+	pos     int
+}
+
+func (n *unaryNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !truthy(value), nil
+	}
+	f, ok := toFloat(value)
+	if !ok {
+		return nil, &ExpressionError{Pos: n.pos, Msg: fmt.Sprintf("cannot negate %T", value)}
+	}
+	return numberValue(-f), nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+	pos         int
+}
+
+func (n *binaryNode) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	// Logical operators short-circuit
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(vars)
+		return truthy(right), err
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(vars)
+		return truthy(right), err
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "in":
+		return n.contains(right, left)
+	case "<", "<=", ">", ">=":
+		return n.compare(left, right)
+	case "+":
+		if ls, ok := left.(string); ok {
+			if rs, ok := right.(string); ok {
+				return ls + rs, nil
+			}
+		}
+	}
+
+	l, lok := toFloat(left)
+	r, rok := toFloat(right)
+	if !lok || !rok {
+		return nil, &ExpressionError{Pos: n.pos, Msg: fmt.Sprintf("invalid operands %T and %T for %s", left, right, n.op)}
+	}
+	switch n.op {
+	case "+":
+		return numberValue(l + r), nil
+	case "-":
+		return numberValue(l - r), nil
+	case "*":
+		return numberValue(l * r), nil
+	case "/":
+		if r == 0 {
+			return nil, &ExpressionError{Pos: n.pos, Msg: "division by zero"}
+		}
+		return numberValue(l / r), nil
+	default:
+		if r == 0 {
+			return nil, &ExpressionError{Pos: n.pos, Msg: "division by zero"}
+		}
+		return numberValue(math.Mod(l, r)), nil
+	}
+}
+
+// compare orders numbers or strings
+func (n *binaryNode) compare(left, right interface{}) (interface{}, error) {
+	var c int
+	if l, ok := toFloat(left); ok {
+		r, ok := toFloat(right)
+		if !ok {
+			return nil, &ExpressionError{Pos: n.pos, Msg: fmt.Sprintf("cannot compare %T and %T", left, right)}
+		}
+		switch {
+		case l < r:
+			c = -1
+		case l > r:
+			c = 1
+		}
+	} else {
+		l, lok := left.(string)
+		r, rok := right.(string)
+		if !lok || !rok {
+			return nil, &ExpressionError{Pos: n.pos, Msg: fmt.Sprintf("cannot compare %T and %T", left, right)}
+		}
+		c = strings.Compare(l, r)
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// contains implements the in operator on lists, maps and strings
+func (n *binaryNode) contains(collection, item interface{}) (interface{}, error) {
+	if collection == nil {
+		return false, nil
+	}
+	v := reflect.ValueOf(collection)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if valuesEqual(v.Index(i).Interface(), item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		key, ok := item.(string)
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return false, nil
+		}
+		return v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())).IsValid(), nil
+	case reflect.String:
+		s, ok := item.(string)
+		return ok && strings.Contains(v.String(), s), nil
+	}
+	return nil, &ExpressionError{Pos: n.pos, Msg: fmt.Sprintf("cannot use in with %T", collection)}
+}
+
+// builtinFuncs are the functions available to every expression
+var builtinFuncs = map[string]ExpressionFunc{
+	"len": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("len expects 1 argument, got %d", len(args))
+		}
+		if args[0] == nil {
+			return 0, nil
+		}
+		v := reflect.ValueOf(args[0])
+		switch v.Kind() {
+		case reflect.String:
+			return utf8.RuneCountInString(v.String()), nil
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return v.Len(), nil
+		}
+		return nil, fmt.Errorf("len of %T", args[0])
+	},
+}
+
+// truthy reports whether a value counts as true in a condition
+func truthy(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if b, ok := value.(bool); ok {
+		return b
+	}
+	if f, ok := toFloat(value); ok {
+		return f != 0
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() > 0
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil()
+	}
+	return true
+}
+
+// valuesEqual compares values, numbers by value
+func valuesEqual(a, b interface{}) bool {
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		return ok && af == bf
+	}
+	// Named string types such as Place compare equal to strings
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	if av.IsValid() && bv.IsValid() && av.Kind() == reflect.String && bv.Kind() == reflect.String {
+		return av.String() == bv.String()
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// numberValue returns integral results as int
+func numberValue(f float64) interface{} {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int(f)
+	}
+	return f
+}
+
+// identifiers returns the names of the variables and functions the expression refers to
+func (e *Expression) identifiers() []string {
+	seen := make(map[string]bool)
+	var names []string
+	var walk func(n exprNode)
+	walk = func(n exprNode) {
+		switch n := n.(type) {
+		case *identNode:
+			if !seen[n.name] {
+				seen[n.name] = true
+				names = append(names, n.name)
+			}
+		case *listNode:
+			for _, item := range n.items {
+				walk(item)
+			}
+		case *indexNode:
+			walk(n.target)
+			walk(n.index)
+		case *callNode:
+			walk(n.fn)
+			for _, arg := range n.args {
+				walk(arg)
+			}
+		case *unaryNode:
+			walk(n.operand)
+		case *binaryNode:
+			walk(n.left)
+			walk(n.right)
+		}
+	}
+	walk(e.root)
+	return names
+}
@@ -0,0 +1,131 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+func TestExpression_Evaluate(t *testing.T) {
+	vars := map[string]interface{}{
+		"context": map[string]interface{}{
+			"amount":   150,
+			"reviewer": "alice",
+			"tags":     []interface{}{"urgent", "legal"},
+		},
+		"payload": map[string]interface{}{"comment": "ok", "score": 4.5},
+		"status":  workflow.Place("review"),
+	}
+
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"7 / 2", 3.5},
+		{"7 % 4", 3},
+		{"-context.amount + 50", -100},
+		{"context.amount > 100", true},
+		{"context.amount > 100 && context.reviewer == 'alice'", true},
+		{"context.amount < 100 || payload.score >= 4.5", true},
+		{"not context.missing", true},
+		{"!context.missing == true", true},
+		{"context.missing == null", true},
+		{`"legal" in context.tags`, true},
+		{"'x' in ['a', 'b']", false},
+		{"'comment' in payload", true},
+		{"len(context.tags)", 2},
+		{"context['reviewer'] + '!'", "alice!"},
+		{"context.tags[0]", "urgent"},
+		{"len('café')", 4},
+		{"'café'[3]", "é"},
+		{"status == 'review'", true},
+		{"context.amount > 100 and payload.comment", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := workflow.ParseExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseExpression() error = %v", err)
+			}
+			got, err := e.Evaluate(vars)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpression_Errors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		pos     int
+		parse   bool
+		message string
+	}{
+		{"1 +", 4, true, "unexpected end of expression"},
+		{"(a", 3, true, `expected ")", got end of expression`},
+		{"a # b", 3, true, `unexpected character '#'`},
+		{"'open", 1, true, "unterminated string"},
+		{"a b", 3, true, `unexpected "b"`},
+		{"unknown > 1", 1, false, `unknown identifier "unknown"`},
+		{"'a' < 1", 5, false, "cannot compare string and int"},
+		{"1 / 0", 3, false, "division by zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := workflow.ParseExpression(tt.expr)
+			if !tt.parse {
+				if err != nil {
+					t.Fatalf("ParseExpression() error = %v", err)
+				}
+				_, err = e.Evaluate(nil)
+			}
+			var exprErr *workflow.ExpressionError
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("expected an *ExpressionError, got %v", err)
+			}
+			if exprErr.Pos != tt.pos || exprErr.Msg != tt.message {
+				t.Errorf("error = %d: %s, want %d: %s", exprErr.Pos, exprErr.Msg, tt.pos, tt.message)
+			}
+		})
+	}
+}
+
+func TestExpressionConstraint(t *testing.T) {
+	approve := workflow.MustNewTransition("approve", []workflow.Place{"review"}, []workflow.Place{"approved"})
+	guard, err := workflow.NewExpressionConstraint("context.amount <= 1000 && payload.comment != null && marked('review')")
+	if err != nil {
+		t.Fatalf("NewExpressionConstraint() error = %v", err)
+	}
+	approve.AddConstraint(guard)
+
+	def, err := workflow.NewDefinition([]workflow.Place{"review", "approved"}, []workflow.Transition{*approve})
+	if err != nil {
+		t.Fatalf("NewDefinition() error = %v", err)
+	}
+	wf, err := workflow.NewWorkflow("wf", def, "review")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+
+	wf.SetContext("amount", 5000)
+	payload := map[string]interface{}{"comment": "fine"}
+	if err := wf.CanWithPayload(context.Background(), []workflow.Place{"approved"}, payload); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("expected ErrTransitionNotAllowed, got %v", err)
+	}
+
+	wf.SetContext("amount", 500)
+	if err := wf.CanWithPayload(context.Background(), []workflow.Place{"approved"}, nil); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("expected ErrTransitionNotAllowed without a comment, got %v", err)
+	}
+	if err := wf.ApplyWithPayload(context.Background(), []workflow.Place{"approved"}, payload); err != nil {
+		t.Errorf("ApplyWithPayload() error = %v", err)
+	}
+}
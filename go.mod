@@ -2,4 +2,7 @@ module github.com/euphoria-laxis/workflow
 
 go 1.25
 
-require github.com/mattn/go-sqlite3 v1.14.32
+require (
+	github.com/mattn/go-sqlite3 v1.14.32
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,371 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetadataPosition is the place and transition metadata key holding the
+// position of a node in a graph editor, as {"x": x, "y": y}
+const MetadataPosition = "position"
+
+// GraphNodeType is the type of a graph node
+type GraphNodeType string
+
+const (
+	// GraphPlace is a node representing a place
+	GraphPlace GraphNodeType = "place"
+	// GraphTransition is a node representing a transition
+	GraphTransition GraphNodeType = "transition"
+)
+
+// Graph is a node and edge representation of a definition, in the shape
+// expected by graph editors such as Cytoscape or React Flow
+type Graph struct {
+	Name     string                 `json:"name,omitempty" yaml:"name,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Nodes    []GraphNode            `json:"nodes" yaml:"nodes"`
+	Edges    []GraphEdge            `json:"edges" yaml:"edges"`
+}
+
+// GraphNode is a place or a transition of a graph
+type GraphNode struct {
+	// ID is "place:<name>" for places and "transition:<name>" for
+	// transitions, followed by ":<n>" for the nth transition sharing a name.
+	// Colons and percent signs in names are escaped as %3A and %25, so IDs
+	// are unique.
+	ID    string        `json:"id" yaml:"id"`
+	Type  GraphNodeType `json:"type" yaml:"type"`
+	Name  string        `json:"name" yaml:"name"`
+	Label string        `json:"label" yaml:"label"`
+	// Initial and Final are set on initial and final places
+	Initial bool `json:"initial,omitempty" yaml:"initial,omitempty"`
+	Final   bool `json:"final,omitempty" yaml:"final,omitempty"`
+	// Marked is set on the current places of a workflow
+	Marked bool `json:"marked,omitempty" yaml:"marked,omitempty"`
+	// Count is the number of instances in a place, when counts are given
+	Count *int `json:"count,omitempty" yaml:"count,omitempty"`
+	// Guard holds the guard expressions of a transition, joined with "&&".
+	// Other constraints are left out.
+	Guard string `json:"guard,omitempty" yaml:"guard,omitempty"`
+	// Blocked is set on transitions with constraints other than guard
+	// expressions, such as guard functions or untranslated Symfony guards.
+	// ImportGraph keeps such transitions blocked.
+	Blocked  bool                   `json:"blocked,omitempty" yaml:"blocked,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Position *GraphPosition         `json:"position,omitempty" yaml:"position,omitempty"`
+}
+
+// GraphPosition is the position of a node
+type GraphPosition struct {
+	X float64 `json:"x" yaml:"x"`
+	Y float64 `json:"y" yaml:"y"`
+}
+
+// GraphEdge is an arc between a place and a transition
+type GraphEdge struct {
+	// ID is "<source>--<target>"
+	ID     string `json:"id" yaml:"id"`
+	Source string `json:"source" yaml:"source"`
+	Target string `json:"target" yaml:"target"`
+}
+
+// GraphOptions configures graph export
+type GraphOptions struct {
+	// Layout computes positions for the nodes without a stored position,
+	// using the layered layout of SVG rendering
+	Layout bool
+	// Counts sets the number of instances in each place
+	Counts map[Place]int
+}
+
+// Graph exports the definition as a node and edge graph. Labels default to
+// names, and positions stored in the MetadataPosition metadata are returned
+// as node positions. Expression guards are exported, while other constraints,
+// such as guard functions, are left out and mark their transition as Blocked.
+func (d *Definition) Graph(opts GraphOptions) *Graph {
+	return d.graph(opts, nil)
+}
+
+// Graph exports the workflow definition as a node and edge graph, marking
+// its current places
+func (w *Workflow) Graph(opts GraphOptions) *Graph {
+	return w.Definition().graph(opts, w.CurrentPlaces())
+}
+
+// graph builds the graph of the definition with the given current places
+func (d *Definition) graph(opts GraphOptions, current []Place) *Graph {
+	g := &Graph{Name: d.name, Metadata: copyMetadata(d.metadata), Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	initial, final, marked := placeSet(d.initialPlaces), placeSet(d.finalPlaces), placeSet(current)
+
+	var layout []svgNode
+	if opts.Layout {
+		// Without initial places there is no start node, so node i of the
+		// layout is place i, or transition i-len(places)
+		var edges []svgEdge
+		layout, edges = d.svgGraph(SVGOptions{}, nil)
+		svgLayout(layout, edges)
+	}
+	position := func(i int, metadata map[string]interface{}) *GraphPosition {
+		if p, ok := graphPosition(metadata[MetadataPosition]); ok {
+			return p
+		}
+		if layout == nil {
+			return nil
+		}
+		return &GraphPosition{X: float64(layout[i].x), Y: float64(layout[i].y)}
+	}
+
+	for i, place := range d.places {
+		metadata := copyMetadata(d.placeMetadata[place])
+		node := GraphNode{
+			ID:       graphPlaceID(place),
+			Type:     GraphPlace,
+			Name:     string(place),
+			Label:    string(place),
+			Initial:  initial[place],
+			Final:    final[place],
+			Marked:   marked[place],
+			Position: position(i, metadata),
+		}
+		if label, ok := metadataString(metadata, MetadataLabel); ok {
+			node.Label = label
+		}
+		if count, ok := opts.Counts[place]; ok {
+			node.Count = &count
+		}
+		delete(metadata, MetadataPosition)
+		if len(metadata) > 0 {
+			node.Metadata = metadata
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+
+	used := make(map[string]bool)
+	for _, node := range g.Nodes {
+		used[node.ID] = true
+	}
+	for i, t := range d.transitions {
+		metadata := copyMetadata(t.metadata)
+		id := "transition:" + graphIDEscaper.Replace(t.Name())
+		for n := 2; used[id]; n++ {
+			id = fmt.Sprintf("transition:%s:%d", graphIDEscaper.Replace(t.Name()), n)
+		}
+		used[id] = true
+		node := GraphNode{
+			ID:       id,
+			Type:     GraphTransition,
+			Name:     t.Name(),
+			Label:    t.Name(),
+			Position: position(len(d.places)+i, metadata),
+		}
+		if label, ok := metadataString(metadata, MetadataLabel); ok {
+			node.Label = label
+		}
+		guard, complete := guardExpression(&t)
+		node.Guard = guard
+		node.Blocked = !complete
+		delete(metadata, MetadataPosition)
+		if len(metadata) > 0 {
+			node.Metadata = metadata
+		}
+		g.Nodes = append(g.Nodes, node)
+
+		for _, place := range t.From() {
+			g.Edges = append(g.Edges, GraphEdge{ID: graphPlaceID(place) + "--" + id, Source: graphPlaceID(place), Target: id})
+		}
+		for _, place := range t.To() {
+			g.Edges = append(g.Edges, GraphEdge{ID: id + "--" + graphPlaceID(place), Source: id, Target: graphPlaceID(place)})
+		}
+	}
+	return g
+}
+
+// ImportGraph rebuilds a definition from a graph in the JSON form of Graph,
+// such as one edited in a graph editor. Nodes are matched to edges by ID, so
+// new nodes can use any unique ID. Labels other than the name are kept in the
+// MetadataLabel metadata, positions in the MetadataPosition metadata, and
+// guards are parsed as guard expressions. Blocked transitions get a constraint
+// that always blocks them. Markings and counts are ignored.
+// Errors are *LoadError values carrying the line and column of the offending
+// node or edge.
+func ImportGraph(data []byte) (*Definition, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, &LoadError{Err: err}
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil, &LoadError{Err: fmt.Errorf("empty graph")}
+	}
+	var g Graph
+	if err := root.Content[0].Decode(&g); err != nil {
+		return nil, &LoadError{Err: err}
+	}
+
+	// Locate the nodes and edges to report errors
+	located := map[string][]*yaml.Node{}
+	doc := root.Content[0]
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if key := doc.Content[i].Value; key == "nodes" || key == "edges" {
+			located[key] = doc.Content[i+1].Content
+		}
+	}
+	at := func(list string, i int) *yaml.Node {
+		if i < len(located[list]) {
+			return located[list][i]
+		}
+		return doc
+	}
+	return g.definition(at)
+}
+
+// definition builds a definition from the graph
+func (g *Graph) definition(at func(list string, i int) *yaml.Node) (*Definition, error) {
+	type node struct {
+		index int
+		place Place
+		t     *Transition
+	}
+	byID := make(map[string]*node)
+	var places, initial, final []Place
+	var transitions []*node
+	opts := []DefinitionOption{WithName(g.Name)}
+	if len(g.Metadata) > 0 {
+		opts = append(opts, WithMetadata(g.Metadata))
+	}
+
+	for i, n := range g.Nodes {
+		if n.ID == "" {
+			return nil, yamlErrorf(at("nodes", i), "node has no id")
+		}
+		if _, ok := byID[n.ID]; ok {
+			return nil, yamlErrorf(at("nodes", i), "duplicate node id '%s'", n.ID)
+		}
+		if n.Name == "" {
+			return nil, yamlErrorf(at("nodes", i), "node '%s' has no name", n.ID)
+		}
+		metadata := copyMetadata(n.Metadata)
+		if metadata == nil {
+			metadata = make(map[string]interface{})
+		}
+		if n.Label != "" && n.Label != n.Name {
+			metadata[MetadataLabel] = n.Label
+		}
+		if n.Position != nil {
+			metadata[MetadataPosition] = map[string]interface{}{"x": n.Position.X, "y": n.Position.Y}
+		}
+
+		switch n.Type {
+		case GraphPlace:
+			place := Place(n.Name)
+			places = append(places, place)
+			if n.Initial {
+				initial = append(initial, place)
+			}
+			if n.Final {
+				final = append(final, place)
+			}
+			if len(metadata) > 0 {
+				opts = append(opts, WithPlaceMetadata(place, metadata))
+			}
+			byID[n.ID] = &node{index: i, place: place}
+		case GraphTransition:
+			t := &Transition{name: n.Name, metadata: metadata}
+			if n.Guard != "" {
+				guard, err := NewExpressionConstraint(n.Guard)
+				if err != nil {
+					return nil, yamlErrorf(at("nodes", i), "invalid guard: %v", err)
+				}
+				t.AddConstraint(guard)
+			}
+			if n.Blocked {
+				t.AddConstraint(blockedConstraint{})
+			}
+			byID[n.ID] = &node{index: i, t: t}
+			transitions = append(transitions, byID[n.ID])
+		default:
+			return nil, yamlErrorf(at("nodes", i), "node '%s' has unknown type %q", n.ID, n.Type)
+		}
+	}
+	opts = append(opts, WithInitialPlaces(initial...), WithFinalPlaces(final...))
+
+	arcs := make(map[[2]string]bool)
+	for i, e := range g.Edges {
+		source, ok := byID[e.Source]
+		if !ok {
+			return nil, yamlErrorf(at("edges", i), "edge refers to unknown node '%s'", e.Source)
+		}
+		target, ok := byID[e.Target]
+		if !ok {
+			return nil, yamlErrorf(at("edges", i), "edge refers to unknown node '%s'", e.Target)
+		}
+		if arcs[[2]string{e.Source, e.Target}] {
+			return nil, yamlErrorf(at("edges", i), "duplicate edge from '%s' to '%s'", e.Source, e.Target)
+		}
+		arcs[[2]string{e.Source, e.Target}] = true
+		switch {
+		case source.t == nil && target.t != nil:
+			target.t.from = append(target.t.from, source.place)
+		case source.t != nil && target.t == nil:
+			source.t.to = append(source.t.to, target.place)
+		default:
+			return nil, yamlErrorf(at("edges", i), "edge must connect a place and a transition")
+		}
+	}
+
+	var result []Transition
+	for _, n := range transitions {
+		t := n.t
+		if len(t.from) == 0 || len(t.to) == 0 {
+			return nil, yamlErrorf(at("nodes", n.index), "transition '%s' needs incoming and outgoing edges", t.name)
+		}
+		checked, err := NewTransition(t.name, t.from, t.to)
+		if err != nil {
+			return nil, yamlErrorf(at("nodes", n.index), "%v", err)
+		}
+		checked.metadata = t.metadata
+		checked.constraints = t.constraints
+		result = append(result, *checked)
+	}
+
+	definition, err := NewDefinition(places, result, opts...)
+	if err != nil {
+		return nil, &LoadError{Err: err}
+	}
+	return definition, nil
+}
+
+// graphIDEscaper escapes the names used in node IDs
+var graphIDEscaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+// graphPlaceID returns the node ID of a place
+func graphPlaceID(place Place) string {
+	return "place:" + graphIDEscaper.Replace(string(place))
+}
+
+// graphPosition reads a position stored in metadata
+func graphPosition(value interface{}) (*GraphPosition, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	x, xok := graphNumber(m["x"])
+	y, yok := graphNumber(m["y"])
+	if !xok || !yok {
+		return nil, false
+	}
+	return &GraphPosition{X: x, Y: y}, true
+}
+
+// graphNumber converts a decoded number to a float
+func graphNumber(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
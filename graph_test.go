@@ -0,0 +1,258 @@
+package workflow_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+func TestDefinition_Graph(t *testing.T) {
+	def, err := workflow.NewBuilder("order").
+		Place("new", "paid", "packed", "shipped").
+		InitialPlace("new").
+		FinalPlace("shipped").
+		PlaceMetadata("paid", workflow.MetadataLabel, "Paid").
+		PlaceMetadata("paid", workflow.MetadataPosition, map[string]interface{}{"x": 10, "y": 20}).
+		Transition("pay").From("new").To("paid", "packed").Metadata("cost", 2).
+		Transition("ship").From("paid", "packed").To("shipped").
+		Transition("ship").From("new").To("shipped").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	wf, err := workflow.NewWorkflow("o1", def, "new")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+
+	g := wf.Graph(workflow.GraphOptions{Counts: map[workflow.Place]int{"new": 0, "shipped": 4}})
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, want := range []string{
+		`{"id":"place:new","type":"place","name":"new","label":"new","initial":true,"marked":true,"count":0}`,
+		`{"id":"place:paid","type":"place","name":"paid","label":"Paid","metadata":{"label":"Paid"},"position":{"x":10,"y":20}}`,
+		`{"id":"place:shipped","type":"place","name":"shipped","label":"shipped","final":true,"count":4}`,
+		`{"id":"transition:pay","type":"transition","name":"pay","label":"pay","metadata":{"cost":2}}`,
+		`{"id":"transition:ship:2","type":"transition","name":"ship","label":"ship"}`,
+		`{"id":"place:packed--transition:ship","source":"place:packed","target":"transition:ship"}`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("graph does not contain %s:\n%s", want, data)
+		}
+	}
+
+	laid := def.Graph(workflow.GraphOptions{Layout: true})
+	for _, node := range laid.Nodes {
+		if node.Position == nil {
+			t.Errorf("node %s has no position", node.ID)
+		}
+	}
+	if laid.Nodes[1].Position.X != 10 {
+		t.Errorf("stored position should be kept, got %v", laid.Nodes[1].Position)
+	}
+	if !(laid.Nodes[0].Position.X < laid.Nodes[3].Position.X) {
+		t.Errorf("new should be laid out before shipped")
+	}
+
+	imported, err := workflow.ImportGraph(data)
+	if err != nil {
+		t.Fatalf("ImportGraph() error = %v", err)
+	}
+	if imported.Fingerprint() != def.Fingerprint() {
+		got, _ := imported.MarshalJSON()
+		want, _ := def.MarshalJSON()
+		t.Errorf("definition changed after round trip:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestDefinition_GraphUniqueIDs(t *testing.T) {
+	def := workflow.NewBuilder("ids").
+		Place("a", "b:c", "b%3Ac").
+		Transition("x").From("a").To("b:c").
+		Transition("x").From("a").To("b%3Ac").
+		Transition("x:2").From("b:c").To("a").
+		MustBuild()
+
+	g := def.Graph(workflow.GraphOptions{})
+	ids := make(map[string]bool)
+	for _, node := range g.Nodes {
+		if ids[node.ID] {
+			t.Errorf("duplicate node id %s", node.ID)
+		}
+		ids[node.ID] = true
+	}
+	for _, edge := range g.Edges {
+		if ids[edge.ID] {
+			t.Errorf("duplicate edge id %s", edge.ID)
+		}
+		ids[edge.ID] = true
+	}
+	if !ids["transition:x%3A2"] || !ids["transition:x:2"] || !ids["place:b%253Ac"] {
+		t.Errorf("unexpected ids: %v", ids)
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	imported, err := workflow.ImportGraph(data)
+	if err != nil {
+		t.Fatalf("ImportGraph() error = %v", err)
+	}
+	if imported.Fingerprint() != def.Fingerprint() {
+		t.Error("definition changed after round trip")
+	}
+}
+
+func TestDefinition_GraphMixedConstraints(t *testing.T) {
+	ready, err := workflow.NewExpressionConstraint("context.ready == true")
+	if err != nil {
+		t.Fatalf("NewExpressionConstraint() error = %v", err)
+	}
+	def := workflow.NewBuilder("mixed").
+		Place("a", "b").
+		Transition("go").From("a").To("b").
+		Constraint(ready).
+		Guard(func(workflow.Event) error { return nil }).
+		MustBuild()
+
+	g := def.Graph(workflow.GraphOptions{})
+	if guard := g.Nodes[2].Guard; guard != "context.ready == true" {
+		t.Errorf("guard = %q, want the expression guard", guard)
+	}
+	if !g.Nodes[2].Blocked {
+		t.Error("transition with a guard function should be marked as blocked")
+	}
+
+	// A transition blocked by an untranslated guard stays blocked
+	imported, err := workflow.ImportSymfony([]byte(`
+workflows:
+  door:
+    places: [closed, opened]
+    transitions:
+      open:
+        from: closed
+        to: opened
+        guard: "is_granted('ROLE_ADMIN')"
+`))
+	if err != nil {
+		t.Fatalf("ImportSymfony() error = %v", err)
+	}
+	data, err := json.Marshal(imported.Workflows[0].Definition.Graph(workflow.GraphOptions{}))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"blocked":true`) {
+		t.Errorf("graph should mark the transition as blocked:\n%s", data)
+	}
+	def, err = workflow.ImportGraph(data)
+	if err != nil {
+		t.Fatalf("ImportGraph() error = %v", err)
+	}
+	wf, err := workflow.NewWorkflow("d1", def, "closed")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	if err := wf.Apply([]workflow.Place{"opened"}); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("re-imported transition should stay blocked, got %v", err)
+	}
+}
+
+func TestImportGraph_Edited(t *testing.T) {
+	data := `{
+  "name": "tickets",
+  "nodes": [
+    {"id": "n1", "type": "place", "name": "open", "initial": true, "position": {"x": 0, "y": 0}},
+    {"id": "n2", "type": "place", "name": "closed", "label": "Closed", "final": true},
+    {"id": "n3", "type": "transition", "name": "close", "guard": "context.resolved == true"}
+  ],
+  "edges": [
+    {"id": "e1", "source": "n1", "target": "n3"},
+    {"id": "e2", "source": "n3", "target": "n2"}
+  ]
+}`
+	def, err := workflow.ImportGraph([]byte(data))
+	if err != nil {
+		t.Fatalf("ImportGraph() error = %v", err)
+	}
+	if label, _ := def.PlaceMetadata("closed", workflow.MetadataLabel); label != "Closed" {
+		t.Errorf("closed label = %v", label)
+	}
+	if _, ok := def.PlaceMetadata("open", workflow.MetadataPosition); !ok {
+		t.Error("position should be kept in metadata")
+	}
+
+	wf, err := workflow.NewWorkflow("t1", def, "open")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	if err := wf.Apply([]workflow.Place{"closed"}); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("expected the guard to block, got %v", err)
+	}
+	wf.SetContext("resolved", true)
+	if err := wf.Apply([]workflow.Place{"closed"}); err != nil {
+		t.Errorf("Apply() error = %v", err)
+	}
+}
+
+func TestImportGraph_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   string
+		edges   string
+		line    int
+		message string
+	}{
+		{
+			name:    "unknown node",
+			nodes:   `{"id": "a", "type": "place", "name": "a"}`,
+			edges:   `{"id": "e", "source": "a", "target": "t"}`,
+			line:    3,
+			message: "edge refers to unknown node 't'",
+		},
+		{
+			name:    "place to place",
+			nodes:   `{"id": "a", "type": "place", "name": "a"}, {"id": "b", "type": "place", "name": "b"}`,
+			edges:   `{"id": "e", "source": "a", "target": "b"}`,
+			line:    3,
+			message: "edge must connect a place and a transition",
+		},
+		{
+			name:    "unknown type",
+			nodes:   `{"id": "a", "type": "gateway", "name": "a"}`,
+			line:    2,
+			message: `node 'a' has unknown type "gateway"`,
+		},
+		{
+			name:    "dangling transition",
+			nodes:   `{"id": "a", "type": "place", "name": "a"}, {"id": "t", "type": "transition", "name": "go"}`,
+			edges:   `{"id": "e", "source": "a", "target": "t"}`,
+			line:    2,
+			message: "transition 'go' needs incoming and outgoing edges",
+		},
+		{
+			name:    "invalid guard",
+			nodes:   `{"id": "t", "type": "transition", "name": "go", "guard": "x >"}`,
+			line:    2,
+			message: "invalid guard",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "{\n\"nodes\": [" + tt.nodes + "],\n\"edges\": [" + tt.edges + "]\n}"
+			_, err := workflow.ImportGraph([]byte(data))
+			var loadErr *workflow.LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("expected a *LoadError, got %v", err)
+			}
+			if loadErr.Line != tt.line || !strings.Contains(loadErr.Error(), tt.message) {
+				t.Errorf("error = %v, want line %d containing %q", loadErr, tt.line, tt.message)
+			}
+		})
+	}
+}
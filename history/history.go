@@ -4,14 +4,16 @@ import "time"
 
 // TransitionRecord is the base struct for a transition event.
 type TransitionRecord struct {
-	WorkflowID   string
-	FromState    string
-	ToState      string
-	Transition   string
-	Notes        string
-	Actor        string
-	CreatedAt    time.Time
-	CustomFields map[string]interface{} // For custom columns, if any
+	WorkflowID        string
+	FromState         string
+	ToState           string
+	Transition        string
+	Notes             string
+	Actor             string
+	CreatedAt         time.Time
+	Payload           map[string]interface{} // Transition input payload, stored as JSON; callers copy it from Event.Payload
+	DefinitionVersion string                 // Version of the definition used, typically its fingerprint
+	CustomFields      map[string]interface{} // For custom columns, if any
 }
 
 // QueryOptions allows for pagination and filtering.
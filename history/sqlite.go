@@ -2,9 +2,12 @@ package history
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/euphoria-laxis/workflow/internal/sqlschema"
 )
 
 type SQLiteHistory struct {
@@ -35,6 +38,21 @@ func NewSQLiteHistory(db *sql.DB, opts ...Option) *SQLiteHistory {
 }
 
 func (h *SQLiteHistory) GenerateSchema() string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", h.table, strings.Join(h.columns(), ", "))
+}
+
+// Initialize creates the history table, then adds the columns missing from an
+// existing table, such as the payload column of a table created by an earlier version.
+func (h *SQLiteHistory) Initialize() error {
+	schema := h.GenerateSchema()
+	if _, err := h.db.Exec(schema); err != nil {
+		return err
+	}
+	return sqlschema.AddMissingColumns(h.db, h.table, h.columns())
+}
+
+// columns returns the column definitions of the history table
+func (h *SQLiteHistory) columns() []string {
 	columns := []string{
 		"id INTEGER PRIMARY KEY AUTOINCREMENT",
 		"workflow_id TEXT NOT NULL",
@@ -43,24 +61,34 @@ func (h *SQLiteHistory) GenerateSchema() string {
 		"transition TEXT NOT NULL",
 		"notes TEXT",
 		"actor TEXT",
+		"payload TEXT",
+		"definition_version TEXT",
 		"created_at DATETIME DEFAULT CURRENT_TIMESTAMP",
 	}
 	for _, colDef := range h.customFields {
 		columns = append(columns, colDef)
 	}
-	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", h.table, strings.Join(columns, ", "))
-}
-
-func (h *SQLiteHistory) Initialize() error {
-	schema := h.GenerateSchema()
-	_, err := h.db.Exec(schema)
-	return err
+	return columns
 }
 
 func (h *SQLiteHistory) SaveTransition(record *TransitionRecord) error {
-	cols := []string{"workflow_id", "from_state", "to_state", "transition", "notes", "actor", "created_at"}
-	vals := []interface{}{record.WorkflowID, record.FromState, record.ToState, record.Transition, record.Notes, record.Actor, record.CreatedAt.Format(time.RFC3339)}
-	placeholders := []string{"?", "?", "?", "?", "?", "?", "?"}
+	var payload interface{}
+	if record.Payload != nil {
+		payloadJSON, err := json.Marshal(record.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		payload = string(payloadJSON)
+	}
+
+	var definitionVersion interface{}
+	if record.DefinitionVersion != "" {
+		definitionVersion = record.DefinitionVersion
+	}
+
+	cols := []string{"workflow_id", "from_state", "to_state", "transition", "notes", "actor", "payload", "definition_version", "created_at"}
+	vals := []interface{}{record.WorkflowID, record.FromState, record.ToState, record.Transition, record.Notes, record.Actor, payload, definitionVersion, record.CreatedAt.Format(time.RFC3339)}
+	placeholders := []string{"?", "?", "?", "?", "?", "?", "?", "?", "?"}
 
 	// Add custom fields if present in record.CustomFields
 	for key := range h.customFields {
@@ -79,7 +107,7 @@ func (h *SQLiteHistory) SaveTransition(record *TransitionRecord) error {
 }
 
 func (h *SQLiteHistory) ListHistory(workflowID string, opts QueryOptions) ([]TransitionRecord, error) {
-	baseCols := []string{"workflow_id", "from_state", "to_state", "transition", "notes", "actor", "created_at"}
+	baseCols := []string{"workflow_id", "from_state", "to_state", "transition", "notes", "actor", "payload", "definition_version", "created_at"}
 	customCols := []string{}
 	for key := range h.customFields {
 		customCols = append(customCols, key)
@@ -123,7 +151,8 @@ func (h *SQLiteHistory) ListHistory(workflowID string, opts QueryOptions) ([]Tra
 	for rows.Next() {
 		var r TransitionRecord
 		var createdAt string
-		scanArgs := []interface{}{&r.WorkflowID, &r.FromState, &r.ToState, &r.Transition, &r.Notes, &r.Actor, &createdAt}
+		var payload, definitionVersion sql.NullString
+		scanArgs := []interface{}{&r.WorkflowID, &r.FromState, &r.ToState, &r.Transition, &r.Notes, &r.Actor, &payload, &definitionVersion, &createdAt}
 		customVals := make([]interface{}, len(customCols))
 		for i := range customVals {
 			customVals[i] = new(interface{})
@@ -133,6 +162,12 @@ func (h *SQLiteHistory) ListHistory(workflowID string, opts QueryOptions) ([]Tra
 			return nil, err
 		}
 		r.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		r.DefinitionVersion = definitionVersion.String
+		if payload.Valid {
+			if err := json.Unmarshal([]byte(payload.String), &r.Payload); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+			}
+		}
 		if len(customCols) > 0 {
 			r.CustomFields = make(map[string]interface{})
 			for i, col := range customCols {
@@ -48,6 +48,71 @@ func TestSQLiteHistory_Basic(t *testing.T) {
 	}
 }
 
+func TestSQLiteHistory_Payload(t *testing.T) {
+	db := setupTestDB(t)
+	h := NewSQLiteHistory(db)
+	if err := h.Initialize(); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
+	}
+
+	rec := &TransitionRecord{
+		WorkflowID: "wf1",
+		FromState:  "review",
+		ToState:    "approved",
+		Transition: "approve",
+		CreatedAt:  time.Now(),
+		Payload:    map[string]interface{}{"comment": "looks good", "amount": 120.5},
+	}
+	if err := h.SaveTransition(rec); err != nil {
+		t.Fatalf("failed to save transition: %v", err)
+	}
+
+	history, err := h.ListHistory("wf1", QueryOptions{})
+	if err != nil {
+		t.Fatalf("failed to list history: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(history))
+	}
+	if history[0].Payload["comment"] != "looks good" || history[0].Payload["amount"] != 120.5 {
+		t.Errorf("unexpected payload: %+v", history[0].Payload)
+	}
+}
+
+func TestSQLiteHistory_DefinitionVersion(t *testing.T) {
+	db := setupTestDB(t)
+	h := NewSQLiteHistory(db)
+	if err := h.Initialize(); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
+	}
+
+	for _, version := range []string{"v1", ""} {
+		rec := &TransitionRecord{
+			WorkflowID:        "wf1",
+			FromState:         "draft",
+			ToState:           "review",
+			Transition:        "submit",
+			CreatedAt:         time.Now(),
+			DefinitionVersion: version,
+		}
+		if err := h.SaveTransition(rec); err != nil {
+			t.Fatalf("failed to save transition: %v", err)
+		}
+	}
+
+	history, err := h.ListHistory("wf1", QueryOptions{})
+	if err != nil {
+		t.Fatalf("failed to list history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(history))
+	}
+	// Records are listed newest first
+	if history[0].DefinitionVersion != "" || history[1].DefinitionVersion != "v1" {
+		t.Errorf("unexpected definition versions: %q, %q", history[0].DefinitionVersion, history[1].DefinitionVersion)
+	}
+}
+
 func TestSQLiteHistory_CustomFields(t *testing.T) {
 	db := setupTestDB(t)
 	h := NewSQLiteHistory(db, WithCustomFields(map[string]string{
@@ -134,3 +199,37 @@ func TestSQLiteHistory_PaginationAndFiltering(t *testing.T) {
 		t.Errorf("expected 10 records, got %d", len(hist3))
 	}
 }
+
+func TestSQLiteHistory_MigratesOldSchema(t *testing.T) {
+	db := setupTestDB(t)
+	if _, err := db.Exec(`CREATE TABLE transition_history (id INTEGER PRIMARY KEY AUTOINCREMENT, workflow_id TEXT NOT NULL, from_state TEXT NOT NULL, to_state TEXT NOT NULL, transition TEXT NOT NULL, notes TEXT, actor TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("failed to create old table: %v", err)
+	}
+	h := NewSQLiteHistory(db)
+	if err := h.Initialize(); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
+	}
+	if err := h.Initialize(); err != nil {
+		t.Fatalf("failed to initialize schema twice: %v", err)
+	}
+
+	rec := &TransitionRecord{
+		WorkflowID:        "wf1",
+		FromState:         "review",
+		ToState:           "approved",
+		Transition:        "approve",
+		CreatedAt:         time.Now(),
+		Payload:           map[string]interface{}{"comment": "ok"},
+		DefinitionVersion: "v1",
+	}
+	if err := h.SaveTransition(rec); err != nil {
+		t.Fatalf("failed to save transition: %v", err)
+	}
+	history, err := h.ListHistory("wf1", QueryOptions{})
+	if err != nil {
+		t.Fatalf("failed to list history: %v", err)
+	}
+	if len(history) != 1 || history[0].Payload["comment"] != "ok" || history[0].DefinitionVersion != "v1" {
+		t.Errorf("unexpected history: %+v", history)
+	}
+}
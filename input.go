@@ -0,0 +1,155 @@
+package workflow
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// FieldType represents the type of a transition input field
+type FieldType string
+
+const (
+	// FieldAny accepts any value
+	FieldAny FieldType = "any"
+	// FieldString accepts string values
+	FieldString FieldType = "string"
+	// FieldNumber accepts integer and floating point values
+	FieldNumber FieldType = "number"
+	// FieldInteger accepts integer values, including integral floats decoded from JSON
+	FieldInteger FieldType = "integer"
+	// FieldBool accepts boolean values
+	FieldBool FieldType = "bool"
+)
+
+// InputField describes a single field of a transition input payload
+type InputField struct {
+	Name     string
+	Type     FieldType
+	Required bool
+	// Enum restricts the field to the listed values when not empty
+	Enum []interface{}
+}
+
+// InputSchema describes the payload accepted by a transition
+type InputSchema struct {
+	Fields []InputField
+}
+
+// NewInputSchema creates a new input schema from the given fields
+func NewInputSchema(fields ...InputField) *InputSchema {
+	return &InputSchema{Fields: fields}
+}
+
+// Field returns the field with the given name
+func (s *InputSchema) Field(name string) (InputField, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return InputField{}, false
+}
+
+// FieldViolation describes why a payload field is invalid
+type FieldViolation struct {
+	Field  string
+	Reason string
+}
+
+// PayloadError is returned when a transition payload does not match the transition input schema
+type PayloadError struct {
+	Transition string
+	Violations []FieldViolation
+}
+
+// Error implements the error interface
+func (e *PayloadError) Error() string {
+	reasons := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		reasons[i] = fmt.Sprintf("%s: %s", v.Field, v.Reason)
+	}
+	return fmt.Sprintf("invalid payload for transition '%s': %s", e.Transition, strings.Join(reasons, "; "))
+}
+
+// Unwrap returns ErrInvalidPayload so callers can use errors.Is
+func (e *PayloadError) Unwrap() error {
+	return ErrInvalidPayload
+}
+
+// Validate checks the payload against the schema and returns every violation
+func (s *InputSchema) Validate(payload map[string]interface{}) []FieldViolation {
+	var violations []FieldViolation
+	for _, field := range s.Fields {
+		value, ok := payload[field.Name]
+		if !ok || value == nil {
+			if field.Required {
+				violations = append(violations, FieldViolation{Field: field.Name, Reason: "is required"})
+			}
+			continue
+		}
+		if !field.Type.accepts(value) {
+			violations = append(violations, FieldViolation{
+				Field:  field.Name,
+				Reason: fmt.Sprintf("must be of type %s, got %T", field.Type, value),
+			})
+			continue
+		}
+		if len(field.Enum) > 0 && !enumContains(field.Enum, value) {
+			violations = append(violations, FieldViolation{
+				Field:  field.Name,
+				Reason: fmt.Sprintf("must be one of %v, got %v", field.Enum, value),
+			})
+		}
+	}
+	return violations
+}
+
+// accepts checks whether the value matches the field type
+func (t FieldType) accepts(value interface{}) bool {
+	switch t {
+	case FieldString:
+		return reflect.ValueOf(value).Kind() == reflect.String
+	case FieldNumber:
+		_, ok := toFloat(value)
+		return ok
+	case FieldInteger:
+		f, ok := toFloat(value)
+		return ok && f == math.Trunc(f)
+	case FieldBool:
+		return reflect.ValueOf(value).Kind() == reflect.Bool
+	default:
+		return true
+	}
+}
+
+// toFloat converts numeric values to float64
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// enumContains checks whether the value is one of the allowed values, comparing numbers by value
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if a, ok := toFloat(allowed); ok {
+			if v, ok := toFloat(value); ok && a == v {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+var inputTestDefinition = workflow.NewBuilder("approval").
+	Place("review", "approved").
+	Transition("approve").From("review").To("approved").
+	Input(
+		workflow.InputField{Name: "comment", Type: workflow.FieldString, Required: true},
+		workflow.InputField{Name: "amount", Type: workflow.FieldNumber},
+		workflow.InputField{Name: "level", Type: workflow.FieldString, Enum: []interface{}{"low", "high"}},
+	).
+	MustBuild()
+
+func TestWorkflow_ApplyWithPayload(t *testing.T) {
+	tests := []struct {
+		name           string
+		payload        map[string]interface{}
+		wantErr        bool
+		wantViolations []string
+	}{
+		{
+			name:    "valid payload",
+			payload: map[string]interface{}{"comment": "approved by finance", "amount": 10, "level": "high"},
+		},
+		{
+			name:           "missing required field",
+			payload:        map[string]interface{}{"amount": 10},
+			wantErr:        true,
+			wantViolations: []string{"comment"},
+		},
+		{
+			name:           "nil payload",
+			payload:        nil,
+			wantErr:        true,
+			wantViolations: []string{"comment"},
+		},
+		{
+			name:           "wrong types and enum",
+			payload:        map[string]interface{}{"comment": 1, "amount": "ten", "level": "medium"},
+			wantErr:        true,
+			wantViolations: []string{"comment", "amount", "level"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf, err := workflow.NewWorkflow("test", inputTestDefinition, "review")
+			if err != nil {
+				t.Fatalf("failed to create workflow: %v", err)
+			}
+			guardCalled := false
+			var guardPayload, afterPayload map[string]interface{}
+			wf.AddGuardEventListener(func(event *workflow.GuardEvent) error {
+				guardCalled = true
+				guardPayload = event.Payload()
+				return nil
+			})
+			wf.AddEventListener(workflow.EventAfterTransition, func(event workflow.Event) error {
+				afterPayload = event.Payload()
+				return nil
+			})
+
+			err = wf.ApplyWithPayload(context.Background(), []workflow.Place{"approved"}, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyWithPayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				// Listeners see the payload the transition was applied with
+				if guardPayload["comment"] != tt.payload["comment"] || afterPayload["comment"] != tt.payload["comment"] {
+					t.Errorf("guard payload = %v, after transition payload = %v", guardPayload, afterPayload)
+				}
+				return
+			}
+			if !errors.Is(err, workflow.ErrInvalidPayload) {
+				t.Errorf("ApplyWithPayload() error = %v, want ErrInvalidPayload", err)
+			}
+			if guardCalled {
+				t.Error("guard was called for an invalid payload")
+			}
+			var payloadErr *workflow.PayloadError
+			if !errors.As(err, &payloadErr) {
+				t.Fatalf("ApplyWithPayload() error = %T, want *PayloadError", err)
+			}
+			if len(payloadErr.Violations) != len(tt.wantViolations) {
+				t.Fatalf("violations = %+v, want fields %v", payloadErr.Violations, tt.wantViolations)
+			}
+			for i, field := range tt.wantViolations {
+				if payloadErr.Violations[i].Field != field {
+					t.Errorf("violation %d field = %s, want %s", i, payloadErr.Violations[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkflow_CanWithPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		can     func(wf *workflow.Workflow) error
+		wantErr error
+	}{
+		{
+			name: "Can skips payload validation",
+			can:  func(wf *workflow.Workflow) error { return wf.Can([]workflow.Place{"approved"}) },
+		},
+		{
+			name: "CanWithPayload validates the payload",
+			can: func(wf *workflow.Workflow) error {
+				return wf.CanWithPayload(context.Background(), []workflow.Place{"approved"}, nil)
+			},
+			wantErr: workflow.ErrInvalidPayload,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf, err := workflow.NewWorkflow("test", inputTestDefinition, "review")
+			if err != nil {
+				t.Fatalf("failed to create workflow: %v", err)
+			}
+			if err := tt.can(wf); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Package sqlschema holds the SQLite schema helpers shared by the storage and
+// history packages.
+package sqlschema
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// AddMissingColumns adds the columns missing from an existing table. Each
+// column is a definition starting with the column name, such as "notes TEXT".
+func AddMissingColumns(db *sql.DB, table string, columns []string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid          int
+			name, kind   string
+			notNull, pk  int
+			defaultValue interface{}
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[strings.ToLower(name)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		if existing[strings.ToLower(strings.Fields(column)[0])] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, column)); err != nil {
+			return fmt.Errorf("failed to add column to %s: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package workflow
+
+import (
+	"fmt"
+)
+
+// Invariant is a property that every marking of a workflow must satisfy
+type Invariant interface {
+	// Name identifies the invariant in errors and diagnostics
+	Name() string
+	// Places returns the places the invariant refers to
+	Places() []Place
+	// Check returns an error describing the violation when the marking does not satisfy the invariant
+	Check(marking []Place) error
+}
+
+// InvariantError is returned when a marking violates an invariant
+type InvariantError struct {
+	Invariant string
+	Marking   []Place
+	Reason    string
+}
+
+// Error implements the error interface
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("invariant '%s' violated by marking [%s]: %s", e.Invariant, joinPlaces(e.Marking), e.Reason)
+}
+
+// Unwrap returns ErrInvariantViolated so callers can use errors.Is
+func (e *InvariantError) Unwrap() error {
+	return ErrInvariantViolated
+}
+
+// funcInvariant implements Invariant with a check function
+type funcInvariant struct {
+	name   string
+	places []Place
+	check  func(marking []Place) error
+	// kind identifies built-in invariants so that they can be serialized
+	kind string
+}
+
+// NewInvariant creates an invariant from a check function
+func NewInvariant(name string, places []Place, check func(marking []Place) error) Invariant {
+	return &funcInvariant{name: name, places: append([]Place(nil), places...), check: check}
+}
+
+// Name implements Invariant
+func (i *funcInvariant) Name() string {
+	return i.name
+}
+
+// Places implements Invariant
+func (i *funcInvariant) Places() []Place {
+	places := make([]Place, len(i.places))
+	copy(places, i.places)
+	return places
+}
+
+// Check implements Invariant
+func (i *funcInvariant) Check(marking []Place) error {
+	return i.check(marking)
+}
+
+// Kinds of built-in invariants
+const (
+	invariantMutuallyExclusive = "mutually_exclusive"
+	invariantExactlyOne        = "exactly_one"
+)
+
+// newBuiltinInvariant creates an invariant of a built-in kind
+func newBuiltinInvariant(kind, name string, places []Place, check func(marking []Place) error) Invariant {
+	return &funcInvariant{name: name, places: append([]Place(nil), places...), check: check, kind: kind}
+}
+
+// MutuallyExclusive returns an invariant requiring that at most one of the places is marked
+func MutuallyExclusive(places ...Place) Invariant {
+	name := fmt.Sprintf("%s(%s)", invariantMutuallyExclusive, joinPlaces(places))
+	return newBuiltinInvariant(invariantMutuallyExclusive, name, places, func(marking []Place) error {
+		if marked := markedAmong(marking, places); len(marked) > 1 {
+			return fmt.Errorf("[%s] are marked together", joinPlaces(marked))
+		}
+		return nil
+	})
+}
+
+// ExactlyOne returns an invariant requiring that exactly one of the places is marked
+func ExactlyOne(places ...Place) Invariant {
+	name := fmt.Sprintf("%s(%s)", invariantExactlyOne, joinPlaces(places))
+	return newBuiltinInvariant(invariantExactlyOne, name, places, func(marking []Place) error {
+		switch marked := markedAmong(marking, places); len(marked) {
+		case 1:
+			return nil
+		case 0:
+			return fmt.Errorf("none of [%s] is marked", joinPlaces(places))
+		default:
+			return fmt.Errorf("[%s] are marked together", joinPlaces(marked))
+		}
+	})
+}
+
+// WithInvariants declares invariants checked on every marking of the workflow
+func WithInvariants(invariants ...Invariant) DefinitionOption {
+	return func(d *Definition) {
+		d.invariants = append(d.invariants, invariants...)
+	}
+}
+
+// Invariants returns the invariants declared on the definition
+func (d *Definition) Invariants() []Invariant {
+	invariants := make([]Invariant, len(d.invariants))
+	copy(invariants, d.invariants)
+	return invariants
+}
+
+// checkInvariants checks the marking against all invariants of the definition
+func (d *Definition) checkInvariants(marking []Place) error {
+	for _, invariant := range d.invariants {
+		if err := invariant.Check(marking); err != nil {
+			return &InvariantError{Invariant: invariant.Name(), Marking: marking, Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// markedAmong returns the places that are marked, in the order they are given
+func markedAmong(marking []Place, places []Place) []Place {
+	set := placeSet(marking)
+	var marked []Place
+	for _, place := range places {
+		if set[place] {
+			marked = append(marked, place)
+		}
+	}
+	return marked
+}
@@ -0,0 +1,136 @@
+package workflow_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+var invariantReviewDefinition = workflow.NewBuilder("review").
+	Place("review", "approved", "rejected").
+	Option(workflow.WithInvariants(workflow.MutuallyExclusive("approved", "rejected"))).
+	Transition("approve").From("review").To("approved").
+	Transition("reject_both").From("review").To("approved", "rejected").
+	MustBuild()
+
+func TestWorkflow_InvariantOnApply(t *testing.T) {
+	wf, err := workflow.NewWorkflow("review-1", invariantReviewDefinition, "review")
+	if err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+
+	afterCalled := false
+	wf.AddEventListener(workflow.EventAfterTransition, func(event workflow.Event) error {
+		afterCalled = true
+		return nil
+	})
+
+	err = wf.Apply([]workflow.Place{"approved", "rejected"})
+	if !errors.Is(err, workflow.ErrInvariantViolated) {
+		t.Fatalf("Apply() error = %v, want ErrInvariantViolated", err)
+	}
+	var invErr *workflow.InvariantError
+	if !errors.As(err, &invErr) || invErr.Invariant != "mutually_exclusive(approved, rejected)" {
+		t.Errorf("Apply() error = %v, want *InvariantError for mutually_exclusive", err)
+	}
+	if places := wf.CurrentPlaces(); len(places) != 1 || places[0] != "review" {
+		t.Errorf("CurrentPlaces() = %v, want [review]", places)
+	}
+	if afterCalled {
+		t.Error("after transition event fired for a rejected marking")
+	}
+
+	if err := wf.Apply([]workflow.Place{"approved"}); err != nil {
+		t.Errorf("Apply() error = %v, want nil", err)
+	}
+}
+
+func TestWorkflow_InvariantOnMarking(t *testing.T) {
+	def, err := workflow.NewDefinition(
+		[]workflow.Place{"draft", "review", "done", "archived"},
+		[]workflow.Transition{},
+		workflow.WithInvariants(workflow.ExactlyOne("draft", "review", "done")),
+	)
+	if err != nil {
+		t.Fatalf("failed to create definition: %v", err)
+	}
+	setMarking := func(places ...workflow.Place) func() error {
+		return func() error {
+			wf, err := workflow.NewWorkflow("doc", def, "draft")
+			if err != nil {
+				return err
+			}
+			return wf.SetMarking(workflow.NewMarking(places))
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mark    func() error
+		wantErr error
+	}{
+		{
+			name: "valid initial marking",
+			mark: func() error { _, err := workflow.NewWorkflow("doc", def, "draft"); return err },
+		},
+		{
+			name:    "invalid initial marking",
+			mark:    func() error { _, err := workflow.NewWorkflow("doc", def, "archived"); return err },
+			wantErr: workflow.ErrInvariantViolated,
+		},
+		{
+			name: "invalid marking store",
+			mark: func() error {
+				store := workflow.NewFuncMarkingStore(
+					func() []workflow.Place { return []workflow.Place{"draft", "review"} },
+					func([]workflow.Place) error { return nil },
+				)
+				_, err := workflow.NewWorkflowWithMarkingStore("doc", def, "draft", store)
+				return err
+			},
+			wantErr: workflow.ErrInvariantViolated,
+		},
+		{name: "set empty marking", mark: setMarking(), wantErr: workflow.ErrInvariantViolated},
+		{name: "set two places", mark: setMarking("draft", "done"), wantErr: workflow.ErrInvariantViolated},
+		{name: "set valid marking", mark: setMarking("review")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mark(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewDefinition_InvariantUnknownPlace(t *testing.T) {
+	_, err := workflow.NewDefinition(
+		[]workflow.Place{"draft"},
+		[]workflow.Transition{},
+		workflow.WithInvariants(workflow.MutuallyExclusive("draft", "missing")),
+	)
+	if err == nil {
+		t.Error("NewDefinition() error = nil, want error")
+	}
+}
+
+func TestAnalyze_Invariants(t *testing.T) {
+	var found *workflow.Diagnostic
+	for _, d := range workflow.Analyze(invariantReviewDefinition) {
+		if d.Code == workflow.DiagnosticInvariantViolation {
+			d := d
+			found = &d
+		}
+	}
+	if found == nil {
+		t.Fatal("Analyze() reported no invariant violation")
+	}
+	if found.Severity != workflow.SeverityError {
+		t.Errorf("Severity = %s, want error", found.Severity)
+	}
+	if len(found.Transitions) != 1 || found.Transitions[0] != "reject_both" {
+		t.Errorf("Transitions = %v, want [reject_both]", found.Transitions)
+	}
+}
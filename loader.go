@@ -0,0 +1,803 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a definition file format
+type Format string
+
+const (
+	// FormatYAML is the YAML definition format
+	FormatYAML Format = "yaml"
+	// FormatJSON is the JSON definition format
+	FormatJSON Format = "json"
+)
+
+// Catalog holds the constraints and listeners that definition files refer to by name
+type Catalog struct {
+	constraints map[string]Constraint
+	listeners   map[string]interface{}
+	mu          sync.RWMutex
+}
+
+// NewCatalog creates an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{
+		constraints: make(map[string]Constraint),
+		listeners:   make(map[string]interface{}),
+	}
+}
+
+// RegisterConstraint registers a constraint under a name
+func (c *Catalog) RegisterConstraint(name string, constraint Constraint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.constraints[name] = constraint
+}
+
+// RegisterListener registers an event listener under a name
+func (c *Catalog) RegisterListener(name string, listener EventListener) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners[name] = listener
+}
+
+// RegisterGuardListener registers a guard event listener under a name
+func (c *Catalog) RegisterGuardListener(name string, listener GuardEventListener) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners[name] = listener
+}
+
+// constraint returns a registered constraint
+func (c *Catalog) constraint(name string) (Constraint, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	constraint, ok := c.constraints[name]
+	return constraint, ok
+}
+
+// listener returns a registered listener
+func (c *Catalog) listener(name string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	listener, ok := c.listeners[name]
+	return listener, ok
+}
+
+// namedConstraint is a catalog constraint remembering its name for export
+type namedConstraint struct {
+	name string
+	Constraint
+}
+
+// LoadError is returned when a definition file is invalid
+type LoadError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+// Error implements the error interface
+func (e *LoadError) Error() string {
+	if e.Line == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// placeJSON is the serialized form of a place
+type placeJSON struct {
+	Name     Place                  `json:"name" yaml:"name"`
+	Metadata map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Requires []requirementJSON      `json:"requires,omitempty" yaml:"requires,omitempty"`
+}
+
+// requirementJSON is the serialized form of a place requirement
+type requirementJSON struct {
+	Key  string    `json:"key" yaml:"key"`
+	Type FieldType `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+// inputFieldJSON is the serialized form of a transition input field
+type inputFieldJSON struct {
+	Name     string        `json:"name" yaml:"name"`
+	Type     FieldType     `json:"type,omitempty" yaml:"type,omitempty"`
+	Required bool          `json:"required,omitempty" yaml:"required,omitempty"`
+	Enum     []interface{} `json:"enum,omitempty" yaml:"enum,omitempty"`
+}
+
+// transitionJSON is the serialized form of a transition
+type transitionJSON struct {
+	Name        string                 `json:"name" yaml:"name"`
+	From        []Place                `json:"from" yaml:"from"`
+	To          []Place                `json:"to" yaml:"to"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Guard       string                 `json:"guard,omitempty" yaml:"guard,omitempty"`
+	Constraints []string               `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+	Input       []inputFieldJSON       `json:"input,omitempty" yaml:"input,omitempty"`
+}
+
+// invariantJSON is the serialized form of a built-in invariant
+type invariantJSON struct {
+	Type   string  `json:"type" yaml:"type"`
+	Places []Place `json:"places" yaml:"places"`
+}
+
+// listenerJSON is the serialized form of a named definition listener
+type listenerJSON struct {
+	Event    EventType `json:"event" yaml:"event"`
+	Listener string    `json:"listener" yaml:"listener"`
+}
+
+// definitionJSON is the serialized form of a definition
+type definitionJSON struct {
+	Name          string                 `json:"name,omitempty" yaml:"name,omitempty"`
+	Version       string                 `json:"version,omitempty" yaml:"version,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	InitialPlaces []Place                `json:"initial_places,omitempty" yaml:"initial_places,omitempty"`
+	FinalPlaces   []Place                `json:"final_places,omitempty" yaml:"final_places,omitempty"`
+	Places        []placeJSON            `json:"places" yaml:"places"`
+	Transitions   []transitionJSON       `json:"transitions" yaml:"transitions"`
+	Invariants    []invariantJSON        `json:"invariants,omitempty" yaml:"invariants,omitempty"`
+	Listeners     []listenerJSON         `json:"listeners,omitempty" yaml:"listeners,omitempty"`
+}
+
+// document returns the serialized form of the definition. In strict mode,
+// parts that cannot be serialized are reported as errors instead of being left out.
+func (d *Definition) document(strict bool) (*definitionJSON, error) {
+	doc := &definitionJSON{
+		Name:          d.name,
+		Version:       d.version,
+		InitialPlaces: d.InitialPlaces(),
+		FinalPlaces:   d.FinalPlaces(),
+		Places:        make([]placeJSON, 0, len(d.places)),
+		Transitions:   make([]transitionJSON, 0, len(d.transitions)),
+		Listeners:     append([]listenerJSON(nil), d.listenerRefs...),
+	}
+	if len(d.metadata) > 0 {
+		doc.Metadata = d.AllMetadata()
+	}
+
+	for _, place := range d.places {
+		p := placeJSON{Name: place}
+		if len(d.placeMetadata[place]) > 0 {
+			p.Metadata = d.AllPlaceMetadata(place)
+		}
+		for _, req := range d.requirements[place] {
+			if req.Validator != nil && req.Type == "" && strict {
+				return nil, fmt.Errorf("requirement '%s' of place '%s' has a validator that cannot be serialized", req.Key, place)
+			}
+			p.Requires = append(p.Requires, requirementJSON{Key: req.Key, Type: req.Type})
+		}
+		doc.Places = append(doc.Places, p)
+	}
+
+	for _, t := range d.transitions {
+		tr := transitionJSON{Name: t.Name(), From: t.From(), To: t.To()}
+		if len(t.metadata) > 0 {
+			tr.Metadata = t.AllMetadata()
+		}
+		tr.Guard, _ = guardExpression(&t)
+		for _, c := range t.constraints {
+			switch c := c.(type) {
+			case *ExpressionConstraint:
+				// Exported as the guard
+			case *namedConstraint:
+				tr.Constraints = append(tr.Constraints, c.name)
+			default:
+				if strict {
+					return nil, fmt.Errorf("transition '%s' has a constraint that cannot be serialized", t.Name())
+				}
+			}
+		}
+		if t.inputSchema != nil {
+			for _, f := range t.inputSchema.Fields {
+				tr.Input = append(tr.Input, inputFieldJSON{Name: f.Name, Type: f.Type, Required: f.Required, Enum: f.Enum})
+			}
+		}
+		doc.Transitions = append(doc.Transitions, tr)
+	}
+
+	for _, invariant := range d.invariants {
+		builtin, ok := invariant.(*funcInvariant)
+		if !ok || builtin.kind == "" {
+			if strict {
+				return nil, fmt.Errorf("invariant '%s' cannot be serialized", invariant.Name())
+			}
+			continue
+		}
+		doc.Invariants = append(doc.Invariants, invariantJSON{Type: builtin.kind, Places: builtin.Places()})
+	}
+	return doc, nil
+}
+
+// ExportDefinition serializes a definition in the definition file format.
+// Constraints must be guard expressions or catalog constraints and invariants
+// must be built-in ones; listeners not loaded from a catalog are left out.
+func ExportDefinition(definition *Definition, format Format) ([]byte, error) {
+	doc, err := definition.document(true)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatYAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// LoadDefinitionFile loads a definition from a YAML or JSON file
+func LoadDefinitionFile(path string, catalog *Catalog) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	definition, err := LoadDefinition(data, catalog)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return definition, nil
+}
+
+// LoadDefinition loads a definition from YAML or JSON data. Guards are parsed as
+// expressions; constraints and listeners are looked up by name in the catalog,
+// which may be nil when the file does not refer to any. Errors are *LoadError
+// values carrying the line and column of the offending element.
+func LoadDefinition(data []byte, catalog *Catalog) (*Definition, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, &LoadError{Err: err}
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil, &LoadError{Err: fmt.Errorf("empty definition")}
+	}
+
+	l := &loader{catalog: catalog, places: make(map[Place]bool)}
+	return l.definition(root.Content[0])
+}
+
+// loader builds a definition from a YAML node tree
+type loader struct {
+	catalog *Catalog
+	places  map[Place]bool
+}
+
+// yamlErrorf returns a load error located at the node
+func yamlErrorf(node *yaml.Node, format string, args ...interface{}) error {
+	return &LoadError{Line: node.Line, Column: node.Column, Err: fmt.Errorf(format, args...)}
+}
+
+// yamlFields returns the values of a mapping node by key, rejecting unknown and duplicate keys
+func yamlFields(node *yaml.Node, allowed ...string) (map[string]*yaml.Node, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, yamlErrorf(node, "expected a mapping")
+	}
+	result := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		known := false
+		for _, a := range allowed {
+			if key.Value == a {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, yamlErrorf(key, "unknown field %q", key.Value)
+		}
+		if _, dup := result[key.Value]; dup {
+			return nil, yamlErrorf(key, "duplicate field %q", key.Value)
+		}
+		result[key.Value] = value
+	}
+	return result, nil
+}
+
+// yamlScalar decodes a string scalar
+func yamlScalar(node *yaml.Node) (string, error) {
+	if node.Kind != yaml.ScalarNode {
+		return "", yamlErrorf(node, "expected a string")
+	}
+	return node.Value, nil
+}
+
+// yamlScalarList decodes a single scalar or a sequence of scalars
+func yamlScalarList(node *yaml.Node) ([]string, error) {
+	if node.Kind == yaml.ScalarNode {
+		return []string{node.Value}, nil
+	}
+	if node.Kind != yaml.SequenceNode {
+		return nil, yamlErrorf(node, "expected a string or a list of strings")
+	}
+	values := make([]string, 0, len(node.Content))
+	for _, item := range node.Content {
+		value, err := yamlScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// yamlSequence returns the items of a sequence node
+func yamlSequence(node *yaml.Node) ([]*yaml.Node, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, yamlErrorf(node, "expected a list")
+	}
+	return node.Content, nil
+}
+
+// yamlMetadata decodes a metadata mapping
+func yamlMetadata(node *yaml.Node) (map[string]interface{}, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, yamlErrorf(node, "metadata must be a mapping")
+	}
+	var result map[string]interface{}
+	if err := node.Decode(&result); err != nil {
+		return nil, yamlErrorf(node, "invalid metadata: %v", err)
+	}
+	return result, nil
+}
+
+// placeList decodes places that must be declared
+func (l *loader) placeList(node *yaml.Node) ([]Place, error) {
+	names, err := yamlScalarList(node)
+	if err != nil {
+		return nil, err
+	}
+	places := make([]Place, len(names))
+	for i, name := range names {
+		place := Place(name)
+		if !l.places[place] {
+			item := node
+			if node.Kind == yaml.SequenceNode {
+				item = node.Content[i]
+			}
+			return nil, yamlErrorf(item, "place '%s' is not defined in workflow places", place)
+		}
+		places[i] = place
+	}
+	return places, nil
+}
+
+func (l *loader) definition(node *yaml.Node) (*Definition, error) {
+	f, err := yamlFields(node, "name", "version", "metadata", "initial_places", "final_places", "places", "transitions", "invariants", "listeners")
+	if err != nil {
+		return nil, err
+	}
+	var opts []DefinitionOption
+
+	for _, key := range []string{"name", "version"} {
+		if n, ok := f[key]; ok {
+			value, err := yamlScalar(n)
+			if err != nil {
+				return nil, err
+			}
+			if key == "name" {
+				opts = append(opts, WithName(value))
+			} else {
+				opts = append(opts, WithVersion(value))
+			}
+		}
+	}
+	if n, ok := f["metadata"]; ok {
+		meta, err := yamlMetadata(n)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithMetadata(meta))
+	}
+
+	n, ok := f["places"]
+	if !ok {
+		return nil, yamlErrorf(node, "missing field \"places\"")
+	}
+	places, placeOpts, err := l.placeNodes(n)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, placeOpts...)
+
+	if n, ok := f["initial_places"]; ok {
+		initial, err := l.placeList(n)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithInitialPlaces(initial...))
+	}
+	if n, ok := f["final_places"]; ok {
+		final, err := l.placeList(n)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithFinalPlaces(final...))
+	}
+
+	var transitions []Transition
+	if n, ok := f["transitions"]; ok {
+		items, err := yamlSequence(n)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			t, err := l.transition(item)
+			if err != nil {
+				return nil, err
+			}
+			transitions = append(transitions, *t)
+		}
+	}
+
+	if n, ok := f["invariants"]; ok {
+		invariants, err := l.invariants(n)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithInvariants(invariants...))
+	}
+
+	type boundListener struct {
+		ref      listenerJSON
+		listener interface{}
+	}
+	var listeners []boundListener
+	if n, ok := f["listeners"]; ok {
+		items, err := yamlSequence(n)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			ref, listener, err := l.listener(item)
+			if err != nil {
+				return nil, err
+			}
+			listeners = append(listeners, boundListener{ref: ref, listener: listener})
+		}
+	}
+	opts = append(opts, func(d *Definition) {
+		for _, l := range listeners {
+			d.listenerRefs = append(d.listenerRefs, l.ref)
+			d.addListener(l.ref.Event, l.listener)
+		}
+	})
+
+	definition, err := NewDefinition(places, transitions, opts...)
+	if err != nil {
+		return nil, &LoadError{Line: node.Line, Column: node.Column, Err: err}
+	}
+	return definition, nil
+}
+
+// placeNodes decodes the places, given as names or mappings with metadata and requirements
+func (l *loader) placeNodes(node *yaml.Node) ([]Place, []DefinitionOption, error) {
+	items, err := yamlSequence(node)
+	if err != nil {
+		return nil, nil, err
+	}
+	var places []Place
+	var opts []DefinitionOption
+	for _, item := range items {
+		var place Place
+		if item.Kind == yaml.ScalarNode {
+			place = Place(item.Value)
+		} else {
+			f, err := yamlFields(item, "name", "metadata", "requires")
+			if err != nil {
+				return nil, nil, err
+			}
+			n, ok := f["name"]
+			if !ok {
+				return nil, nil, yamlErrorf(item, "missing field \"name\"")
+			}
+			name, err := yamlScalar(n)
+			if err != nil {
+				return nil, nil, err
+			}
+			place = Place(name)
+			if n, ok := f["metadata"]; ok {
+				meta, err := yamlMetadata(n)
+				if err != nil {
+					return nil, nil, err
+				}
+				opts = append(opts, WithPlaceMetadata(place, meta))
+			}
+			if n, ok := f["requires"]; ok {
+				reqOpts, err := l.requirements(place, n)
+				if err != nil {
+					return nil, nil, err
+				}
+				opts = append(opts, reqOpts...)
+			}
+		}
+		if place == "" {
+			return nil, nil, yamlErrorf(item, "place name cannot be empty")
+		}
+		if l.places[place] {
+			return nil, nil, yamlErrorf(item, "duplicate place '%s'", place)
+		}
+		l.places[place] = true
+		places = append(places, place)
+	}
+	return places, opts, nil
+}
+
+// requirements decodes the context requirements of a place
+func (l *loader) requirements(place Place, node *yaml.Node) ([]DefinitionOption, error) {
+	items, err := yamlSequence(node)
+	if err != nil {
+		return nil, err
+	}
+	var opts []DefinitionOption
+	for _, item := range items {
+		if item.Kind == yaml.ScalarNode {
+			opts = append(opts, WithRequiredContext(place, item.Value))
+			continue
+		}
+		f, err := yamlFields(item, "key", "type")
+		if err != nil {
+			return nil, err
+		}
+		n, ok := f["key"]
+		if !ok {
+			return nil, yamlErrorf(item, "missing field \"key\"")
+		}
+		key, err := yamlScalar(n)
+		if err != nil {
+			return nil, err
+		}
+		n, ok = f["type"]
+		if !ok {
+			opts = append(opts, WithRequiredContext(place, key))
+			continue
+		}
+		fieldType, err := l.fieldType(n)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithContextType(place, key, fieldType))
+	}
+	return opts, nil
+}
+
+// fieldType decodes an input field type
+func (l *loader) fieldType(node *yaml.Node) (FieldType, error) {
+	value, err := yamlScalar(node)
+	if err != nil {
+		return "", err
+	}
+	switch t := FieldType(value); t {
+	case FieldAny, FieldString, FieldNumber, FieldInteger, FieldBool:
+		return t, nil
+	}
+	return "", yamlErrorf(node, "unknown type %q", value)
+}
+
+func (l *loader) transition(node *yaml.Node) (*Transition, error) {
+	f, err := yamlFields(node, "name", "from", "to", "metadata", "guard", "constraints", "input")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"name", "from", "to"} {
+		if _, ok := f[key]; !ok {
+			return nil, yamlErrorf(node, "missing field %q", key)
+		}
+	}
+	name, err := yamlScalar(f["name"])
+	if err != nil {
+		return nil, err
+	}
+	from, err := l.placeList(f["from"])
+	if err != nil {
+		return nil, err
+	}
+	to, err := l.placeList(f["to"])
+	if err != nil {
+		return nil, err
+	}
+	t, err := NewTransition(name, from, to)
+	if err != nil {
+		return nil, yamlErrorf(node, "%v", err)
+	}
+
+	if n, ok := f["metadata"]; ok {
+		meta, err := yamlMetadata(n)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range meta {
+			t.SetMetadata(key, value)
+		}
+	}
+	if n, ok := f["guard"]; ok {
+		source, err := yamlScalar(n)
+		if err != nil {
+			return nil, err
+		}
+		guard, err := NewExpressionConstraint(source)
+		if err != nil {
+			return nil, yamlErrorf(n, "invalid guard: %v", err)
+		}
+		t.AddConstraint(guard)
+	}
+	if n, ok := f["constraints"]; ok {
+		names, err := yamlScalarList(n)
+		if err != nil {
+			return nil, err
+		}
+		for i, name := range names {
+			constraint, ok := l.catalog.constraint(name)
+			if !ok {
+				item := n
+				if n.Kind == yaml.SequenceNode {
+					item = n.Content[i]
+				}
+				return nil, yamlErrorf(item, "unknown constraint %q", name)
+			}
+			t.AddConstraint(&namedConstraint{name: name, Constraint: constraint})
+		}
+	}
+	if n, ok := f["input"]; ok {
+		schema, err := l.input(n)
+		if err != nil {
+			return nil, err
+		}
+		t.SetInputSchema(schema)
+	}
+	return t, nil
+}
+
+// input decodes a transition input schema
+func (l *loader) input(node *yaml.Node) (*InputSchema, error) {
+	items, err := yamlSequence(node)
+	if err != nil {
+		return nil, err
+	}
+	schema := NewInputSchema()
+	for _, item := range items {
+		f, err := yamlFields(item, "name", "type", "required", "enum")
+		if err != nil {
+			return nil, err
+		}
+		n, ok := f["name"]
+		if !ok {
+			return nil, yamlErrorf(item, "missing field \"name\"")
+		}
+		field := InputField{Type: FieldAny}
+		if field.Name, err = yamlScalar(n); err != nil {
+			return nil, err
+		}
+		if n, ok := f["type"]; ok {
+			if field.Type, err = l.fieldType(n); err != nil {
+				return nil, err
+			}
+		}
+		if n, ok := f["required"]; ok {
+			if err := n.Decode(&field.Required); err != nil {
+				return nil, yamlErrorf(n, "required must be a boolean")
+			}
+		}
+		if n, ok := f["enum"]; ok {
+			if n.Kind != yaml.SequenceNode {
+				return nil, yamlErrorf(n, "enum must be a list")
+			}
+			if err := n.Decode(&field.Enum); err != nil {
+				return nil, yamlErrorf(n, "invalid enum: %v", err)
+			}
+		}
+		schema.Fields = append(schema.Fields, field)
+	}
+	return schema, nil
+}
+
+// invariants decodes built-in invariants
+func (l *loader) invariants(node *yaml.Node) ([]Invariant, error) {
+	items, err := yamlSequence(node)
+	if err != nil {
+		return nil, err
+	}
+	var invariants []Invariant
+	for _, item := range items {
+		f, err := yamlFields(item, "type", "places")
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range []string{"type", "places"} {
+			if _, ok := f[key]; !ok {
+				return nil, yamlErrorf(item, "missing field %q", key)
+			}
+		}
+		kind, err := yamlScalar(f["type"])
+		if err != nil {
+			return nil, err
+		}
+		places, err := l.placeList(f["places"])
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case invariantMutuallyExclusive:
+			invariants = append(invariants, MutuallyExclusive(places...))
+		case invariantExactlyOne:
+			invariants = append(invariants, ExactlyOne(places...))
+		default:
+			return nil, yamlErrorf(f["type"], "unknown invariant type %q", kind)
+		}
+	}
+	return invariants, nil
+}
+
+// listener decodes a named listener reference
+func (l *loader) listener(node *yaml.Node) (listenerJSON, interface{}, error) {
+	var ref listenerJSON
+	f, err := yamlFields(node, "event", "listener")
+	if err != nil {
+		return ref, nil, err
+	}
+	for _, key := range []string{"event", "listener"} {
+		if _, ok := f[key]; !ok {
+			return ref, nil, yamlErrorf(node, "missing field %q", key)
+		}
+	}
+	event, err := yamlScalar(f["event"])
+	if err != nil {
+		return ref, nil, err
+	}
+	ref.Event = EventType(event)
+	if ref.Listener, err = yamlScalar(f["listener"]); err != nil {
+		return ref, nil, err
+	}
+
+	listener, ok := l.catalog.listener(ref.Listener)
+	if !ok {
+		return ref, nil, yamlErrorf(f["listener"], "unknown listener %q", ref.Listener)
+	}
+	switch ref.Event {
+	case EventGuard:
+		if _, ok := listener.(GuardEventListener); !ok {
+			return ref, nil, yamlErrorf(f["listener"], "listener %q is not a guard listener", ref.Listener)
+		}
+	case EventBeforeTransition, EventAfterTransition:
+		if _, ok := listener.(EventListener); !ok {
+			return ref, nil, yamlErrorf(f["listener"], "listener %q is a guard listener", ref.Listener)
+		}
+	default:
+		return ref, nil, yamlErrorf(f["event"], "unknown event %q", event)
+	}
+	return ref, listener, nil
+}
@@ -0,0 +1,241 @@
+package workflow_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+const articleYAML = `name: article
+version: "2"
+metadata:
+  label: Article
+initial_places: [draft]
+final_places: [published]
+places:
+  - draft
+  - name: review
+    metadata:
+      color: "#ffcc00"
+    requires:
+      - reviewer
+      - key: amount
+        type: number
+  - published
+transitions:
+  - name: submit
+    from: draft
+    to: review
+    metadata:
+      label: Submit
+      cost: 2
+  - name: publish
+    from: [review]
+    to: [published]
+    guard: context.amount <= 1000
+    constraints: [not_weekend]
+    input:
+      - name: comment
+        type: string
+        required: true
+      - name: channel
+        enum: [web, print]
+invariants:
+  - type: exactly_one
+    places: [draft, review, published]
+listeners:
+  - event: after_transition
+    listener: audit
+`
+
+func testCatalog(calls *[]string) *workflow.Catalog {
+	catalog := workflow.NewCatalog()
+	catalog.RegisterConstraint("not_weekend", workflow.ConstraintFunc(func(workflow.Event) error { return nil }))
+	catalog.RegisterListener("audit", func(e workflow.Event) error {
+		*calls = append(*calls, e.Transition().Name())
+		return nil
+	})
+	return catalog
+}
+
+func TestLoadDefinition(t *testing.T) {
+	var calls []string
+	def, err := workflow.LoadDefinition([]byte(articleYAML), testCatalog(&calls))
+	if err != nil {
+		t.Fatalf("LoadDefinition() error = %v", err)
+	}
+
+	if def.Name() != "article" || def.Version() != "2" {
+		t.Errorf("unexpected ref %v", def.Ref())
+	}
+	if got := def.AllPlaces(); len(got) != 3 || got[1] != "review" {
+		t.Errorf("unexpected places %v", got)
+	}
+	if color, _ := def.PlaceMetadata("review", workflow.MetadataColor); color != "#ffcc00" {
+		t.Errorf("review color = %v", color)
+	}
+	if reqs := def.Requirements("review"); len(reqs) != 2 || reqs[1].Type != workflow.FieldNumber {
+		t.Errorf("unexpected requirements %+v", reqs)
+	}
+	if cost, _ := def.Transition("submit").Metadata("cost"); cost != 2 {
+		t.Errorf("submit cost = %#v, want 2", cost)
+	}
+	if schema := def.Transition("publish").InputSchema(); schema == nil || len(schema.Fields) != 2 {
+		t.Errorf("unexpected input schema %+v", schema)
+	}
+	if len(def.Invariants()) != 1 {
+		t.Errorf("expected 1 invariant, got %d", len(def.Invariants()))
+	}
+
+	wf, err := workflow.NewWorkflow("a1", def, "draft")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	wf.SetContext("reviewer", "alice")
+	wf.SetContext("amount", 5000)
+	if err := wf.Apply([]workflow.Place{"review"}); err != nil {
+		t.Fatalf("Apply(review) error = %v", err)
+	}
+	payload := map[string]interface{}{"comment": "ok"}
+	if err := wf.ApplyWithPayload(context.Background(), []workflow.Place{"published"}, payload); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("expected the guard to block, got %v", err)
+	}
+	wf.SetContext("amount", 10)
+	if err := wf.ApplyWithPayload(context.Background(), []workflow.Place{"published"}, payload); err != nil {
+		t.Errorf("Apply(published) error = %v", err)
+	}
+	if strings.Join(calls, ",") != "submit,publish" {
+		t.Errorf("listener calls = %v", calls)
+	}
+}
+
+func TestLoadDefinition_RoundTrip(t *testing.T) {
+	var calls []string
+	catalog := testCatalog(&calls)
+	def, err := workflow.LoadDefinition([]byte(articleYAML), catalog)
+	if err != nil {
+		t.Fatalf("LoadDefinition() error = %v", err)
+	}
+
+	for _, format := range []workflow.Format{workflow.FormatYAML, workflow.FormatJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			data, err := workflow.ExportDefinition(def, format)
+			if err != nil {
+				t.Fatalf("ExportDefinition() error = %v", err)
+			}
+			loaded, err := workflow.LoadDefinition(data, catalog)
+			if err != nil {
+				t.Fatalf("LoadDefinition() of exported data error = %v\n%s", err, data)
+			}
+			if loaded.Fingerprint() != def.Fingerprint() {
+				t.Errorf("fingerprint changed after round trip\n%s", data)
+			}
+			again, err := workflow.ExportDefinition(loaded, format)
+			if err != nil {
+				t.Fatalf("ExportDefinition() error = %v", err)
+			}
+			if !bytes.Equal(data, again) {
+				t.Errorf("export is not stable:\n%s\n---\n%s", data, again)
+			}
+		})
+	}
+}
+
+func TestLoadDefinition_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		line    int
+		message string
+	}{
+		{
+			name:    "unknown field",
+			data:    "places: [a]\ntransitons: []\n",
+			line:    2,
+			message: `unknown field "transitons"`,
+		},
+		{
+			name:    "undefined place",
+			data:    "places: [a, b]\ntransitions:\n  - name: t\n    from: a\n    to: [b, c]\n",
+			line:    5,
+			message: "place 'c' is not defined in workflow places",
+		},
+		{
+			name:    "invalid guard",
+			data:    "places: [a, b]\ntransitions:\n  - name: t\n    from: a\n    to: b\n    guard: context.x >\n",
+			line:    6,
+			message: "invalid guard",
+		},
+		{
+			name:    "unknown constraint",
+			data:    "places: [a, b]\ntransitions:\n  - name: t\n    from: a\n    to: b\n    constraints:\n      - missing\n",
+			line:    7,
+			message: `unknown constraint "missing"`,
+		},
+		{
+			name:    "unknown listener",
+			data:    "places: [a]\nlisteners:\n  - event: guard\n    listener: nope\n",
+			line:    4,
+			message: `unknown listener "nope"`,
+		},
+		{
+			name:    "duplicate place",
+			data:    "places:\n  - a\n  - a\n",
+			line:    3,
+			message: "duplicate place 'a'",
+		},
+		{
+			name:    "json",
+			data:    "{\n\t\"places\": [\"a\"],\n\t\"initial_places\": [\"b\"]\n}\n",
+			line:    3,
+			message: "place 'b' is not defined in workflow places",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := workflow.LoadDefinition([]byte(tt.data), nil)
+			var loadErr *workflow.LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("expected a *LoadError, got %v", err)
+			}
+			if loadErr.Line != tt.line || !strings.Contains(loadErr.Error(), tt.message) {
+				t.Errorf("error = %v, want line %d containing %q", loadErr, tt.line, tt.message)
+			}
+		})
+	}
+}
+
+func TestExportDefinition_Unserializable(t *testing.T) {
+	def, err := workflow.NewBuilder("article").
+		Place("draft", "review").
+		Transition("submit").From("draft").To("review").
+		Guard(func(workflow.Event) error { return nil }).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if _, err := workflow.ExportDefinition(def, workflow.FormatYAML); err == nil {
+		t.Error("expected an error for a Go constraint")
+	}
+}
+
+func TestLoadDefinitionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "article.json")
+	data := `{"name": "article", "places": ["draft", "review"], "transitions": [{"name": "submit", "from": ["draft"], "to": ["review"]}]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	def, err := workflow.LoadDefinitionFile(path, nil)
+	if err != nil {
+		t.Fatalf("LoadDefinitionFile() error = %v", err)
+	}
+	if def.Transition("submit") == nil {
+		t.Error("transition submit not loaded")
+	}
+}
@@ -2,6 +2,7 @@ package workflow
 
 import (
 	"fmt"
+	"sync"
 )
 
 // Manager handles workflow instances and their persistence
@@ -9,6 +10,10 @@ type Manager struct {
 	registry *Registry
 	storage  Storage
 
+	// Registered definition versions by definition name
+	definitions map[string][]*Definition
+	mu          sync.RWMutex
+
 	// Dynamic listeners for all managed workflows
 	Listeners map[EventType][]interface{}
 }
@@ -30,16 +35,19 @@ func (m *Manager) LoadWorkflow(id string, definition *Definition) (*Workflow, er
 	}
 
 	// Load state and context from storage
-	places, wfContext, err := m.storage.LoadState(id)
+	places, wfContext, definition, err := m.loadState(id, definition)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load workflow state: %w", err)
+		return nil, err
 	}
 
-	// Create new workflow instance
-	wf, err = NewWorkflow(id, definition, places[0])
+	// Create new workflow instance; the stored marking replaces the initial one
+	wf, err = newWorkflow(id, definition, places[0])
 	if err != nil {
 		return nil, fmt.Errorf("failed to create workflow: %w", err)
 	}
+	if err := definition.checkInvariants(places); err != nil {
+		return nil, fmt.Errorf("failed to load workflow %s: %w", id, err)
+	}
 	wf.SetManager(m)
 	wf.context = wfContext // Set the loaded context
 
@@ -53,7 +61,52 @@ func (m *Manager) LoadWorkflow(id string, definition *Definition) (*Workflow, er
 
 // SaveWorkflow saves a workflow instance state to storage
 func (m *Manager) SaveWorkflow(id string, wf *Workflow) error {
-	return m.storage.SaveState(id, wf.Marking().Places(), wf.context)
+	return m.saveState(id, wf.Definition(), wf.Marking().Places(), wf.context)
+}
+
+// loadState loads a workflow state from storage. When the storage records
+// definition versions, the instance is loaded with the registered definition
+// it was saved with; the given definition is used for unversioned instances.
+func (m *Manager) loadState(id string, definition *Definition) ([]Place, map[string]interface{}, *Definition, error) {
+	storage, ok := m.storage.(VersionedStorage)
+	if !ok {
+		places, wfContext, err := m.storage.LoadState(id)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to load workflow state: %w", err)
+		}
+		return places, wfContext, definition, nil
+	}
+
+	ref, places, wfContext, err := storage.LoadVersionedState(id)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load workflow state: %w", err)
+	}
+	if ref.Name == "" || (definition != nil && definition.Ref() == ref) {
+		return places, wfContext, definition, nil
+	}
+	registered, err := m.Definition(ref)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load workflow %s: %w", id, err)
+	}
+	return places, wfContext, registered, nil
+}
+
+// saveState saves a workflow state to storage, recording the definition
+// version when both the storage and the definition support it
+func (m *Manager) saveState(id string, definition *Definition, places []Place, wfContext map[string]interface{}) error {
+	storage, ok := m.storage.(VersionedStorage)
+	if !ok || definition == nil || definition.Name() == "" {
+		return m.storage.SaveState(id, places, wfContext)
+	}
+
+	// Keep track of the version so that the instance can be loaded with it later
+	m.mu.Lock()
+	err := m.registerDefinition(definition)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return storage.SaveVersionedState(id, definition.Ref(), places, wfContext)
 }
 
 // GetWorkflow gets a workflow instance from the registry or loads it from storage
@@ -77,7 +130,7 @@ func (m *Manager) CreateWorkflow(id string, definition *Definition, initialPlace
 	wf.SetManager(m)
 
 	// Save initial state
-	if err := m.storage.SaveState(id, wf.Marking().Places(), wf.context); err != nil {
+	if err := m.saveState(id, definition, wf.Marking().Places(), wf.context); err != nil {
 		return nil, fmt.Errorf("failed to save initial state: %w", err)
 	}
 
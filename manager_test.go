@@ -1,6 +1,7 @@
 package workflow
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -269,3 +270,27 @@ func TestManager_LoadWorkflow(t *testing.T) {
 		t.Errorf("Expected workflow state to be %v, got %v", initialPlace, places)
 	}
 }
+
+func TestManager_LoadWorkflowInvariant(t *testing.T) {
+	storage := NewMockStorage()
+	manager := NewManager(NewRegistry(), storage)
+
+	definition, err := NewDefinition(
+		[]Place{"draft", "review", "published"},
+		[]Transition{},
+		WithInvariants(ExactlyOne("draft", "review", "published")),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create workflow definition: %v", err)
+	}
+
+	if err := storage.SaveState("broken", []Place{"draft", "published"}, nil); err != nil {
+		t.Fatalf("Failed to save workflow state: %v", err)
+	}
+	if _, err := manager.LoadWorkflow("broken", definition); !errors.Is(err, ErrInvariantViolated) {
+		t.Errorf("Expected ErrInvariantViolated, got %v", err)
+	}
+	if _, err := manager.registry.Workflow("broken"); err == nil {
+		t.Error("Workflow with an invalid marking should not be registered")
+	}
+}
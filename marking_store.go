@@ -0,0 +1,160 @@
+package workflow
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MarkingStore reads and writes a workflow marking on an external subject,
+// such as a field of a domain object, so that the subject stays the source of truth
+type MarkingStore interface {
+	// Places reads the current places from the subject
+	Places() ([]Place, error)
+	// SetPlaces writes the given places back to the subject
+	SetPlaces(places []Place) error
+}
+
+// fieldMarkingStore implements MarkingStore on a struct field using reflection
+type fieldMarkingStore struct {
+	field reflect.Value
+	name  string
+}
+
+// NewFieldMarkingStore creates a marking store backed by a field of the given struct.
+// The subject must be a pointer to a struct. Supported field types are:
+//   - string kinds (e.g. string or Place), holding a single place for state machines
+//   - slices of string kinds, holding the places of a Petri net
+//   - maps keyed by string kinds with bool or integer values, holding marked places;
+//     markings hold at most one token per place, so counts above 1 are rejected
+func NewFieldMarkingStore(subject interface{}, field string) (MarkingStore, error) {
+	v := reflect.ValueOf(subject)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("subject must be a non-nil pointer to a struct, got %T", subject)
+	}
+
+	f := v.Elem().FieldByName(field)
+	if !f.IsValid() {
+		return nil, fmt.Errorf("field %s not found on %T", field, subject)
+	}
+	if !f.CanSet() {
+		return nil, fmt.Errorf("field %s on %T cannot be set", field, subject)
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.String {
+			return nil, fmt.Errorf("field %s must be a slice of strings, got %s", field, f.Type())
+		}
+	case reflect.Map:
+		if f.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("field %s must be a map keyed by strings, got %s", field, f.Type())
+		}
+		switch f.Type().Elem().Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		default:
+			return nil, fmt.Errorf("field %s must be a map of bool or int values, got %s", field, f.Type())
+		}
+	default:
+		return nil, fmt.Errorf("unsupported marking field type %s for field %s", f.Type(), field)
+	}
+
+	return &fieldMarkingStore{field: f, name: field}, nil
+}
+
+// Places implements MarkingStore
+func (s *fieldMarkingStore) Places() ([]Place, error) {
+	switch s.field.Kind() {
+	case reflect.String:
+		if s.field.String() == "" {
+			return []Place{}, nil
+		}
+		return []Place{Place(s.field.String())}, nil
+	case reflect.Slice:
+		places := make([]Place, s.field.Len())
+		for i := 0; i < s.field.Len(); i++ {
+			places[i] = Place(s.field.Index(i).String())
+		}
+		return places, nil
+	default:
+		places := make([]Place, 0, s.field.Len())
+		iter := s.field.MapRange()
+		for iter.Next() {
+			marked := false
+			if iter.Value().Kind() == reflect.Bool {
+				marked = iter.Value().Bool()
+			} else {
+				count := iter.Value().Int()
+				if count > 1 {
+					return nil, fmt.Errorf("field %s marks place %s %d times, markings hold at most one token per place", s.name, iter.Key().String(), count)
+				}
+				marked = count > 0
+			}
+			if marked {
+				places = append(places, Place(iter.Key().String()))
+			}
+		}
+		// Map iteration order is random; keep the marking stable
+		sort.Slice(places, func(i, j int) bool { return places[i] < places[j] })
+		return places, nil
+	}
+}
+
+// SetPlaces implements MarkingStore
+func (s *fieldMarkingStore) SetPlaces(places []Place) error {
+	t := s.field.Type()
+	switch s.field.Kind() {
+	case reflect.String:
+		if len(places) > 1 {
+			return fmt.Errorf("field %s holds a single place but the marking has %d places", s.name, len(places))
+		}
+		value := ""
+		if len(places) == 1 {
+			value = string(places[0])
+		}
+		s.field.SetString(value)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(t, len(places), len(places))
+		for i, place := range places {
+			slice.Index(i).SetString(string(place))
+		}
+		s.field.Set(slice)
+	default:
+		m := reflect.MakeMapWithSize(t, len(places))
+		for _, place := range places {
+			key := reflect.New(t.Key()).Elem()
+			key.SetString(string(place))
+			value := reflect.New(t.Elem()).Elem()
+			if value.Kind() == reflect.Bool {
+				value.SetBool(true)
+			} else {
+				value.SetInt(1)
+			}
+			m.SetMapIndex(key, value)
+		}
+		s.field.Set(m)
+	}
+	return nil
+}
+
+// funcMarkingStore implements MarkingStore with accessor functions
+type funcMarkingStore struct {
+	get func() []Place
+	set func([]Place) error
+}
+
+// NewFuncMarkingStore creates a marking store from accessor functions
+func NewFuncMarkingStore(get func() []Place, set func([]Place) error) MarkingStore {
+	return &funcMarkingStore{get: get, set: set}
+}
+
+// Places implements MarkingStore
+func (s *funcMarkingStore) Places() ([]Place, error) {
+	return s.get(), nil
+}
+
+// SetPlaces implements MarkingStore
+func (s *funcMarkingStore) SetPlaces(places []Place) error {
+	return s.set(places)
+}
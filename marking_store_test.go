@@ -0,0 +1,162 @@
+package workflow_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+type testArticle struct {
+	State  string
+	Places []workflow.Place
+	Marks  map[string]int
+	Count  int
+}
+
+var markingStoreDefinition = workflow.NewBuilder("article").
+	Place("draft", "review", "checked", "published").
+	Transition("submit").From("draft").To("review", "checked").
+	Transition("publish").From("review", "checked").To("published").
+	MustBuild()
+
+func TestNewFieldMarkingStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject interface{}
+		field   string
+		wantErr bool
+	}{
+		{name: "string field", subject: &testArticle{}, field: "State"},
+		{name: "slice field", subject: &testArticle{}, field: "Places"},
+		{name: "map field", subject: &testArticle{}, field: "Marks"},
+		{name: "unsupported type", subject: &testArticle{}, field: "Count", wantErr: true},
+		{name: "missing field", subject: &testArticle{}, field: "Missing", wantErr: true},
+		{name: "non-pointer subject", subject: testArticle{}, field: "State", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := workflow.NewFieldMarkingStore(tt.subject, tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFieldMarkingStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorkflow_FieldMarkingStore(t *testing.T) {
+	for _, field := range []string{"Places", "Marks"} {
+		t.Run(field, func(t *testing.T) {
+			article := &testArticle{}
+			store, err := workflow.NewFieldMarkingStore(article, field)
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			wf, err := workflow.NewWorkflowWithMarkingStore("article", markingStoreDefinition, "draft", store)
+			if err != nil {
+				t.Fatalf("failed to create workflow: %v", err)
+			}
+
+			if err := wf.Apply([]workflow.Place{"review", "checked"}); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			places, _ := store.Places()
+			if len(places) != 2 {
+				t.Errorf("stored places = %v, want [review checked]", places)
+			}
+
+			if err := wf.Apply([]workflow.Place{"published"}); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			places, _ = store.Places()
+			if len(places) != 1 || places[0] != "published" {
+				t.Errorf("stored places = %v, want [published]", places)
+			}
+		})
+	}
+}
+
+func TestWorkflow_MarkingStoreIsSourceOfTruth(t *testing.T) {
+	article := &testArticle{}
+	store, _ := workflow.NewFieldMarkingStore(article, "State")
+	wf, err := workflow.NewWorkflowWithMarkingStore("article", markingStoreDefinition, "draft", store)
+	if err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	if article.State != "draft" {
+		t.Errorf("State = %q, want %q", article.State, "draft")
+	}
+
+	// The entity is updated outside of the workflow
+	article.State = "review"
+	if places := wf.CurrentPlaces(); len(places) != 1 || places[0] != "review" {
+		t.Errorf("CurrentPlaces() = %v, want [review]", places)
+	}
+	if err := wf.Apply([]workflow.Place{"review"}); err == nil {
+		t.Error("Apply() error = nil, want error")
+	}
+}
+
+func TestWorkflow_FuncMarkingStoreWriteFailure(t *testing.T) {
+	places := []workflow.Place{"draft"}
+	fail := false
+	store := workflow.NewFuncMarkingStore(
+		func() []workflow.Place { return places },
+		func(p []workflow.Place) error {
+			if fail {
+				return fmt.Errorf("database unavailable")
+			}
+			places = p
+			return nil
+		},
+	)
+	wf, err := workflow.NewWorkflowWithMarkingStore("article", markingStoreDefinition, "draft", store)
+	if err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+
+	fail = true
+	if err := wf.Apply([]workflow.Place{"review", "checked"}); err == nil {
+		t.Fatal("Apply() error = nil, want error")
+	}
+	if got := wf.CurrentPlaces(); len(got) != 1 || got[0] != "draft" {
+		t.Errorf("CurrentPlaces() = %v, want [draft]", got)
+	}
+}
+
+func TestWorkflow_MarkingStoreInvalidPlaces(t *testing.T) {
+	tests := []struct {
+		name   string
+		field  string
+		change func(article *testArticle)
+	}{
+		{name: "undefined place", field: "Places", change: func(a *testArticle) { a.Places = []workflow.Place{"archived"} }},
+		{name: "count above one", field: "Marks", change: func(a *testArticle) { a.Marks = map[string]int{"draft": 2} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article := &testArticle{}
+			store, err := workflow.NewFieldMarkingStore(article, tt.field)
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			wf, err := workflow.NewWorkflowWithMarkingStore("article", markingStoreDefinition, "draft", store)
+			if err != nil {
+				t.Fatalf("failed to create workflow: %v", err)
+			}
+
+			tt.change(article)
+			if _, err := wf.EnabledTransitions(); err == nil {
+				t.Error("EnabledTransitions() error = nil, want error")
+			}
+			if places := wf.CurrentPlaces(); len(places) != 1 || places[0] != "draft" {
+				t.Errorf("CurrentPlaces() = %v, want the last valid marking [draft]", places)
+			}
+			if _, err := workflow.NewWorkflowWithMarkingStore("article", markingStoreDefinition, "draft", store); err == nil {
+				t.Error("NewWorkflowWithMarkingStore() error = nil, want error")
+			}
+		})
+	}
+}
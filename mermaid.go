@@ -2,22 +2,162 @@ package workflow
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 )
 
+// DiagramOptions configures Mermaid diagram generation
+type DiagramOptions struct {
+	// Direction is the layout direction of the diagram, such as "LR" or "TB".
+	// Defaults to the Mermaid layout, from top to bottom.
+	Direction string
+	// LabelKey is the metadata key holding the labels of places, transitions
+	// and the diagram title. Defaults to MetadataLabel.
+	LabelKey string
+	// RenderOptions sets the places to highlight as current places and the
+	// instance counts, listed in a note on each place. Metadata lists the
+	// remaining place metadata in a note on each place, followed by the
+	// metadata of the transitions leaving it.
+	RenderOptions
+	// HideTransitionNames draws transitions as unlabeled arrows. The
+	// transitions of such a diagram are named after their target places when
+	// imported with ImportMermaid.
+	HideTransitionNames bool
+	// HighlightEnabled highlights the places entered by the enabled
+	// transitions. For a definition, these are the transitions enabled by
+	// its initial places, ignoring guards.
+	HighlightEnabled bool
+	// Path lists the transitions taken, in order, such as the Transition
+	// field of history records. The places they visit are highlighted, and
+	// the numbered steps are listed in a note on the place each step enters.
+	Path []string
+	// Styles sets Mermaid class styles, such as "fill:#eee,stroke:#333".
+	// The currentPlace, enabledPlace and visitedPlace classes can be
+	// overridden, and other classes can be assigned with PlaceClasses.
+	Styles map[string]string
+	// PlaceClasses assigns classes of Styles to places
+	PlaceClasses map[Place]string
+}
+
+// Default styles of the classes used to highlight places
+var diagramStyles = map[string]string{
+	"currentPlace": "font-weight:bold,stroke-width:4px",
+	"enabledPlace": "stroke-dasharray:5 5",
+	"visitedPlace": "fill:#e3f2fd",
+}
+
 // Diagram generates a Mermaid state diagram for the workflow
 func (w *Workflow) Diagram() string {
+	return w.DiagramWithOptions(DiagramOptions{})
+}
+
+// DiagramWithOptions generates a Mermaid state diagram for the workflow,
+// highlighting its current places
+func (w *Workflow) DiagramWithOptions(opts DiagramOptions) string {
+	var enabled []Transition
+	if opts.HighlightEnabled {
+		// A failed read leaves the enabled transitions out
+		enabled, _ = w.EnabledTransitions()
+	}
+	return w.Definition().diagram(opts, []Place{w.InitialPlace()}, w.CurrentPlaces(), enabled)
+}
+
+// Diagram generates a Mermaid state diagram for the definition, starting from
+// its initial places
+func (d *Definition) Diagram(opts DiagramOptions) string {
+	var enabled []Transition
+	if opts.HighlightEnabled {
+		initial := placeSet(d.initialPlaces)
+		for _, t := range d.transitions {
+			ready := true
+			for _, place := range t.From() {
+				ready = ready && initial[place]
+			}
+			if ready {
+				enabled = append(enabled, t)
+			}
+		}
+	}
+	return d.diagram(opts, d.initialPlaces, nil, enabled)
+}
+
+// diagram renders the definition with the given initial and current places
+// and enabled transitions
+func (d *Definition) diagram(opts DiagramOptions, initial, current []Place, enabled []Transition) string {
 	var diagram strings.Builder
+	labelKey := opts.LabelKey
+	if labelKey == "" {
+		labelKey = MetadataLabel
+	}
+	style := func(class string) string {
+		if s, ok := opts.Styles[class]; ok {
+			return s
+		}
+		return diagramStyles[class]
+	}
+
+	// Use the definition label as the diagram title
+	if title, ok := metadataString(d.metadata, labelKey); ok {
+		diagram.WriteString(fmt.Sprintf("---\ntitle: %s\n---\n", title))
+	}
+
 	diagram.WriteString("stateDiagram-v2\n")
-	diagram.WriteString("    classDef currentPlace font-weight:bold,stroke-width:4px\n")
+	if opts.Direction != "" {
+		diagram.WriteString(fmt.Sprintf("    direction %s\n", opts.Direction))
+	}
+	diagram.WriteString(fmt.Sprintf("    classDef currentPlace %s\n", style("currentPlace")))
+	if len(enabled) > 0 {
+		diagram.WriteString(fmt.Sprintf("    classDef enabledPlace %s\n", style("enabledPlace")))
+	}
+	if len(opts.Path) > 0 {
+		diagram.WriteString(fmt.Sprintf("    classDef visitedPlace %s\n", style("visitedPlace")))
+	}
+	var customClasses []string
+	for class := range opts.Styles {
+		if _, ok := diagramStyles[class]; !ok {
+			customClasses = append(customClasses, class)
+		}
+	}
+	sort.Strings(customClasses)
+	for _, class := range customClasses {
+		diagram.WriteString(fmt.Sprintf("    classDef %s %s\n", class, opts.Styles[class]))
+	}
+
+	// Add a style class for each colored place
+	var styledPlaces []Place
+	for _, place := range d.places {
+		if color, ok := metadataString(d.placeMetadata[place], MetadataColor); ok {
+			diagram.WriteString(fmt.Sprintf("    classDef %s fill:%s\n", placeClass(place), color))
+			styledPlaces = append(styledPlaces, place)
+		}
+	}
 
 	// Add all places
-	for _, place := range w.definition.Places {
-		diagram.WriteString(fmt.Sprintf("    %s\n", place))
+	for _, place := range d.places {
+		if label, ok := diagramLabel(string(place), d.placeMetadata[place], labelKey); ok {
+			diagram.WriteString(fmt.Sprintf("    state \"%s\" as %s\n", mermaidEscape(label), place))
+		} else {
+			diagram.WriteString(fmt.Sprintf("    %s\n", place))
+		}
 	}
 
 	// Add all transitions
-	for _, trans := range w.definition.Transitions {
+	for _, trans := range d.transitions {
+		label, _ := diagramLabel(trans.Name(), trans.metadata, labelKey)
+		if opts.HideTransitionNames {
+			label = ""
+		}
+		if len(trans.From()) == 1 && len(trans.To()) == 1 && label != "" && label != trans.Name() {
+			// The arrow shows the label, so keep the name for ImportMermaid
+			diagram.WriteString(fmt.Sprintf("    %%%% transition %s\n", trans.Name()))
+		}
+		arrow := func(from, to interface{}) string {
+			if label == "" {
+				return fmt.Sprintf("    %s --> %s\n", from, to)
+			}
+			return fmt.Sprintf("    %s --> %s : %s\n", from, to, label)
+		}
 		// Handle multiple to places
 		if len(trans.To()) > 1 {
 			// This is a fork
@@ -28,11 +168,11 @@ func (w *Workflow) Diagram() string {
 				joinState := fmt.Sprintf("%s_join", trans.Name())
 				diagram.WriteString(fmt.Sprintf("    state %s <<join>>\n", joinState))
 				for _, from := range trans.From() {
-					diagram.WriteString(fmt.Sprintf("    %s --> %s : %s\n", from, joinState, trans.Name()))
+					diagram.WriteString(arrow(from, joinState))
 				}
 				diagram.WriteString(fmt.Sprintf("    %s --> %s\n", joinState, forkState))
 			} else {
-				diagram.WriteString(fmt.Sprintf("    %s --> %s : %s\n", trans.From()[0], forkState, trans.Name()))
+				diagram.WriteString(arrow(trans.From()[0], forkState))
 			}
 			for _, to := range trans.To() {
 				diagram.WriteString(fmt.Sprintf("    %s --> %s\n", forkState, to))
@@ -43,27 +183,595 @@ func (w *Workflow) Diagram() string {
 				joinState := fmt.Sprintf("%s_join", trans.Name())
 				diagram.WriteString(fmt.Sprintf("    state %s <<join>>\n", joinState))
 				for _, from := range trans.From() {
-					diagram.WriteString(fmt.Sprintf("    %s --> %s : %s\n", from, joinState, trans.Name()))
+					diagram.WriteString(arrow(from, joinState))
 				}
 				diagram.WriteString(fmt.Sprintf("    %s --> %s\n", joinState, trans.To()[0]))
 			} else {
 				// Regular transition
-				diagram.WriteString(fmt.Sprintf("    %s --> %s : %s\n", trans.From()[0], trans.To()[0], trans.Name()))
+				diagram.WriteString(arrow(trans.From()[0], trans.To()[0]))
 			}
 		}
 	}
 
+	// Apply place style classes
+	if len(styledPlaces) > 0 {
+		diagram.WriteString("\n    %% Place styles\n")
+		for _, place := range styledPlaces {
+			diagram.WriteString(fmt.Sprintf("    class %s %s\n", place, placeClass(place)))
+		}
+	}
+
+	// Apply custom classes
+	if len(opts.PlaceClasses) > 0 {
+		diagram.WriteString("\n    %% Custom classes\n")
+		for _, place := range d.places {
+			if class, ok := opts.PlaceClasses[place]; ok {
+				diagram.WriteString(fmt.Sprintf("    class %s %s\n", place, class))
+			}
+		}
+	}
+
+	// List the metadata in notes, keeping labels importable
+	if opts.Metadata {
+		notes := make(map[Place][]string)
+		for _, place := range d.places {
+			notes[place] = metadataLines(d.placeMetadata[place])
+		}
+		for _, trans := range d.transitions {
+			for _, line := range metadataLines(trans.metadata) {
+				from := trans.From()[0]
+				notes[from] = append(notes[from], trans.Name()+"."+line)
+			}
+		}
+		diagramNotes(&diagram, "Metadata", "right", d.places, notes)
+	}
+
+	// Highlight the places visited by the path and number its steps
+	steps := make(map[Place][]string)
+	var visited []Place
+	for i, name := range opts.Path {
+		if t := d.Transition(name); t != nil {
+			visited = append(visited, t.From()...)
+			visited = append(visited, t.To()...)
+			steps[t.To()[0]] = append(steps[t.To()[0]], fmt.Sprintf("%d. %s", i+1, name))
+		}
+	}
+	if len(visited) > 0 {
+		diagram.WriteString("\n    %% Path taken\n")
+		for _, place := range uniquePlaces(visited) {
+			diagram.WriteString(fmt.Sprintf("    class %s visitedPlace\n", place))
+		}
+		diagramNotes(&diagram, "", "left", d.places, steps)
+	}
+
+	// Highlight the places entered by enabled transitions
+	if len(enabled) > 0 {
+		var targets []Place
+		for _, t := range enabled {
+			targets = append(targets, t.To()...)
+		}
+		diagram.WriteString("\n    %% Enabled transitions\n")
+		for _, place := range uniquePlaces(targets) {
+			diagram.WriteString(fmt.Sprintf("    class %s enabledPlace\n", place))
+		}
+	}
+
+	// List the instance counts in notes
+	counts := make(map[Place][]string)
+	for _, place := range d.places {
+		if count, ok := opts.instanceCount(place); ok {
+			counts[place] = []string{count}
+		}
+	}
+	diagramNotes(&diagram, "Instances", "left", d.places, counts)
+
 	// Add current place highlighting
-	currentPlaces := w.marking.Places()
-	if len(currentPlaces) > 0 {
+	if highlighted := uniquePlaces(append(append([]Place{}, current...), opts.Highlight...)); len(highlighted) > 0 {
 		diagram.WriteString("\n    %% Current places\n")
-		for _, place := range currentPlaces {
+		for _, place := range highlighted {
 			diagram.WriteString(fmt.Sprintf("    class %s currentPlace\n", place))
 		}
 	}
 
-	diagram.WriteString("\n    %% Initial place\n")
-	diagram.WriteString(fmt.Sprintf("    [*] --> %s\n", w.InitialPlace()))
+	if len(initial) > 0 {
+		diagram.WriteString("\n    %% Initial place\n")
+		for _, place := range initial {
+			diagram.WriteString(fmt.Sprintf("    [*] --> %s\n", place))
+		}
+	}
+
+	if len(d.finalPlaces) > 0 {
+		diagram.WriteString("\n    %% Final places\n")
+		for _, place := range d.finalPlaces {
+			diagram.WriteString(fmt.Sprintf("    %s --> [*]\n", place))
+		}
+	}
 
 	return diagram.String()
 }
+
+// diagramNotes writes a note on the given side of each place with lines,
+// under a section comment when one is given
+func diagramNotes(diagram *strings.Builder, section, side string, places []Place, lines map[Place][]string) {
+	for _, place := range places {
+		if len(lines[place]) == 0 {
+			continue
+		}
+		if section != "" {
+			diagram.WriteString(fmt.Sprintf("\n    %%%% %s\n", section))
+			section = ""
+		}
+		diagram.WriteString(fmt.Sprintf("    note %s of %s\n", side, place))
+		for _, line := range lines[place] {
+			diagram.WriteString(fmt.Sprintf("        %s\n", line))
+		}
+		diagram.WriteString("    end note\n")
+	}
+}
+
+// diagramLabel returns the label of a node read from the given metadata key.
+// It reports whether the label was set from metadata.
+func diagramLabel(name string, metadata map[string]interface{}, key string) (string, bool) {
+	label, ok := metadataString(metadata, key)
+	if !ok {
+		label = name
+	}
+	return label, ok
+}
+
+// placeClass returns the Mermaid class name used to style a place
+func placeClass(place Place) string {
+	return fmt.Sprintf("place_%s", place)
+}
+
+// mermaidEscape escapes double quotes in Mermaid labels
+func mermaidEscape(label string) string {
+	return strings.ReplaceAll(label, "\"", "#quot;")
+}
+
+// mermaidID matches the state identifiers accepted by ImportMermaid
+var mermaidID = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// mermaidBar is a fork or join pseudo-state
+type mermaidBar struct {
+	line int
+	// group is the bar that represents the chain of bars it belongs to
+	group string
+}
+
+// mermaidEdge is an arrow between two states
+type mermaidEdge struct {
+	from, to     string
+	label        string
+	name         string
+	line, column int
+}
+
+// mermaidParser builds a definition from a Mermaid state diagram
+type mermaidParser struct {
+	places  []Place
+	seen    map[Place]bool
+	bars    map[string]*mermaidBar
+	edges   []mermaidEdge
+	labels  map[Place]string
+	fills   map[string]string
+	classes map[Place][]string
+	initial []Place
+	final   []Place
+	title   string
+	// name is the transition name given by a "%% transition" comment, for
+	// the next arrow
+	name string
+}
+
+// ImportMermaid parses a Mermaid stateDiagram-v2, such as the one generated by
+// Workflow.Diagram, and returns the definition and its initial place. States
+// become places, and arrows from [*] and to [*] mark the initial and final
+// places. Arrow labels become transition names; unlabeled arrows are named
+// after their target. A "%% transition <name>" comment before an arrow, as
+// written by Workflow.Diagram for labelled transitions, names the transition
+// and keeps the arrow text as its label. Arrows through <<fork>> and <<join>> states form a
+// single transition named after the fork or join state, without its _fork or
+// _join suffix. Quoted state descriptions become labels, a fill color applied
+// with classDef and class becomes the place color, and the title of the front
+// matter becomes the definition label. Composite states, choices and
+// concurrent regions are rejected with a *LoadError wrapping
+// ErrUnsupportedElement.
+func ImportMermaid(data []byte) (*Definition, Place, error) {
+	p := &mermaidParser{
+		seen:    make(map[Place]bool),
+		bars:    make(map[string]*mermaidBar),
+		labels:  make(map[Place]string),
+		fills:   make(map[string]string),
+		classes: make(map[Place][]string),
+	}
+	if err := p.parse(string(data)); err != nil {
+		return nil, "", err
+	}
+	return p.definition()
+}
+
+// parse reads the lines of the diagram
+func (p *mermaidParser) parse(source string) error {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	i := 0
+	skipBlank := func() {
+		for i < len(lines) && mermaidSkip(lines[i]) {
+			i++
+		}
+	}
+
+	// Front matter
+	skipBlank()
+	if i < len(lines) && strings.TrimSpace(lines[i]) == "---" {
+		start := i
+		for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "---"; i++ {
+			if key, value, ok := strings.Cut(strings.TrimSpace(lines[i]), ":"); ok && strings.TrimSpace(key) == "title" {
+				p.title = strings.Trim(strings.TrimSpace(value), `"'`)
+			}
+		}
+		if i == len(lines) {
+			return &LoadError{Line: start + 1, Column: 1, Err: fmt.Errorf("unterminated front matter")}
+		}
+		i++
+		skipBlank()
+	}
+
+	if i == len(lines) {
+		return &LoadError{Err: fmt.Errorf("empty diagram")}
+	}
+	if header := strings.TrimSpace(lines[i]); header != "stateDiagram-v2" && header != "stateDiagram" {
+		return mermaidError(i+1, lines[i], "expected stateDiagram-v2, got %q", header)
+	}
+
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if name, ok := strings.CutPrefix(strings.TrimSpace(line), "%% transition "); ok {
+			p.name = strings.TrimSpace(name)
+			continue
+		}
+		if mermaidSkip(line) {
+			continue
+		}
+		text := strings.TrimSpace(line)
+		if strings.HasPrefix(text, "note ") {
+			// Notes are either a single line or a block closed by "end note"
+			if strings.Contains(text, ":") {
+				continue
+			}
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "end note"; i++ {
+			}
+			continue
+		}
+		if err := p.statement(i+1, line, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statement parses a single line of the diagram
+func (p *mermaidParser) statement(number int, line, text string) error {
+	fields := strings.Fields(text)
+	switch {
+	case strings.Contains(text, "-->"):
+		return p.edge(number, line, text)
+	case fields[0] == "direction" || fields[0] == "accTitle:" || fields[0] == "accDescr:":
+		return nil
+	case fields[0] == "classDef":
+		if len(fields) < 2 {
+			return mermaidError(number, line, "classDef needs a name")
+		}
+		for _, style := range strings.Split(strings.Join(fields[2:], " "), ",") {
+			if key, value, ok := strings.Cut(style, ":"); ok && strings.TrimSpace(key) == "fill" {
+				p.fills[fields[1]] = strings.TrimSpace(strings.TrimSuffix(value, ";"))
+			}
+		}
+		return nil
+	case fields[0] == "class":
+		if len(fields) != 3 {
+			return mermaidError(number, line, "expected class <states> <class>")
+		}
+		for _, id := range strings.Split(fields[1], ",") {
+			place, err := p.place(number, line, strings.TrimSpace(id))
+			if err != nil {
+				return err
+			}
+			p.classes[place] = append(p.classes[place], fields[2])
+		}
+		return nil
+	case fields[0] == "state":
+		return p.state(number, line, text)
+	case text == "--" || strings.HasSuffix(text, "{") || text == "}":
+		return mermaidError(number, line, "%w: composite state", ErrUnsupportedElement)
+	}
+
+	// A state, optionally followed by its description
+	ref, class := mermaidRef(text)
+	id, description, hasDescription := strings.Cut(ref, ":")
+	place, err := p.place(number, line, strings.TrimSpace(id))
+	if err != nil {
+		return err
+	}
+	if class != "" {
+		p.classes[place] = append(p.classes[place], class)
+	}
+	if hasDescription {
+		p.labels[place] = mermaidUnescape(strings.TrimSpace(description))
+	}
+	return nil
+}
+
+// state parses a state declaration
+func (p *mermaidParser) state(number int, line, text string) error {
+	rest := strings.TrimSpace(strings.TrimPrefix(text, "state"))
+	if strings.HasSuffix(rest, "{") {
+		return mermaidError(number, line, "%w: composite state", ErrUnsupportedElement)
+	}
+	if strings.HasPrefix(rest, `"`) {
+		end := strings.Index(rest[1:], `"`)
+		if end < 0 {
+			return mermaidError(number, line, "unterminated state description")
+		}
+		label := rest[1 : end+1]
+		id := strings.TrimSpace(rest[end+2:])
+		if !strings.HasPrefix(id, "as ") {
+			return mermaidError(number, line, `expected state "description" as <id>`)
+		}
+		place, err := p.place(number, line, strings.TrimSpace(strings.TrimPrefix(id, "as ")))
+		if err != nil {
+			return err
+		}
+		p.labels[place] = mermaidUnescape(label)
+		return nil
+	}
+
+	fields := strings.Fields(rest)
+	switch {
+	case len(fields) == 1:
+		_, err := p.place(number, line, fields[0])
+		return err
+	case len(fields) == 2 && (fields[1] == "<<fork>>" || fields[1] == "<<join>>"):
+		if !mermaidID.MatchString(fields[0]) {
+			return mermaidError(number, line, "invalid state %q", fields[0])
+		}
+		if p.seen[Place(fields[0])] {
+			return mermaidError(number, line, "state '%s' is used before being declared as a %s", fields[0], fields[1])
+		}
+		p.bars[fields[0]] = &mermaidBar{line: number, group: fields[0]}
+		return nil
+	case len(fields) == 2 && strings.HasPrefix(fields[1], "<<"):
+		return mermaidError(number, line, "%w %s", ErrUnsupportedElement, fields[1])
+	}
+	return mermaidError(number, line, "invalid state declaration")
+}
+
+// edge parses an arrow between two states
+func (p *mermaidParser) edge(number int, line, text string) error {
+	left, right, _ := strings.Cut(text, "-->")
+	label := ""
+	if i := strings.Index(strings.ReplaceAll(right, ":::", "   "), ":"); i >= 0 {
+		right, label = right[:i], strings.TrimSpace(right[i+1:])
+	}
+	from, fromClass := mermaidRef(left)
+	to, toClass := mermaidRef(right)
+	column := strings.Index(line, "-->") + 1
+	name := p.name
+	p.name = ""
+
+	switch {
+	case from == "[*]" && to == "[*]":
+		return mermaidError(number, line, "arrow from [*] to [*]")
+	case from == "[*]":
+		if p.bars[to] != nil {
+			return mermaidError(number, line, "arrow from [*] to a fork or join state")
+		}
+		place, err := p.place(number, line, to)
+		if err != nil {
+			return err
+		}
+		p.initial = append(p.initial, place)
+		return nil
+	case to == "[*]":
+		if p.bars[from] != nil {
+			return mermaidError(number, line, "arrow from a fork or join state to [*]")
+		}
+		place, err := p.place(number, line, from)
+		if err != nil {
+			return err
+		}
+		p.final = append(p.final, place)
+		return nil
+	}
+
+	for _, ref := range [][2]string{{from, fromClass}, {to, toClass}} {
+		if p.bars[ref[0]] != nil {
+			continue
+		}
+		place, err := p.place(number, line, ref[0])
+		if err != nil {
+			return err
+		}
+		if ref[1] != "" {
+			p.classes[place] = append(p.classes[place], ref[1])
+		}
+	}
+	if p.bars[from] != nil && p.bars[to] != nil {
+		p.mergeBars(from, to)
+	}
+	if p.bars[from] != nil || p.bars[to] != nil {
+		// Bars carry the transition name themselves
+		name = ""
+	}
+	p.edges = append(p.edges, mermaidEdge{from: from, to: to, label: label, name: name, line: number, column: column})
+	return nil
+}
+
+// place records a reference to a state
+func (p *mermaidParser) place(number int, line, id string) (Place, error) {
+	if !mermaidID.MatchString(id) {
+		return "", mermaidError(number, line, "invalid state %q", id)
+	}
+	if p.bars[id] != nil {
+		return "", mermaidError(number, line, "'%s' is a fork or join state", id)
+	}
+	place := Place(id)
+	if !p.seen[place] {
+		p.seen[place] = true
+		p.places = append(p.places, place)
+	}
+	return place, nil
+}
+
+// mergeBars joins the groups of two connected bars
+func (p *mermaidParser) mergeBars(a, b string) {
+	from, to := p.bars[a].group, p.bars[b].group
+	for _, bar := range p.bars {
+		if bar.group == to {
+			bar.group = from
+		}
+	}
+}
+
+// definition builds the definition from the parsed diagram
+func (p *mermaidParser) definition() (*Definition, Place, error) {
+	if len(p.initial) == 0 {
+		return nil, "", &LoadError{Err: fmt.Errorf("no initial state: add [*] --> <state>")}
+	}
+
+	// Each arrow between places is a transition, and each group of connected
+	// bars is a transition from the places entering it to the places leaving it
+	type barTransition struct {
+		edge     mermaidEdge
+		group    string
+		name     string
+		label    string
+		from, to []Place
+	}
+	var transitions []Transition
+	groups := make(map[string]*barTransition)
+	var order []interface{}
+	for _, edge := range p.edges {
+		fromBar, toBar := p.bars[edge.from], p.bars[edge.to]
+		if fromBar == nil && toBar == nil {
+			name := edge.name
+			if name == "" {
+				name = edge.label
+			}
+			if name == "" {
+				name = "to_" + edge.to
+			}
+			t, err := NewTransition(name, []Place{Place(edge.from)}, []Place{Place(edge.to)})
+			if err != nil {
+				return nil, "", &LoadError{Line: edge.line, Column: edge.column, Err: err}
+			}
+			if edge.label != "" && edge.label != name {
+				t.SetMetadata(MetadataLabel, edge.label)
+			}
+			order = append(order, t)
+			continue
+		}
+
+		bar := edge.from
+		if toBar != nil {
+			bar = edge.to
+		}
+		group := p.bars[bar].group
+		bt := groups[group]
+		if bt == nil {
+			bt = &barTransition{edge: edge, group: group}
+			groups[group] = bt
+			order = append(order, bt)
+		}
+		if bt.label == "" {
+			bt.label = edge.label
+		}
+		if bt.name == "" {
+			if name := strings.TrimSuffix(strings.TrimSuffix(bar, "_fork"), "_join"); name != bar {
+				bt.name = name
+			}
+		}
+		if fromBar == nil {
+			bt.from = append(bt.from, Place(edge.from))
+		}
+		if toBar == nil {
+			bt.to = append(bt.to, Place(edge.to))
+		}
+	}
+	for _, item := range order {
+		switch item := item.(type) {
+		case *Transition:
+			transitions = append(transitions, *item)
+		case *barTransition:
+			name := item.name
+			if name == "" {
+				name = item.label
+			}
+			if name == "" {
+				name = item.group
+			}
+			if len(item.from) == 0 || len(item.to) == 0 {
+				return nil, "", &LoadError{Line: item.edge.line, Column: item.edge.column, Err: fmt.Errorf("fork or join of transition '%s' needs incoming and outgoing states", name)}
+			}
+			t, err := NewTransition(name, uniquePlaces(item.from), uniquePlaces(item.to))
+			if err != nil {
+				return nil, "", &LoadError{Line: item.edge.line, Column: item.edge.column, Err: err}
+			}
+			if item.label != "" && item.label != name {
+				t.SetMetadata(MetadataLabel, item.label)
+			}
+			transitions = append(transitions, *t)
+		}
+	}
+
+	opts := []DefinitionOption{WithInitialPlaces(uniquePlaces(p.initial)...)}
+	if len(p.final) > 0 {
+		opts = append(opts, WithFinalPlaces(uniquePlaces(p.final)...))
+	}
+	if p.title != "" {
+		opts = append(opts, WithMetadata(map[string]interface{}{MetadataLabel: p.title}))
+	}
+	for _, place := range p.places {
+		metadata := make(map[string]interface{})
+		if label, ok := p.labels[place]; ok && label != string(place) {
+			metadata[MetadataLabel] = label
+		}
+		for _, class := range p.classes[place] {
+			if fill, ok := p.fills[class]; ok {
+				metadata[MetadataColor] = fill
+			}
+		}
+		if len(metadata) > 0 {
+			opts = append(opts, WithPlaceMetadata(place, metadata))
+		}
+	}
+
+	definition, err := NewDefinition(p.places, transitions, opts...)
+	if err != nil {
+		return nil, "", &LoadError{Err: err}
+	}
+	return definition, p.initial[0], nil
+}
+
+// mermaidSkip reports whether a line is blank or a comment
+func mermaidSkip(line string) bool {
+	text := strings.TrimSpace(line)
+	return text == "" || strings.HasPrefix(text, "%%")
+}
+
+// mermaidRef splits a state reference and its :::class shorthand
+func mermaidRef(ref string) (string, string) {
+	id, class, _ := strings.Cut(strings.TrimSpace(ref), ":::")
+	return strings.TrimSpace(id), strings.TrimSpace(class)
+}
+
+// mermaidUnescape reverses mermaidEscape
+func mermaidUnescape(label string) string {
+	return strings.ReplaceAll(label, "#quot;", "\"")
+}
+
+// mermaidError returns a *LoadError located at the first character of a line
+func mermaidError(number int, line, format string, args ...interface{}) error {
+	column := len(line) - len(strings.TrimLeft(line, " \t")) + 1
+	return &LoadError{Line: number, Column: column, Err: fmt.Errorf(format, args...)}
+}
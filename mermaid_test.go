@@ -0,0 +1,396 @@
+package workflow_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+func TestImportMermaid_Example(t *testing.T) {
+	data, err := os.ReadFile("examples/document_approval/diagram.mermaid")
+	if err != nil {
+		t.Fatalf("failed to read diagram: %v", err)
+	}
+	def, initial, err := workflow.ImportMermaid(data)
+	if err != nil {
+		t.Fatalf("ImportMermaid() error = %v", err)
+	}
+	if initial != "draft" {
+		t.Errorf("initial place = %q, want draft", initial)
+	}
+	if got := def.AllPlaces(); len(got) != 10 {
+		t.Errorf("places = %v", got)
+	}
+	if got := def.AllTransitions(); len(got) != 14 {
+		t.Errorf("transitions = %d, want 14", len(got))
+	}
+	submit := def.Transition("submit_for_review")
+	if submit == nil || len(submit.To()) != 2 {
+		t.Fatalf("submit_for_review should fork, got %v", submit)
+	}
+	join := def.Transition("pending_manager_approve")
+	if join == nil || len(join.From()) != 2 || join.To()[0] != "pending_manager_approval" {
+		t.Fatalf("pending_manager_approve should join, got %v", join)
+	}
+
+	wf, err := workflow.NewWorkflow("doc", def, initial)
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	if err := wf.Apply([]workflow.Place{"pending_technical_review", "pending_legal_review"}); err != nil {
+		t.Errorf("Apply() error = %v", err)
+	}
+}
+
+func TestImportMermaid_RoundTrip(t *testing.T) {
+	def, err := workflow.NewBuilder("order").
+		Metadata(workflow.MetadataLabel, "Order").
+		Place("new", "paid", "packed", "shipped", "cancelled").
+		InitialPlace("new").
+		PlaceMetadata("paid", workflow.MetadataLabel, `Paid "in full"`).
+		PlaceMetadata("shipped", workflow.MetadataColor, "#00ff00").
+		Transition("pay").From("new").To("paid", "packed").
+		Transition("ship").From("paid", "packed").To("shipped").
+		Metadata(workflow.MetadataLabel, "Ship order").
+		Transition("split").From("shipped").To("paid", "packed").
+		Transition("merge").From("paid", "packed").To("new", "shipped").
+		Transition("cancel").From("new").To("cancelled").
+		Metadata(workflow.MetadataLabel, "Cancel: now").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	wf, err := workflow.NewWorkflow("o1", def, "new")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+
+	imported, initial, err := workflow.ImportMermaid([]byte(wf.Diagram()))
+	if err != nil {
+		t.Fatalf("ImportMermaid() error = %v\n%s", err, wf.Diagram())
+	}
+	if initial != "new" {
+		t.Errorf("initial place = %q", initial)
+	}
+	// Diagrams do not carry the definition name
+	got, _ := imported.MarshalJSON()
+	want, _ := def.MarshalJSON()
+	if string(got) != strings.Replace(string(want), `"name":"order",`, "", 1) {
+		t.Errorf("definition changed after round trip:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestImportMermaid_DiagramOptions(t *testing.T) {
+	def := workflow.NewBuilder("order").
+		Place("new", "paid", "packed", "shipped").
+		InitialPlace("new").
+		FinalPlace("shipped").
+		PlaceMetadata("paid", "owner", "billing").
+		Transition("pay").From("new").To("paid", "packed").
+		Metadata("cost", 2).
+		Transition("ship").From("paid", "packed").To("shipped").
+		Transition("submit").From("new").To("shipped").
+		Metadata("owner", "ops").
+		MustBuild()
+	diagram := def.Diagram(workflow.DiagramOptions{
+		RenderOptions:    workflow.RenderOptions{Metadata: true},
+		HighlightEnabled: true,
+		Path:             []string{"pay", "ship"},
+		Styles:           map[string]string{"blocked": "fill:#fcc"},
+		PlaceClasses:     map[workflow.Place]string{"packed": "blocked"},
+	})
+
+	imported, initial, err := workflow.ImportMermaid([]byte(diagram))
+	if err != nil {
+		t.Fatalf("ImportMermaid() error = %v\n%s", err, diagram)
+	}
+	if initial != "new" {
+		t.Errorf("initial place = %q", initial)
+	}
+	if got := imported.FinalPlaces(); len(got) != 1 || got[0] != "shipped" {
+		t.Errorf("final places = %v\n%s", got, diagram)
+	}
+	var names []string
+	for _, tr := range imported.AllTransitions() {
+		names = append(names, tr.Name())
+	}
+	if got := strings.Join(names, ","); got != "pay,ship,submit" {
+		t.Errorf("transitions = %s\n%s", got, diagram)
+	}
+}
+
+func TestImportMermaid_Syntax(t *testing.T) {
+	diagram := `---
+title: "Tickets"
+---
+stateDiagram-v2
+    direction LR
+    classDef urgent fill:#f00,color:white
+    [*] --> open
+    open : Open ticket
+    open --> triage:::urgent
+    note right of triage
+        Handled by support
+    end note
+    note left of open : first state
+    triage --> closed : resolve
+    closed --> [*]
+`
+	def, initial, err := workflow.ImportMermaid([]byte(diagram))
+	if err != nil {
+		t.Fatalf("ImportMermaid() error = %v", err)
+	}
+	if initial != "open" {
+		t.Errorf("initial place = %q", initial)
+	}
+	if label, _ := def.Metadata(workflow.MetadataLabel); label != "Tickets" {
+		t.Errorf("title = %v", label)
+	}
+	if label, _ := def.PlaceMetadata("open", workflow.MetadataLabel); label != "Open ticket" {
+		t.Errorf("open label = %v", label)
+	}
+	if color, _ := def.PlaceMetadata("triage", workflow.MetadataColor); color != "#f00" {
+		t.Errorf("triage color = %v", color)
+	}
+	if got := def.FinalPlaces(); len(got) != 1 || got[0] != "closed" {
+		t.Errorf("final places = %v", got)
+	}
+	var names []string
+	for _, tr := range def.AllTransitions() {
+		names = append(names, tr.Name())
+	}
+	if got := strings.Join(names, ","); got != "to_triage,resolve" {
+		t.Errorf("transitions = %v", got)
+	}
+}
+
+func TestImportMermaid_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		line     int
+		column   int
+		message  string
+		sentinel error
+	}{
+		{
+			name:    "missing header",
+			body:    "flowchart LR\n",
+			line:    1,
+			column:  1,
+			message: `expected stateDiagram-v2, got "flowchart LR"`,
+		},
+		{
+			name:     "composite state",
+			body:     "stateDiagram-v2\n  [*] --> a\n  state b {\n  }\n",
+			line:     3,
+			column:   3,
+			message:  "composite state",
+			sentinel: workflow.ErrUnsupportedElement,
+		},
+		{
+			name:     "choice",
+			body:     "stateDiagram-v2\n  [*] --> a\n  state c <<choice>>\n",
+			line:     3,
+			column:   3,
+			message:  "<<choice>>",
+			sentinel: workflow.ErrUnsupportedElement,
+		},
+		{
+			name:    "invalid state",
+			body:    "stateDiagram-v2\n  [*] --> a\n  a --> b c : go\n",
+			line:    3,
+			column:  3,
+			message: `invalid state "b c"`,
+		},
+		{
+			name:    "dangling fork",
+			body:    "stateDiagram-v2\n  [*] --> a\n  state f <<fork>>\n  a --> f : go\n",
+			line:    4,
+			column:  5,
+			message: "fork or join of transition 'go' needs incoming and outgoing states",
+		},
+		{
+			name:    "no initial state",
+			body:    "stateDiagram-v2\n  a --> b\n",
+			message: "no initial state",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := workflow.ImportMermaid([]byte(tt.body))
+			var loadErr *workflow.LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("expected a *LoadError, got %v", err)
+			}
+			if loadErr.Line != tt.line || loadErr.Column != tt.column || !strings.Contains(loadErr.Error(), tt.message) {
+				t.Errorf("error = %v, want %d:%d containing %q", loadErr, tt.line, tt.column, tt.message)
+			}
+			if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+				t.Errorf("expected %v, got %v", tt.sentinel, err)
+			}
+		})
+	}
+}
+
+func TestDefinition_Diagram(t *testing.T) {
+	def := workflow.NewBuilder("article").
+		Metadata(workflow.MetadataLabel, "Article").
+		Metadata("title", "Article lifecycle").
+		Place("draft", "review", "published").
+		InitialPlace("draft").
+		PlaceMetadata("review", workflow.MetadataLabel, "In review").
+		PlaceMetadata("review", "title", "Reviewing").
+		Transition("submit").From("draft").To("review").
+		Transition("publish").From("review").To("published").
+		Metadata(workflow.MetadataLabel, "Publish now").
+		Transition("reject").From("review").To("draft").
+		MustBuild()
+
+	want := `---
+title: Article
+---
+stateDiagram-v2
+    classDef currentPlace font-weight:bold,stroke-width:4px
+    draft
+    state "In review" as review
+    published
+    draft --> review : submit
+    %% transition publish
+    review --> published : Publish now
+    review --> draft : reject
+
+    %% Initial place
+    [*] --> draft
+
+    %% Final places
+    published --> [*]
+`
+	if got := def.Diagram(workflow.DiagramOptions{}); got != want {
+		t.Errorf("Diagram() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name     string
+		opts     workflow.DiagramOptions
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "direction",
+			opts:     workflow.DiagramOptions{Direction: "LR"},
+			contains: []string{"stateDiagram-v2\n    direction LR\n"},
+		},
+		{
+			name: "label key and metadata",
+			opts: workflow.DiagramOptions{LabelKey: "title", RenderOptions: workflow.RenderOptions{Metadata: true}},
+			contains: []string{
+				"title: Article lifecycle\n",
+				`state "Reviewing" as review`,
+				"    %% Metadata\n    note right of review\n        title: Reviewing\n    end note\n",
+			},
+			excludes: []string{"<br/>"},
+		},
+		{
+			name:     "hidden transition names",
+			opts:     workflow.DiagramOptions{HideTransitionNames: true},
+			contains: []string{"    draft --> review\n", "    review --> draft\n"},
+			excludes: []string{"submit", "Publish now"},
+		},
+		{
+			name: "highlight and counts",
+			opts: workflow.DiagramOptions{RenderOptions: workflow.RenderOptions{
+				Highlight: []workflow.Place{"review"},
+				Counts:    map[workflow.Place]int{"review": 3, "published": 1},
+			}},
+			contains: []string{
+				"    %% Instances\n    note left of review\n        3 instances\n    end note\n    note left of published\n        1 instance\n    end note\n",
+				"    %% Current places\n    class review currentPlace\n",
+			},
+		},
+		{
+			name: "enabled transitions",
+			opts: workflow.DiagramOptions{HighlightEnabled: true},
+			contains: []string{
+				"    classDef enabledPlace stroke-dasharray:5 5\n",
+				"    %% Enabled transitions\n    class review enabledPlace\n",
+			},
+		},
+		{
+			name: "path",
+			opts: workflow.DiagramOptions{Path: []string{"submit", "reject", "submit", "publish"}},
+			contains: []string{
+				"    draft --> review : submit\n",
+				"    review --> published : Publish now\n",
+				"    %% Path taken\n    class draft visitedPlace\n    class review visitedPlace\n    class published visitedPlace\n",
+				"    note left of draft\n        2. reject\n    end note\n",
+				"    note left of review\n        1. submit\n        3. submit\n    end note\n",
+				"    note left of published\n        4. publish\n    end note\n",
+			},
+		},
+		{
+			name: "custom styles",
+			opts: workflow.DiagramOptions{
+				Styles: map[string]string{
+					"currentPlace": "stroke:#f00",
+					"blocked":      "fill:#fcc",
+				},
+				PlaceClasses: map[workflow.Place]string{"review": "blocked"},
+			},
+			contains: []string{
+				"    classDef currentPlace stroke:#f00\n    classDef blocked fill:#fcc\n",
+				"    %% Custom classes\n    class review blocked\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := def.Diagram(tt.opts)
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("Diagram() does not contain %q:\n%s", want, got)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("Diagram() contains %q:\n%s", unwanted, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkflow_DiagramWithOptions(t *testing.T) {
+	def := workflow.NewBuilder("order").
+		Place("new", "paid", "packed", "shipped").
+		Transition("pay").From("new").To("paid", "packed").
+		Transition("ship").From("paid", "packed").To("shipped").
+		MustBuild()
+	wf, err := workflow.NewWorkflow("o1", def, "new")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	if wf.DiagramWithOptions(workflow.DiagramOptions{}) != wf.Diagram() {
+		t.Error("DiagramWithOptions() with zero options should match Diagram()")
+	}
+	if err := wf.Apply([]workflow.Place{"paid", "packed"}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	got := wf.DiagramWithOptions(workflow.DiagramOptions{HighlightEnabled: true, Path: []string{"pay"}})
+	for _, want := range []string{
+		"    new --> pay_fork : pay\n",
+		"    note left of paid\n        1. pay\n    end note\n",
+		"    paid --> ship_join : ship\n",
+		"    %% Enabled transitions\n    class shipped enabledPlace\n",
+		"    %% Current places\n    class paid currentPlace\n    class packed currentPlace\n",
+		"    %% Initial place\n    [*] --> new\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("DiagramWithOptions() does not contain %q:\n%s", want, got)
+		}
+	}
+}
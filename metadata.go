@@ -0,0 +1,104 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Well-known metadata keys used by diagram generators
+const (
+	// MetadataLabel is a human readable label for a place, transition or definition
+	MetadataLabel = "label"
+	// MetadataDescription is a longer description
+	MetadataDescription = "description"
+	// MetadataColor is a CSS color used to style places in diagrams
+	MetadataColor = "color"
+	// MetadataOwner is the team owning a place or definition
+	MetadataOwner = "owner"
+)
+
+// WithMetadata sets metadata on the definition
+func WithMetadata(metadata map[string]interface{}) DefinitionOption {
+	return func(d *Definition) {
+		if d.metadata == nil {
+			d.metadata = make(map[string]interface{})
+		}
+		for key, value := range metadata {
+			d.metadata[key] = value
+		}
+	}
+}
+
+// WithPlaceMetadata sets metadata on a place of the definition
+func WithPlaceMetadata(place Place, metadata map[string]interface{}) DefinitionOption {
+	return func(d *Definition) {
+		if d.placeMetadata == nil {
+			d.placeMetadata = make(map[Place]map[string]interface{})
+		}
+		if d.placeMetadata[place] == nil {
+			d.placeMetadata[place] = make(map[string]interface{})
+		}
+		for key, value := range metadata {
+			d.placeMetadata[place][key] = value
+		}
+	}
+}
+
+// Metadata returns the value for the given key from the definition metadata
+func (d *Definition) Metadata(key string) (interface{}, bool) {
+	value, ok := d.metadata[key]
+	return value, ok
+}
+
+// AllMetadata returns a copy of the definition metadata
+func (d *Definition) AllMetadata() map[string]interface{} {
+	return copyMetadata(d.metadata)
+}
+
+// PlaceMetadata returns the value for the given key from the metadata of a place
+func (d *Definition) PlaceMetadata(place Place, key string) (interface{}, bool) {
+	value, ok := d.placeMetadata[place][key]
+	return value, ok
+}
+
+// AllPlaceMetadata returns a copy of the metadata of a place
+func (d *Definition) AllPlaceMetadata(place Place) map[string]interface{} {
+	return copyMetadata(d.placeMetadata[place])
+}
+
+// AllMetadata returns a copy of the transition metadata
+func (t *Transition) AllMetadata() map[string]interface{} {
+	return copyMetadata(t.metadata)
+}
+
+// copyMetadata returns a shallow copy of a metadata map
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		result[key] = value
+	}
+	return result
+}
+
+// metadataString returns a metadata value as a string, if it is one
+func metadataString(metadata map[string]interface{}, key string) (string, bool) {
+	value, ok := metadata[key].(string)
+	return value, ok && value != ""
+}
+
+// metadataLines returns "key: value" lines for the metadata, except the
+// label and color used for styling
+func metadataLines(metadata map[string]interface{}) []string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		if key != MetadataLabel && key != MetadataColor {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = fmt.Sprintf("%s: %v", key, metadata[key])
+	}
+	return lines
+}
@@ -0,0 +1,173 @@
+package workflow_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+func TestDefinition_Metadata(t *testing.T) {
+	submit := workflow.MustNewTransition("submit", []workflow.Place{"draft"}, []workflow.Place{"review"})
+	submit.SetMetadata(workflow.MetadataLabel, "Submit for review")
+	def, err := workflow.NewDefinition(
+		[]workflow.Place{"draft", "review"},
+		[]workflow.Transition{*submit},
+		workflow.WithMetadata(map[string]interface{}{
+			workflow.MetadataLabel: "Article",
+			workflow.MetadataOwner: "content-team",
+		}),
+		workflow.WithPlaceMetadata("review", map[string]interface{}{
+			workflow.MetadataLabel: "In review",
+			workflow.MetadataColor: "#ffcc00",
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create definition: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		places []workflow.Place
+		got    func(wf *workflow.Workflow) (string, error)
+		want   string
+	}{
+		{
+			name: "definition metadata",
+			got: func(wf *workflow.Workflow) (string, error) {
+				value, ok := wf.Definition().Metadata(workflow.MetadataOwner)
+				return fmt.Sprintf("%v %v", value, ok), nil
+			},
+			want: "content-team true",
+		},
+		{
+			name: "place metadata",
+			got: func(wf *workflow.Workflow) (string, error) {
+				value, ok := wf.Definition().PlaceMetadata("review", workflow.MetadataLabel)
+				return fmt.Sprintf("%v %v", value, ok), nil
+			},
+			want: "In review true",
+		},
+		{
+			name: "missing place metadata",
+			got: func(wf *workflow.Workflow) (string, error) {
+				value, ok := wf.Definition().PlaceMetadata("draft", workflow.MetadataLabel)
+				return fmt.Sprintf("%v %v", value, ok), nil
+			},
+			want: "<nil> false",
+		},
+		{
+			name: "returned maps are copies",
+			got: func(wf *workflow.Workflow) (string, error) {
+				wf.Definition().AllPlaceMetadata("review")[workflow.MetadataLabel] = "changed"
+				label, _ := wf.Definition().PlaceMetadata("review", workflow.MetadataLabel)
+				return fmt.Sprint(label), nil
+			},
+			want: "In review",
+		},
+		{
+			name: "json",
+			got: func(wf *workflow.Workflow) (string, error) {
+				data, err := json.Marshal(wf.Definition())
+				return string(data), err
+			},
+			want: `{"metadata":{"label":"Article","owner":"content-team"},` +
+				`"places":[{"name":"draft"},{"name":"review","metadata":{"color":"#ffcc00","label":"In review"}}],` +
+				`"transitions":[{"name":"submit","from":["draft"],"to":["review"],"metadata":{"label":"Submit for review"}}]}`,
+		},
+		{
+			name: "mermaid",
+			got:  func(wf *workflow.Workflow) (string, error) { return wf.Diagram(), nil },
+			want: `---
+title: Article
+---
+stateDiagram-v2
+    classDef currentPlace font-weight:bold,stroke-width:4px
+    classDef place_review fill:#ffcc00
+    draft
+    state "In review" as review
+    %% transition submit
+    draft --> review : Submit for review
+
+    %% Place styles
+    class review place_review
+
+    %% Current places
+    class draft currentPlace
+
+    %% Initial place
+    [*] --> draft
+`,
+		},
+		{
+			name:   "dot",
+			places: []workflow.Place{"review"},
+			got:    func(wf *workflow.Workflow) (string, error) { return wf.DOT(workflow.DotOptions{}), nil },
+			want: `digraph "" {
+    label="Article";
+    labelloc=t;
+    rankdir=LR;
+    node [fontsize=10];
+    edge [fontsize=9];
+
+    "place_draft" [shape=circle, label="draft"];
+    "place_review" [shape=circle, label="In review", style=filled, fillcolor="#ffcc00", penwidth=3];
+    start [shape=point, width=0.15];
+    start -> "place_draft";
+
+    transition_0 [shape=box, label="Submit for review"];
+    "place_draft" -> transition_0;
+    transition_0 -> "place_review";
+}
+`,
+		},
+		{
+			name: "plantuml",
+			got:  func(wf *workflow.Workflow) (string, error) { return wf.PlantUML(workflow.PlantUMLOptions{}), nil },
+			want: `@startuml
+title Article
+hide empty description
+
+state draft #line.bold
+state "In review" as review #ffcc00
+
+[*] --> draft
+draft --> review : Submit for review
+@enduml
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf, err := workflow.NewWorkflow("article", def, "draft")
+			if err != nil {
+				t.Fatalf("failed to create workflow: %v", err)
+			}
+			if tt.places != nil {
+				if err := wf.Apply(tt.places); err != nil {
+					t.Fatalf("Apply() error = %v", err)
+				}
+			}
+			got, err := tt.got(wf)
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDefinition_PlaceMetadataUnknownPlace(t *testing.T) {
+	_, err := workflow.NewDefinition(
+		[]workflow.Place{"draft"},
+		[]workflow.Transition{},
+		workflow.WithPlaceMetadata("missing", map[string]interface{}{"label": "Missing"}),
+	)
+	if err == nil {
+		t.Error("NewDefinition() error = nil, want error")
+	}
+}
@@ -0,0 +1,238 @@
+package workflow
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CostMetadataKey is the transition metadata key conventionally holding a transition cost
+const CostMetadataKey = "cost"
+
+// PlanOptions configures path planning
+type PlanOptions struct {
+	// Exact requires the final marking to equal the goal places instead of containing them
+	Exact bool
+	// CostKey is the transition metadata key holding a numeric cost. When empty,
+	// every transition costs 1 and the shortest path is returned.
+	CostKey string
+	// MaxStates bounds the number of explored markings. Defaults to DefaultMaxStates.
+	MaxStates int
+}
+
+// Path is a sequence of transitions leading to a goal marking
+type Path struct {
+	Transitions []Transition
+	Cost        float64
+}
+
+// Names returns the names of the transitions of the path
+func (p *Path) Names() []string {
+	names := make([]string, len(p.Transitions))
+	for i, t := range p.Transitions {
+		names[i] = t.Name()
+	}
+	return names
+}
+
+// PathError is returned when a step of a path cannot be applied
+type PathError struct {
+	Step       int
+	Transition string
+	Err        error
+}
+
+// Error implements the error interface
+func (e *PathError) Error() string {
+	return fmt.Sprintf("step %d (%s) failed: %v", e.Step, e.Transition, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// planState is a marking explored by the planner
+type planState struct {
+	places []Place
+	cost   float64
+	seq    int
+	parent *planState
+	via    *Transition
+	index  int
+}
+
+// planQueue is a priority queue of planner states ordered by cost
+type planQueue []*planState
+
+func (q planQueue) Len() int { return len(q) }
+func (q planQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].seq < q[j].seq
+}
+func (q planQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *planQueue) Push(x interface{}) {
+	s := x.(*planState)
+	s.index = len(*q)
+	*q = append(*q, s)
+}
+func (q *planQueue) Pop() interface{} {
+	old := *q
+	s := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return s
+}
+
+// Plan finds the cheapest sequence of transitions from the workflow's current
+// marking to a marking containing the goal places (or equal to them when
+// opts.Exact is set). Guards and constraints are not evaluated while planning;
+// they are checked when the path is applied with ApplyPath.
+func (w *Workflow) Plan(goal []Place, opts PlanOptions) (*Path, error) {
+	if len(goal) == 0 {
+		return nil, fmt.Errorf("goal cannot be empty")
+	}
+	definition := w.Definition()
+	for _, place := range goal {
+		if !definition.Place(place) {
+			return nil, ErrInvalidPlace
+		}
+	}
+	maxStates := opts.MaxStates
+	if maxStates <= 0 {
+		maxStates = DefaultMaxStates
+	}
+
+	transitions := definition.AllTransitions()
+	costs := make([]float64, len(transitions))
+	for i := range transitions {
+		costs[i] = 1
+		if opts.CostKey == "" {
+			continue
+		}
+		value, ok := transitions[i].Metadata(opts.CostKey)
+		if !ok {
+			continue
+		}
+		cost, ok := toFloat(value)
+		if !ok || cost < 0 {
+			return nil, fmt.Errorf("transition '%s' has invalid cost %v", transitions[i].Name(), value)
+		}
+		costs[i] = cost
+	}
+
+	goalSet := placeSet(goal)
+	reached := func(places []Place) bool {
+		set := placeSet(places)
+		if opts.Exact && len(set) != len(goalSet) {
+			return false
+		}
+		return containsAll(set, goal)
+	}
+
+	start := &planState{places: normalizePlaces(w.CurrentPlaces())}
+	best := map[string]float64{planKey(start.places): 0}
+	queue := &planQueue{start}
+	seq := 0
+	for queue.Len() > 0 {
+		state := heap.Pop(queue).(*planState)
+		if state.cost > best[planKey(state.places)] {
+			continue
+		}
+		if reached(state.places) {
+			return state.path(), nil
+		}
+
+		current := placeSet(state.places)
+		for i := range transitions {
+			t := &transitions[i]
+			if !containsAll(current, t.From()) {
+				continue
+			}
+			next := firePlaces(state.places, t)
+			key := planKey(next)
+			cost := state.cost + costs[i]
+			if c, ok := best[key]; ok && c <= cost {
+				continue
+			}
+			if len(best) >= maxStates {
+				return nil, fmt.Errorf("%w: explored %d markings", ErrStateLimitExceeded, len(best))
+			}
+			best[key] = cost
+			seq++
+			heap.Push(queue, &planState{places: next, cost: cost, seq: seq, parent: state, via: t})
+		}
+	}
+
+	return nil, fmt.Errorf("%w to [%s]", ErrNoPath, joinPlaces(goal))
+}
+
+// ApplyPath applies the transitions of a path one after the other. Each step
+// applies the planned transition, matched by name and places, even when other
+// transitions lead to the same places. It stops at the first step that cannot
+// be applied, for example because a guard blocks it, and returns a *PathError
+// describing that step.
+func (w *Workflow) ApplyPath(ctx context.Context, path *Path) error {
+	for i, t := range path.Transitions {
+		planned := t
+		match := func(c *Transition) bool {
+			return c.Name() == planned.Name() && samePlaces(c.From(), planned.From()) && samePlaces(c.To(), planned.To())
+		}
+		if err := w.apply(ctx, match, t.To(), nil); err != nil {
+			return &PathError{Step: i, Transition: t.Name(), Err: err}
+		}
+	}
+	return nil
+}
+
+// path reconstructs the path leading to the state
+func (s *planState) path() *Path {
+	var transitions []Transition
+	for n := s; n.parent != nil; n = n.parent {
+		transitions = append(transitions, *n.via)
+	}
+	for i, j := 0, len(transitions)-1; i < j; i, j = i+1, j-1 {
+		transitions[i], transitions[j] = transitions[j], transitions[i]
+	}
+	return &Path{Transitions: transitions, Cost: s.cost}
+}
+
+// firePlaces returns the places marked after firing a transition, using the
+// same semantics as Workflow.Apply: source places are unmarked and target places marked
+func firePlaces(places []Place, t *Transition) []Place {
+	from := placeSet(t.From())
+	next := make([]Place, 0, len(places)+len(t.To()))
+	for _, place := range places {
+		if !from[place] {
+			next = append(next, place)
+		}
+	}
+	return normalizePlaces(append(next, t.To()...))
+}
+
+// normalizePlaces returns the distinct places in lexical order
+func normalizePlaces(places []Place) []Place {
+	set := placeSet(places)
+	result := make([]Place, 0, len(set))
+	for place := range set {
+		result = append(result, place)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// planKey returns a map key for a normalized set of places
+func planKey(places []Place) string {
+	names := make([]string, len(places))
+	for i, place := range places {
+		names[i] = string(place)
+	}
+	return strings.Join(names, "\x00")
+}
@@ -0,0 +1,170 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+var plannerOrderDefinition = workflow.NewBuilder("order").
+	Place("payment_failed", "pending", "paid", "manual_review", "shipping").
+	Transition("retry").From("payment_failed").To("pending").Metadata(workflow.CostMetadataKey, 5).
+	Transition("confirm").From("pending").To("paid").Metadata(workflow.CostMetadataKey, 5).
+	Transition("escalate").From("payment_failed").To("manual_review").Metadata(workflow.CostMetadataKey, 1).
+	Transition("approve").From("manual_review").To("paid").Metadata(workflow.CostMetadataKey, 1).
+	Transition("fast_track").From("payment_failed").To("shipping").Metadata(workflow.CostMetadataKey, 100).
+	Transition("ship").From("paid").To("shipping").Metadata(workflow.CostMetadataKey, 1).
+	MustBuild()
+
+func TestWorkflow_Plan(t *testing.T) {
+	review := workflow.NewBuilder("review").
+		Place("start", "legal", "finance", "done").
+		Transition("split").From("start").To("legal", "finance").
+		Transition("finish_legal").From("legal").To("done").
+		MustBuild()
+
+	tests := []struct {
+		name       string
+		definition *workflow.Definition
+		initial    workflow.Place
+		target     workflow.Place
+		opts       workflow.PlanOptions
+		wantPath   string
+		wantCost   float64
+		wantErr    error
+	}{
+		{
+			name:       "shortest",
+			definition: plannerOrderDefinition,
+			initial:    "payment_failed",
+			target:     "shipping",
+			wantPath:   "fast_track",
+			wantCost:   1,
+		},
+		{
+			name:       "cheapest",
+			definition: plannerOrderDefinition,
+			initial:    "payment_failed",
+			target:     "shipping",
+			opts:       workflow.PlanOptions{CostKey: workflow.CostMetadataKey},
+			wantPath:   "escalate,approve,ship",
+			wantCost:   3,
+		},
+		{
+			name:       "no path",
+			definition: plannerOrderDefinition,
+			initial:    "shipping",
+			target:     "pending",
+			wantErr:    workflow.ErrNoPath,
+		},
+		{
+			name:       "covering marking",
+			definition: review,
+			initial:    "start",
+			target:     "done",
+			wantPath:   "split,finish_legal",
+			wantCost:   2,
+		},
+		{
+			// finance is never consumed, so [done] alone is unreachable
+			name:       "exact marking",
+			definition: review,
+			initial:    "start",
+			target:     "done",
+			opts:       workflow.PlanOptions{Exact: true},
+			wantErr:    workflow.ErrNoPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf, err := workflow.NewWorkflow("w1", tt.definition, tt.initial)
+			if err != nil {
+				t.Fatalf("failed to create workflow: %v", err)
+			}
+			path, err := wf.Plan([]workflow.Place{tt.target}, tt.opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Plan() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			if got := strings.Join(path.Names(), ","); got != tt.wantPath {
+				t.Errorf("path = %s, want %s", got, tt.wantPath)
+			}
+			if path.Cost != tt.wantCost {
+				t.Errorf("cost = %v, want %v", path.Cost, tt.wantCost)
+			}
+		})
+	}
+}
+
+func TestWorkflow_ApplyPath(t *testing.T) {
+	wf, _ := workflow.NewWorkflow("order-1", plannerOrderDefinition, "payment_failed")
+
+	path, err := wf.Plan([]workflow.Place{"shipping"}, workflow.PlanOptions{CostKey: workflow.CostMetadataKey})
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	wf.AddGuardEventListener(func(event *workflow.GuardEvent) error {
+		if event.Transition().Name() == "approve" {
+			event.SetBlocking(true)
+		}
+		return nil
+	})
+
+	err = wf.ApplyPath(context.Background(), path)
+	var pathErr *workflow.PathError
+	if !errors.As(err, &pathErr) {
+		t.Fatalf("ApplyPath() error = %v, want *PathError", err)
+	}
+	if pathErr.Step != 1 || pathErr.Transition != "approve" {
+		t.Errorf("PathError = %+v, want step 1 (approve)", pathErr)
+	}
+	if !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("ApplyPath() error = %v, want ErrTransitionNotAllowed", err)
+	}
+	if places := wf.CurrentPlaces(); len(places) != 1 || places[0] != "manual_review" {
+		t.Errorf("CurrentPlaces() = %v, want [manual_review]", places)
+	}
+}
+
+func TestWorkflow_ApplyPathSharedTargets(t *testing.T) {
+	def := workflow.NewBuilder("shipping").
+		Place("a", "b").
+		Transition("expensive").From("a").To("b").
+		Metadata(workflow.CostMetadataKey, 10).
+		Transition("cheap").From("a").To("b").
+		Metadata(workflow.CostMetadataKey, 1).
+		MustBuild()
+	wf, _ := workflow.NewWorkflow("s1", def, "a")
+	wf.AddGuardEventListener(func(event *workflow.GuardEvent) error {
+		if event.Transition().Name() == "expensive" {
+			event.SetBlocking(true)
+		}
+		return nil
+	})
+	var applied []string
+	wf.AddEventListener(workflow.EventAfterTransition, func(event workflow.Event) error {
+		applied = append(applied, event.Transition().Name())
+		return nil
+	})
+
+	path, err := wf.Plan([]workflow.Place{"b"}, workflow.PlanOptions{CostKey: workflow.CostMetadataKey})
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if err := wf.ApplyPath(context.Background(), path); err != nil {
+		t.Fatalf("ApplyPath() error = %v", err)
+	}
+	if len(applied) != 1 || applied[0] != "cheap" {
+		t.Errorf("applied transitions = %v, want [cheap]", applied)
+	}
+}
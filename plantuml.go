@@ -0,0 +1,271 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlantUMLStyle selects the kind of PlantUML diagram generated
+type PlantUMLStyle int
+
+const (
+	// PlantUMLState generates a state diagram, with fork and join bars for parallel transitions
+	PlantUMLState PlantUMLStyle = iota
+	// PlantUMLActivity generates an activity diagram, with places as activities
+	PlantUMLActivity
+)
+
+// PlantUMLOptions configures PlantUML export
+type PlantUMLOptions struct {
+	Style PlantUMLStyle
+	// RenderOptions sets the places to highlight and the instance counts.
+	// Metadata adds transition metadata as notes, or to the arrow labels of
+	// activity diagrams.
+	RenderOptions
+}
+
+// PlantUML generates a PlantUML diagram of the definition
+func (d *Definition) PlantUML(opts PlantUMLOptions) string {
+	return d.plantUML(opts, d.initialPlaces, nil)
+}
+
+// PlantUML generates a PlantUML diagram of the workflow, highlighting its current places
+func (w *Workflow) PlantUML(opts PlantUMLOptions) string {
+	return w.Definition().plantUML(opts, []Place{w.InitialPlace()}, w.CurrentPlaces())
+}
+
+// plantUML renders the definition with the given initial and current places
+func (d *Definition) plantUML(opts PlantUMLOptions, initial, current []Place) string {
+	highlighted := opts.highlighted(current)
+
+	var diagram strings.Builder
+	diagram.WriteString("@startuml\n")
+	if title, ok := metadataString(d.metadata, MetadataLabel); ok {
+		diagram.WriteString(fmt.Sprintf("title %s\n", title))
+	}
+	if opts.Style == PlantUMLActivity {
+		d.plantUMLActivity(&diagram, opts, initial, highlighted)
+	} else {
+		d.plantUMLState(&diagram, opts, initial, highlighted)
+	}
+	diagram.WriteString("@enduml\n")
+	return diagram.String()
+}
+
+// plantUMLState writes the body of a state diagram
+func (d *Definition) plantUMLState(diagram *strings.Builder, opts PlantUMLOptions, initial []Place, highlighted map[Place]bool) {
+	diagram.WriteString("hide empty description\n\n")
+	ids := d.plantUMLIDs()
+
+	// Add all places
+	for _, place := range d.places {
+		metadata := d.placeMetadata[place]
+		line := "state " + plantUMLPlace(place, ids.places[place], metadata)
+		var style []string
+		if color, ok := metadataString(metadata, MetadataColor); ok {
+			style = append(style, strings.TrimPrefix(color, "#"))
+		}
+		if highlighted[place] {
+			style = append(style, "line.bold")
+		}
+		if len(style) > 0 {
+			line += " #" + strings.Join(style, ";")
+		}
+		diagram.WriteString(line + "\n")
+		if count, ok := opts.instanceCount(place); ok {
+			diagram.WriteString(fmt.Sprintf("%s : %s\n", ids.places[place], count))
+		}
+	}
+
+	// Add fork and join bars
+	for i := range d.transitions {
+		if ids.joins[i] != "" {
+			diagram.WriteString(fmt.Sprintf("state %s <<join>>\n", ids.joins[i]))
+		}
+		if ids.forks[i] != "" {
+			diagram.WriteString(fmt.Sprintf("state %s <<fork>>\n", ids.forks[i]))
+		}
+	}
+	diagram.WriteString("\n")
+
+	for _, place := range initial {
+		diagram.WriteString(fmt.Sprintf("[*] --> %s\n", ids.places[place]))
+	}
+
+	// Add all transitions. The label goes on the single arrow standing for the
+	// transition, between its join and fork bars when it has any.
+	for i, trans := range d.transitions {
+		source := ids.places[trans.From()[0]]
+		if ids.joins[i] != "" {
+			source = ids.joins[i]
+			for _, from := range trans.From() {
+				diagram.WriteString(fmt.Sprintf("%s --> %s\n", ids.places[from], source))
+			}
+		}
+		target := ids.places[trans.To()[0]]
+		if ids.forks[i] != "" {
+			target = ids.forks[i]
+		}
+		diagram.WriteString(fmt.Sprintf("%s --> %s : %s\n", source, target, plantUMLLabel(trans.Name(), trans.metadata)))
+		if opts.Metadata {
+			if lines := metadataLines(trans.metadata); len(lines) > 0 {
+				diagram.WriteString("note on link\n")
+				for _, line := range lines {
+					diagram.WriteString("    " + line + "\n")
+				}
+				diagram.WriteString("end note\n")
+			}
+		}
+		if ids.forks[i] != "" {
+			for _, to := range trans.To() {
+				diagram.WriteString(fmt.Sprintf("%s --> %s\n", target, ids.places[to]))
+			}
+		}
+	}
+
+	for _, place := range d.finalPlaces {
+		diagram.WriteString(fmt.Sprintf("%s --> [*]\n", ids.places[place]))
+	}
+}
+
+// plantUMLActivity writes the body of an activity diagram using the legacy
+// syntax, which supports arbitrary arrows between activities
+func (d *Definition) plantUMLActivity(diagram *strings.Builder, opts PlantUMLOptions, initial []Place, highlighted map[Place]bool) {
+	diagram.WriteString("skinparam activity {\n    BackgroundColor<<current>> LightYellow\n}\n\n")
+	ids := d.plantUMLIDs()
+
+	// Activities are declared with their label on first use
+	declared := make(map[Place]bool)
+	activity := func(place Place) string {
+		if declared[place] {
+			return ids.places[place]
+		}
+		declared[place] = true
+		label := string(place)
+		if l, ok := metadataString(d.placeMetadata[place], MetadataLabel); ok {
+			label = l
+		}
+		label = plantUMLEscape(label)
+		if count, ok := opts.instanceCount(place); ok {
+			label += "\\n" + count
+		}
+		result := fmt.Sprintf("\"%s\" as %s", label, ids.places[place])
+		if highlighted[place] {
+			result += " <<current>>"
+		}
+		return result
+	}
+
+	for _, place := range initial {
+		diagram.WriteString(fmt.Sprintf("(*) --> %s\n", activity(place)))
+	}
+
+	for i, trans := range d.transitions {
+		label := plantUMLLabel(trans.Name(), trans.metadata)
+		if opts.Metadata {
+			for _, line := range metadataLines(trans.metadata) {
+				label += "\\n" + line
+			}
+		}
+
+		var source string
+		if len(trans.From()) > 1 {
+			source = fmt.Sprintf("===%s===", ids.joins[i])
+			for _, from := range trans.From() {
+				diagram.WriteString(fmt.Sprintf("%s --> %s\n", activity(from), source))
+			}
+		} else {
+			source = activity(trans.From()[0])
+		}
+		if len(trans.To()) > 1 {
+			fork := fmt.Sprintf("===%s===", ids.forks[i])
+			diagram.WriteString(fmt.Sprintf("%s -->[%s] %s\n", source, label, fork))
+			for _, to := range trans.To() {
+				diagram.WriteString(fmt.Sprintf("%s --> %s\n", fork, activity(to)))
+			}
+		} else {
+			diagram.WriteString(fmt.Sprintf("%s -->[%s] %s\n", source, label, activity(trans.To()[0])))
+		}
+	}
+
+	for _, place := range d.finalPlaces {
+		diagram.WriteString(fmt.Sprintf("%s --> (*)\n", activity(place)))
+	}
+}
+
+// plantUMLIDs are the identifiers of the places and bars of a diagram
+type plantUMLIDs struct {
+	places map[Place]string
+	// joins and forks hold the bars of each transition, or "" when it has none
+	joins, forks []string
+}
+
+// plantUMLIDs allocates unique identifiers to the places, then to the fork and
+// join bars of the transitions, named after the transition
+func (d *Definition) plantUMLIDs() *plantUMLIDs {
+	used := make(map[string]bool)
+	unique := func(s string) string {
+		id := plantUMLID(s)
+		candidate := id
+		for i := 2; used[candidate]; i++ {
+			candidate = fmt.Sprintf("%s_%d", id, i)
+		}
+		used[candidate] = true
+		return candidate
+	}
+
+	ids := &plantUMLIDs{
+		places: make(map[Place]string, len(d.places)),
+		joins:  make([]string, len(d.transitions)),
+		forks:  make([]string, len(d.transitions)),
+	}
+	for _, place := range d.places {
+		ids.places[place] = unique(string(place))
+	}
+	for i, trans := range d.transitions {
+		if len(trans.From()) > 1 {
+			ids.joins[i] = unique(trans.Name() + "_join")
+		}
+		if len(trans.To()) > 1 {
+			ids.forks[i] = unique(trans.Name() + "_fork")
+		}
+	}
+	return ids
+}
+
+// plantUMLPlace returns the declaration of a place, with its label when it
+// has one or when its name is not its identifier
+func plantUMLPlace(place Place, id string, metadata map[string]interface{}) string {
+	label, ok := metadataString(metadata, MetadataLabel)
+	if !ok {
+		if id == string(place) {
+			return id
+		}
+		label = string(place)
+	}
+	return fmt.Sprintf("\"%s\" as %s", plantUMLEscape(label), id)
+}
+
+// plantUMLLabel returns the label of a transition
+func plantUMLLabel(name string, metadata map[string]interface{}) string {
+	if label, ok := metadataString(metadata, MetadataLabel); ok {
+		return label
+	}
+	return name
+}
+
+// plantUMLID returns s with every character that is not valid in a PlantUML
+// identifier replaced by an underscore
+func plantUMLID(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// plantUMLEscape replaces double quotes, which PlantUML labels cannot contain
+func plantUMLEscape(label string) string {
+	return strings.ReplaceAll(label, "\"", "'")
+}
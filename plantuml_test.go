@@ -0,0 +1,97 @@
+package workflow_test
+
+import (
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+func TestDefinition_PlantUML(t *testing.T) {
+	tests := []struct {
+		name string
+		opts workflow.PlantUMLOptions
+		want string
+	}{
+		{
+			name: "state",
+			opts: workflow.PlantUMLOptions{RenderOptions: workflow.RenderOptions{Highlight: []workflow.Place{"paid"}, Metadata: true, Counts: map[workflow.Place]int{"paid": 12}}},
+			want: `@startuml
+hide empty description
+
+state new
+state paid #c8e6c9;line.bold
+paid : 12 instances
+state packed
+state shipped
+state pay_fork <<fork>>
+state ship_join <<join>>
+
+[*] --> new
+new --> pay_fork : pay
+note on link
+    cost: 2
+end note
+pay_fork --> paid
+pay_fork --> packed
+paid --> ship_join
+packed --> ship_join
+ship_join --> shipped : ship
+shipped --> [*]
+@enduml
+`,
+		},
+		{
+			name: "activity",
+			opts: workflow.PlantUMLOptions{Style: workflow.PlantUMLActivity, RenderOptions: workflow.RenderOptions{Highlight: []workflow.Place{"paid"}, Metadata: true, Counts: map[workflow.Place]int{"paid": 1}}},
+			want: `@startuml
+skinparam activity {
+    BackgroundColor<<current>> LightYellow
+}
+
+(*) --> "new" as new
+new -->[pay\ncost: 2] ===pay_fork===
+===pay_fork=== --> "paid\n1 instance" as paid <<current>>
+===pay_fork=== --> "packed" as packed
+paid --> ===ship_join===
+packed --> ===ship_join===
+===ship_join=== -->[ship] "shipped" as shipped
+shipped --> (*)
+@enduml
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderDiagramDefinition.PlantUML(tt.opts); got != tt.want {
+				t.Errorf("PlantUML() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefinition_PlantUMLUniqueIDs(t *testing.T) {
+	def := workflow.NewBuilder("ids").
+		Place("a-b", "a_b", "x_fork").
+		Transition("x").From("a-b").To("a_b", "x_fork").
+		MustBuild()
+
+	want := `@startuml
+hide empty description
+
+state "a-b" as a_b
+state "a_b" as a_b_2
+state x_fork
+state x_fork_2 <<fork>>
+
+[*] --> a_b
+a_b --> x_fork_2 : x
+x_fork_2 --> a_b_2
+x_fork_2 --> x_fork
+a_b_2 --> [*]
+x_fork --> [*]
+@enduml
+`
+	if got := def.PlantUML(workflow.PlantUMLOptions{}); got != want {
+		t.Errorf("PlantUML() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,373 @@
+package workflow
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Metadata keys used to keep the parts of a PNML net that the engine does not
+// use, so that they are exported back
+const (
+	// MetadataInitialTokens is the place metadata key holding the initial
+	// number of tokens of a place, when more than one
+	MetadataInitialTokens = "initial_tokens"
+	// MetadataArcWeights is the transition metadata key holding the arc weights
+	// other than one, as {"from": {place: weight}, "to": {place: weight}}
+	MetadataArcWeights = "arc_weights"
+)
+
+// PNML namespaces
+const (
+	pnmlNamespace = "http://www.pnml.org/version-2009/grammar/pnml"
+	pnmlPTNet     = "http://www.pnml.org/version-2009/grammar/ptnet"
+)
+
+// pnmlIgnored lists the PNML elements that have no effect on the definition
+var pnmlIgnored = map[string]bool{
+	"name":         true,
+	"graphics":     true,
+	"toolspecific": true,
+}
+
+// pnmlImporter converts a PNML net to a definition
+type pnmlImporter struct {
+	places      []*xmlNode
+	transitions []*xmlNode
+	arcs        []*xmlNode
+	byID        map[string]*xmlNode
+}
+
+// ImportPNML imports a place/transition net from a PNML document. Places and
+// transitions keep their IDs, and their names become labels and transition
+// names. Places with an initial marking become initial places. Arc weights and
+// initial markings other than one are kept in the MetadataArcWeights and
+// MetadataInitialTokens metadata, since the engine fires transitions with a
+// weight of one. Other net types and reference nodes are rejected with a
+// *LoadError wrapping ErrUnsupportedElement.
+func ImportPNML(data []byte) (*Definition, error) {
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	if root.name != "pnml" {
+		return nil, root.errorf("expected a pnml element, got %q", root.name)
+	}
+	var net *xmlNode
+	for _, child := range root.children {
+		if child.name != "net" {
+			continue
+		}
+		if net != nil {
+			return nil, child.errorf("only one net is supported")
+		}
+		net = child
+	}
+	if net == nil {
+		return nil, root.errorf("no net found")
+	}
+	if netType := net.attrs["type"]; netType != "" && !strings.HasSuffix(netType, "/ptnet") {
+		return nil, net.errorf("%w: net type %q", ErrUnsupportedElement, netType)
+	}
+
+	im := &pnmlImporter{byID: make(map[string]*xmlNode)}
+	if err := im.collect(net); err != nil {
+		return nil, err
+	}
+	return im.definition(pnmlName(net))
+}
+
+// collect indexes the nodes and arcs of the pages below node
+func (im *pnmlImporter) collect(node *xmlNode) error {
+	for _, child := range node.children {
+		switch child.name {
+		case "page":
+			if err := im.collect(child); err != nil {
+				return err
+			}
+			continue
+		case "place":
+			im.places = append(im.places, child)
+		case "transition":
+			im.transitions = append(im.transitions, child)
+		case "arc":
+			im.arcs = append(im.arcs, child)
+		default:
+			if pnmlIgnored[child.name] {
+				continue
+			}
+			return child.errorf("%w %q", ErrUnsupportedElement, child.name)
+		}
+		id := child.attrs["id"]
+		if id == "" {
+			return child.errorf("%s has no id", child.name)
+		}
+		if _, ok := im.byID[id]; ok {
+			return child.errorf("duplicate id '%s'", id)
+		}
+		im.byID[id] = child
+	}
+	return nil
+}
+
+// definition builds the definition from the collected net
+func (im *pnmlImporter) definition(name string) (*Definition, error) {
+	opts := []DefinitionOption{WithName(name)}
+	var places, initial []Place
+	for _, node := range im.places {
+		place := Place(node.attrs["id"])
+		places = append(places, place)
+		metadata := make(map[string]interface{})
+		if label := pnmlName(node); label != "" && label != string(place) {
+			metadata[MetadataLabel] = label
+		}
+		if marking := node.child("initialMarking"); marking != nil {
+			tokens, err := pnmlCount(marking)
+			if err != nil {
+				return nil, err
+			}
+			if tokens > 0 {
+				initial = append(initial, place)
+			}
+			if tokens > 1 {
+				metadata[MetadataInitialTokens] = tokens
+			}
+		}
+		if len(metadata) > 0 {
+			opts = append(opts, WithPlaceMetadata(place, metadata))
+		}
+	}
+	opts = append(opts, WithInitialPlaces(initial...))
+
+	type arcs struct {
+		from, to       []Place
+		fromW, toW     map[string]interface{}
+		fromSet, toSet map[Place]bool
+	}
+	byTransition := make(map[string]*arcs)
+	for _, node := range im.transitions {
+		byTransition[node.attrs["id"]] = &arcs{
+			fromW:   make(map[string]interface{}),
+			toW:     make(map[string]interface{}),
+			fromSet: make(map[Place]bool),
+			toSet:   make(map[Place]bool),
+		}
+	}
+	for _, node := range im.arcs {
+		source, ok := im.byID[node.attrs["source"]]
+		if !ok {
+			return nil, node.errorf("arc refers to unknown node '%s'", node.attrs["source"])
+		}
+		target, ok := im.byID[node.attrs["target"]]
+		if !ok {
+			return nil, node.errorf("arc refers to unknown node '%s'", node.attrs["target"])
+		}
+		weight := 1
+		if inscription := node.child("inscription"); inscription != nil {
+			var err error
+			if weight, err = pnmlCount(inscription); err != nil {
+				return nil, err
+			}
+			if weight < 1 {
+				return nil, inscription.errorf("arc weight must be positive, got %d", weight)
+			}
+		}
+		switch {
+		case source.name == "place" && target.name == "transition":
+			a, place := byTransition[target.attrs["id"]], Place(source.attrs["id"])
+			if a.fromSet[place] {
+				return nil, node.errorf("duplicate arc from '%s' to '%s'", place, target.attrs["id"])
+			}
+			a.fromSet[place] = true
+			a.from = append(a.from, place)
+			if weight > 1 {
+				a.fromW[string(place)] = weight
+			}
+		case source.name == "transition" && target.name == "place":
+			a, place := byTransition[source.attrs["id"]], Place(target.attrs["id"])
+			if a.toSet[place] {
+				return nil, node.errorf("duplicate arc from '%s' to '%s'", source.attrs["id"], place)
+			}
+			a.toSet[place] = true
+			a.to = append(a.to, place)
+			if weight > 1 {
+				a.toW[string(place)] = weight
+			}
+		default:
+			return nil, node.errorf("arc must connect a place and a transition")
+		}
+	}
+
+	var transitions []Transition
+	for _, node := range im.transitions {
+		a := byTransition[node.attrs["id"]]
+		name := pnmlName(node)
+		if name == "" {
+			name = node.attrs["id"]
+		}
+		if len(a.from) == 0 || len(a.to) == 0 {
+			return nil, node.errorf("transition '%s' needs input and output arcs", node.attrs["id"])
+		}
+		t, err := NewTransition(name, a.from, a.to)
+		if err != nil {
+			return nil, node.errorf("%v", err)
+		}
+		if len(a.fromW) > 0 || len(a.toW) > 0 {
+			weights := make(map[string]interface{})
+			if len(a.fromW) > 0 {
+				weights["from"] = a.fromW
+			}
+			if len(a.toW) > 0 {
+				weights["to"] = a.toW
+			}
+			t.SetMetadata(MetadataArcWeights, weights)
+		}
+		transitions = append(transitions, *t)
+	}
+
+	definition, err := NewDefinition(places, transitions, opts...)
+	if err != nil {
+		return nil, &LoadError{Err: err}
+	}
+	return definition, nil
+}
+
+// pnmlName returns the text of the name of a node
+func pnmlName(node *xmlNode) string {
+	if name := node.child("name"); name != nil {
+		if text := name.child("text"); text != nil {
+			return text.content()
+		}
+	}
+	return ""
+}
+
+// pnmlCount returns the number held by an initial marking or inscription
+func pnmlCount(node *xmlNode) (int, error) {
+	text := node.child("text")
+	if text == nil {
+		return 0, node.errorf("%s has no text", node.name)
+	}
+	count, err := strconv.Atoi(text.content())
+	if err != nil {
+		return 0, text.errorf("invalid %s %q", node.name, text.content())
+	}
+	return count, nil
+}
+
+// pnmlDocumentXML is the exported form of a PNML document
+type pnmlDocumentXML struct {
+	XMLName   xml.Name   `xml:"pnml"`
+	Namespace string     `xml:"xmlns,attr"`
+	Net       pnmlNetXML `xml:"net"`
+}
+
+// pnmlNetXML is the exported form of a net
+type pnmlNetXML struct {
+	ID   string       `xml:"id,attr"`
+	Type string       `xml:"type,attr"`
+	Name *pnmlTextXML `xml:"name"`
+	Page pnmlPageXML  `xml:"page"`
+}
+
+// pnmlPageXML holds the nodes and arcs of a net
+type pnmlPageXML struct {
+	ID          string        `xml:"id,attr"`
+	Places      []pnmlNodeXML `xml:"place"`
+	Transitions []pnmlNodeXML `xml:"transition"`
+	Arcs        []pnmlArcXML  `xml:"arc"`
+}
+
+// pnmlNodeXML is the exported form of a place or transition
+type pnmlNodeXML struct {
+	ID             string       `xml:"id,attr"`
+	Name           *pnmlTextXML `xml:"name"`
+	InitialMarking *pnmlTextXML `xml:"initialMarking"`
+}
+
+// pnmlArcXML is the exported form of an arc
+type pnmlArcXML struct {
+	ID          string       `xml:"id,attr"`
+	Source      string       `xml:"source,attr"`
+	Target      string       `xml:"target,attr"`
+	Inscription *pnmlTextXML `xml:"inscription"`
+}
+
+// pnmlTextXML is a PNML label
+type pnmlTextXML struct {
+	Text string `xml:"text"`
+}
+
+// ExportPNML exports a definition as a PNML place/transition net. Initial
+// places get an initial marking, and labels become names. Arc weights and
+// initial markings are read from the MetadataArcWeights and
+// MetadataInitialTokens metadata, and default to one. Guards and other
+// constraints are not part of a place/transition net and are not exported.
+func ExportPNML(definition *Definition) ([]byte, error) {
+	ids := make(xmlIDs)
+	placeIDs := make(map[Place]string)
+	for _, place := range definition.places {
+		placeIDs[place] = ids.unique(string(place))
+	}
+	netID := definition.name
+	if netID == "" {
+		netID = "net"
+	}
+	net := pnmlNetXML{ID: ids.unique(netID), Type: pnmlPTNet}
+	if definition.name != "" {
+		net.Name = &pnmlTextXML{Text: definition.name}
+	}
+	net.Page.ID = ids.unique("page")
+
+	initial := placeSet(definition.initialPlaces)
+	for _, place := range definition.places {
+		node := pnmlNodeXML{ID: placeIDs[place], Name: &pnmlTextXML{Text: string(place)}}
+		if label, ok := metadataString(definition.placeMetadata[place], MetadataLabel); ok {
+			node.Name.Text = label
+		}
+		if initial[place] {
+			tokens := 1
+			if n, ok := definition.placeMetadata[place][MetadataInitialTokens].(int); ok {
+				tokens = n
+			}
+			node.InitialMarking = &pnmlTextXML{Text: strconv.Itoa(tokens)}
+		}
+		net.Page.Places = append(net.Page.Places, node)
+	}
+
+	arc := func(source, target string, weight int) {
+		a := pnmlArcXML{ID: ids.unique(fmt.Sprintf("arc%d", len(net.Page.Arcs)+1)), Source: source, Target: target}
+		if weight > 1 {
+			a.Inscription = &pnmlTextXML{Text: strconv.Itoa(weight)}
+		}
+		net.Page.Arcs = append(net.Page.Arcs, a)
+	}
+	for _, t := range definition.transitions {
+		id := ids.unique(t.Name())
+		net.Page.Transitions = append(net.Page.Transitions, pnmlNodeXML{ID: id, Name: &pnmlTextXML{Text: t.Name()}})
+		weights, _ := t.metadata[MetadataArcWeights].(map[string]interface{})
+		for _, place := range t.From() {
+			arc(placeIDs[place], id, arcWeight(weights, "from", place))
+		}
+		for _, place := range t.To() {
+			arc(id, placeIDs[place], arcWeight(weights, "to", place))
+		}
+	}
+
+	doc := pnmlDocumentXML{Namespace: pnmlNamespace, Net: net}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(xml.Header), data...), '\n'), nil
+}
+
+// arcWeight returns the weight of an arc from the arc weights metadata
+func arcWeight(weights map[string]interface{}, side string, place Place) int {
+	byPlace, _ := weights[side].(map[string]interface{})
+	if weight, ok := byPlace[string(place)].(int); ok {
+		return weight
+	}
+	return 1
+}
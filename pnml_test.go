@@ -0,0 +1,151 @@
+package workflow_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+const producerPNML = `<?xml version="1.0" encoding="UTF-8"?>
+<pnml xmlns="http://www.pnml.org/version-2009/grammar/pnml">
+  <net id="net1" type="http://www.pnml.org/version-2009/grammar/ptnet">
+    <name><text>producer</text></name>
+    <page id="top">
+      <place id="idle">
+        <name><text>Idle</text></name>
+        <initialMarking><text>1</text></initialMarking>
+        <graphics><position x="10" y="10"/></graphics>
+      </place>
+      <place id="buffer">
+        <initialMarking><text>3</text></initialMarking>
+      </place>
+      <transition id="t1"><name><text>produce</text></name></transition>
+      <page id="nested">
+        <place id="done" />
+        <transition id="consume" />
+      </page>
+      <arc id="a1" source="idle" target="t1" />
+      <arc id="a2" source="t1" target="buffer">
+        <inscription><text>2</text></inscription>
+      </arc>
+      <arc id="a3" source="buffer" target="consume" />
+      <arc id="a4" source="consume" target="done" />
+    </page>
+  </net>
+</pnml>
+`
+
+func TestImportPNML(t *testing.T) {
+	def, err := workflow.ImportPNML([]byte(producerPNML))
+	if err != nil {
+		t.Fatalf("ImportPNML() error = %v", err)
+	}
+	if def.Name() != "producer" {
+		t.Errorf("name = %q", def.Name())
+	}
+	if got := def.AllPlaces(); len(got) != 3 || got[2] != "done" {
+		t.Errorf("places = %v", got)
+	}
+	if got := def.InitialPlaces(); len(got) != 2 {
+		t.Errorf("initial places = %v", got)
+	}
+	if label, _ := def.PlaceMetadata("idle", workflow.MetadataLabel); label != "Idle" {
+		t.Errorf("idle label = %v", label)
+	}
+	if tokens, _ := def.PlaceMetadata("buffer", workflow.MetadataInitialTokens); tokens != 3 {
+		t.Errorf("buffer tokens = %v", tokens)
+	}
+	produce := def.Transition("produce")
+	if produce == nil {
+		t.Fatal("transition produce not imported")
+	}
+	weights, _ := produce.Metadata(workflow.MetadataArcWeights)
+	if to, _ := weights.(map[string]interface{})["to"].(map[string]interface{}); to["buffer"] != 2 {
+		t.Errorf("arc weights = %v", weights)
+	}
+	if def.Transition("consume") == nil {
+		t.Error("transition of nested page not imported")
+	}
+}
+
+func TestPNML_RoundTrip(t *testing.T) {
+	def, err := workflow.ImportPNML([]byte(producerPNML))
+	if err != nil {
+		t.Fatalf("ImportPNML() error = %v", err)
+	}
+	data, err := workflow.ExportPNML(def)
+	if err != nil {
+		t.Fatalf("ExportPNML() error = %v", err)
+	}
+	for _, want := range []string{
+		`<net id="producer" type="http://www.pnml.org/version-2009/grammar/ptnet">`,
+		`<initialMarking>` + "\n" + `          <text>3</text>`,
+		`<arc id="arc2" source="produce" target="buffer">`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("export does not contain %s:\n%s", want, data)
+		}
+	}
+
+	imported, err := workflow.ImportPNML(data)
+	if err != nil {
+		t.Fatalf("ImportPNML() of exported data error = %v\n%s", err, data)
+	}
+	if imported.Fingerprint() != def.Fingerprint() {
+		t.Errorf("definition changed after round trip:\n%s", data)
+	}
+}
+
+func TestImportPNML_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		line    int
+		message string
+	}{
+		{
+			name:    "reference place",
+			page:    `<referencePlace id="r" ref="p" />`,
+			line:    3,
+			message: `unsupported element "referencePlace"`,
+		},
+		{
+			name:    "arc between places",
+			page:    "<place id=\"a\" />\n<place id=\"b\" />\n<arc id=\"x\" source=\"a\" target=\"b\" />",
+			line:    5,
+			message: "arc must connect a place and a transition",
+		},
+		{
+			name:    "invalid inscription",
+			page:    "<place id=\"a\" />\n<transition id=\"t\" />\n<arc id=\"x\" source=\"a\" target=\"t\">\n<inscription><text>two</text></inscription>\n</arc>",
+			line:    6,
+			message: `invalid inscription "two"`,
+		},
+		{
+			name:    "source transition",
+			page:    "<place id=\"a\" />\n<transition id=\"t\" />\n<arc id=\"x\" source=\"t\" target=\"a\" />",
+			line:    4,
+			message: "transition 't' needs input and output arcs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "<?xml version=\"1.0\"?>\n<pnml><net id=\"n\">\n<page id=\"p\">" + tt.page + "</page></net></pnml>\n"
+			_, err := workflow.ImportPNML([]byte(data))
+			var loadErr *workflow.LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("expected a *LoadError, got %v", err)
+			}
+			if loadErr.Line != tt.line || !strings.Contains(loadErr.Error(), tt.message) {
+				t.Errorf("error = %v, want line %d containing %q", loadErr, tt.line, tt.message)
+			}
+		})
+	}
+
+	colored := `<pnml><net id="n" type="http://www.pnml.org/version-2009/grammar/symmetricnet"></net></pnml>`
+	if _, err := workflow.ImportPNML([]byte(colored)); !errors.Is(err, workflow.ErrUnsupportedElement) {
+		t.Errorf("expected ErrUnsupportedElement for a colored net, got %v", err)
+	}
+}
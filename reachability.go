@@ -0,0 +1,378 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Omega represents an unbounded number of tokens in a coverability graph
+const Omega = -1
+
+// DefaultMaxStates is the default bound on the number of explored markings
+const DefaultMaxStates = 10000
+
+// TokenMarking maps places to their number of tokens. A count of Omega means
+// the place can hold an unbounded number of tokens.
+type TokenMarking map[Place]int
+
+// NewTokenMarking creates a token marking with one token in each given place
+func NewTokenMarking(places []Place) TokenMarking {
+	m := make(TokenMarking, len(places))
+	for _, place := range places {
+		m.add(place, 1)
+	}
+	return m
+}
+
+// Places returns the marked places in lexical order
+func (m TokenMarking) Places() []Place {
+	places := make([]Place, 0, len(m))
+	for place, count := range m {
+		if count != 0 {
+			places = append(places, place)
+		}
+	}
+	sort.Slice(places, func(i, j int) bool { return places[i] < places[j] })
+	return places
+}
+
+// String returns a canonical representation of the marking, e.g. "[a, b:2, c:ω]"
+func (m TokenMarking) String() string {
+	parts := make([]string, 0, len(m))
+	for _, place := range m.Places() {
+		switch count := m[place]; count {
+		case 1:
+			parts = append(parts, string(place))
+		case Omega:
+			parts = append(parts, fmt.Sprintf("%s:ω", place))
+		default:
+			parts = append(parts, fmt.Sprintf("%s:%d", place, count))
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// add adds tokens to a place, keeping Omega absorbing
+func (m TokenMarking) add(place Place, delta int) {
+	if m[place] == Omega {
+		return
+	}
+	m[place] += delta
+	if m[place] == 0 {
+		delete(m, place)
+	}
+}
+
+// enables reports whether the transition can fire in the marking
+func (m TokenMarking) enables(t *Transition) bool {
+	for _, place := range t.from {
+		if c := m[place]; c != Omega && c < 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// fire returns the marking obtained by firing the transition
+func (m TokenMarking) fire(t *Transition) TokenMarking {
+	next := make(TokenMarking, len(m))
+	for place, count := range m {
+		next[place] = count
+	}
+	for _, place := range t.from {
+		next.add(place, -1)
+	}
+	for _, place := range t.to {
+		next.add(place, 1)
+	}
+	return next
+}
+
+// covers reports whether m has at least as many tokens as other in every place
+func (m TokenMarking) covers(other TokenMarking) bool {
+	for place, count := range other {
+		c := m[place]
+		if c == Omega {
+			continue
+		}
+		if count == Omega || c < count {
+			return false
+		}
+	}
+	return true
+}
+
+// ReachabilityEdge is a transition firing between two markings of the graph
+type ReachabilityEdge struct {
+	// Transition is the name of the fired transition
+	Transition string
+	// TransitionIndex is the index of the transition in the definition
+	TransitionIndex int
+	// To is the index of the resulting node
+	To int
+}
+
+// ReachabilityNode is a marking of the reachability graph
+type ReachabilityNode struct {
+	ID      int
+	Marking TokenMarking
+	Edges   []ReachabilityEdge
+
+	parent     int
+	parentEdge string
+}
+
+// ReachabilityGraph is the reachability graph of a definition from an initial
+// marking. When the net is unbounded it is a Karp-Miller coverability graph in
+// which unbounded places hold Omega tokens.
+type ReachabilityGraph struct {
+	Nodes []*ReachabilityNode
+	// Bounded is false when some place can hold an unbounded number of tokens
+	Bounded bool
+	// Complete is false when exploration stopped at the state bound
+	Complete bool
+}
+
+// ReachabilityOptions configures the reachability analysis
+type ReachabilityOptions struct {
+	// InitialPlaces is the initial marking. Defaults to the definition's initial places.
+	InitialPlaces []Place
+	// MaxStates bounds the number of explored markings. Defaults to DefaultMaxStates.
+	MaxStates int
+}
+
+// Path returns the shortest firing sequence from the initial marking to the node
+func (g *ReachabilityGraph) Path(node int) []string {
+	var path []string
+	for n := g.Nodes[node]; n.parent >= 0; n = g.Nodes[n.parent] {
+		path = append(path, n.parentEdge)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// BuildReachabilityGraph explores the markings reachable from the initial marking.
+// Transitions consume one token from each source place and produce one token in
+// each target place; guards and constraints are not evaluated. When the state
+// bound is reached, the partial graph is returned together with ErrStateLimitExceeded.
+func BuildReachabilityGraph(definition *Definition, opts ReachabilityOptions) (*ReachabilityGraph, error) {
+	initialPlaces := opts.InitialPlaces
+	if len(initialPlaces) == 0 {
+		initialPlaces = definition.InitialPlaces()
+	}
+	if len(initialPlaces) == 0 {
+		return nil, fmt.Errorf("no initial places declared")
+	}
+	for _, place := range initialPlaces {
+		if !definition.Place(place) {
+			return nil, fmt.Errorf("initial place '%s' is not defined in workflow places", place)
+		}
+	}
+	maxStates := opts.MaxStates
+	if maxStates <= 0 {
+		maxStates = DefaultMaxStates
+	}
+
+	g := &ReachabilityGraph{Bounded: true, Complete: true}
+	index := make(map[string]int)
+
+	root := &ReachabilityNode{ID: 0, Marking: NewTokenMarking(initialPlaces), parent: -1}
+	g.Nodes = append(g.Nodes, root)
+	index[root.Marking.String()] = 0
+
+	transitions := definition.transitions
+	for queue := []int{0}; len(queue) > 0; queue = queue[1:] {
+		node := g.Nodes[queue[0]]
+		for i := range transitions {
+			t := &transitions[i]
+			if !node.Marking.enables(t) {
+				continue
+			}
+			next := node.Marking.fire(t)
+
+			// Accelerate to Omega when the new marking strictly covers an ancestor
+			for a := node; ; a = g.Nodes[a.parent] {
+				if next.covers(a.Marking) && next.String() != a.Marking.String() {
+					for place, count := range next {
+						if count != Omega && count > a.Marking[place] {
+							next[place] = Omega
+							g.Bounded = false
+						}
+					}
+				}
+				if a.parent < 0 {
+					break
+				}
+			}
+
+			key := next.String()
+			id, seen := index[key]
+			if !seen {
+				if len(g.Nodes) >= maxStates {
+					g.Complete = false
+					return g, fmt.Errorf("%w: explored %d markings", ErrStateLimitExceeded, len(g.Nodes))
+				}
+				id = len(g.Nodes)
+				g.Nodes = append(g.Nodes, &ReachabilityNode{ID: id, Marking: next, parent: node.ID, parentEdge: t.Name()})
+				index[key] = id
+				queue = append(queue, id)
+			}
+			node.Edges = append(node.Edges, ReachabilityEdge{Transition: t.Name(), TransitionIndex: i, To: id})
+		}
+	}
+	return g, nil
+}
+
+// MarkingWitness is a reachable marking together with a firing sequence leading to it
+type MarkingWitness struct {
+	Marking TokenMarking
+	Path    []string
+}
+
+// SoundnessReport describes the soundness properties of a workflow net
+type SoundnessReport struct {
+	Graph *ReachabilityGraph
+
+	// OptionToComplete is true when a final marking can be reached from every reachable marking
+	OptionToComplete bool
+	// ProperCompletion is true when no reachable marking marks a final place alongside other places
+	ProperCompletion bool
+	// NoDeadTransitions is true when every transition can fire in some reachable marking
+	NoDeadTransitions bool
+	// Bounded is true when every place holds a bounded number of tokens
+	Bounded bool
+
+	// Deadlocks lists non-final markings in which no transition is enabled
+	Deadlocks []MarkingWitness
+	// Incomplete lists markings from which no final marking can be reached
+	Incomplete []MarkingWitness
+	// ImproperCompletions lists markings marking a final place alongside non-final places
+	ImproperCompletions []MarkingWitness
+	// DeadTransitions lists the names of transitions that can never fire
+	DeadTransitions []string
+	// UnboundedPlaces lists places that can hold an unbounded number of tokens
+	UnboundedPlaces []Place
+}
+
+// Sound reports whether the net satisfies all soundness properties
+func (r *SoundnessReport) Sound() bool {
+	return r.OptionToComplete && r.ProperCompletion && r.NoDeadTransitions && r.Bounded
+}
+
+// CheckSoundness checks the soundness of a workflow net. A marking is final when
+// all of its tokens are in final places; the final places default to the
+// places without outgoing transitions when the definition declares none.
+func CheckSoundness(definition *Definition, opts ReachabilityOptions) (*SoundnessReport, error) {
+	g, err := BuildReachabilityGraph(definition, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	final := placeSet(definition.FinalPlaces())
+	if len(final) == 0 {
+		final = placeSet(sinkPlaces(definition))
+	}
+
+	report := &SoundnessReport{
+		Graph:             g,
+		OptionToComplete:  true,
+		ProperCompletion:  true,
+		NoDeadTransitions: true,
+		Bounded:           g.Bounded,
+	}
+
+	isFinal := make([]bool, len(g.Nodes))
+	reverse := make([][]int, len(g.Nodes))
+	fired := make([]bool, len(definition.transitions))
+	unbounded := make(map[Place]bool)
+	for _, node := range g.Nodes {
+		finalTokens, otherTokens := 0, 0
+		for place, count := range node.Marking {
+			if count == Omega {
+				unbounded[place] = true
+			}
+			if final[place] {
+				finalTokens++
+			} else {
+				otherTokens++
+			}
+		}
+		isFinal[node.ID] = finalTokens > 0 && otherTokens == 0
+		if finalTokens > 0 && otherTokens > 0 {
+			report.ProperCompletion = false
+			report.ImproperCompletions = append(report.ImproperCompletions, g.witness(node.ID))
+		}
+		if len(node.Edges) == 0 && !isFinal[node.ID] {
+			report.Deadlocks = append(report.Deadlocks, g.witness(node.ID))
+		}
+		for _, edge := range node.Edges {
+			fired[edge.TransitionIndex] = true
+			reverse[edge.To] = append(reverse[edge.To], node.ID)
+		}
+	}
+
+	// Walk backwards from final markings to find markings that can complete
+	canComplete := make([]bool, len(g.Nodes))
+	var stack []int
+	for id, ok := range isFinal {
+		if ok {
+			canComplete[id] = true
+			stack = append(stack, id)
+		}
+	}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, prev := range reverse[id] {
+			if !canComplete[prev] {
+				canComplete[prev] = true
+				stack = append(stack, prev)
+			}
+		}
+	}
+	for id, ok := range canComplete {
+		if !ok {
+			report.OptionToComplete = false
+			report.Incomplete = append(report.Incomplete, g.witness(id))
+		}
+	}
+
+	for i, t := range definition.transitions {
+		if !fired[i] {
+			report.NoDeadTransitions = false
+			report.DeadTransitions = append(report.DeadTransitions, t.Name())
+		}
+	}
+	for _, place := range definition.places {
+		if unbounded[place] {
+			report.UnboundedPlaces = append(report.UnboundedPlaces, place)
+		}
+	}
+
+	return report, nil
+}
+
+// witness returns the marking of a node with its firing sequence
+func (g *ReachabilityGraph) witness(node int) MarkingWitness {
+	return MarkingWitness{Marking: g.Nodes[node].Marking, Path: g.Path(node)}
+}
+
+// sinkPlaces returns the places without outgoing transitions
+func sinkPlaces(definition *Definition) []Place {
+	outgoing := make(map[Place]bool)
+	for _, t := range definition.transitions {
+		for _, place := range t.From() {
+			outgoing[place] = true
+		}
+	}
+	var sinks []Place
+	for _, place := range definition.places {
+		if !outgoing[place] {
+			sinks = append(sinks, place)
+		}
+	}
+	return sinks
+}
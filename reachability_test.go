@@ -0,0 +1,125 @@
+package workflow_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+func TestCheckSoundness_SoundNet(t *testing.T) {
+	def := workflow.NewBuilder("review").
+		Place("start", "legal", "finance", "legal_ok", "finance_ok", "done").
+		Transition("split").From("start").To("legal", "finance").
+		Transition("legal_review").From("legal").To("legal_ok").
+		Transition("finance_review").From("finance").To("finance_ok").
+		Transition("join").From("legal_ok", "finance_ok").To("done").
+		MustBuild()
+
+	report, err := workflow.CheckSoundness(def, workflow.ReachabilityOptions{})
+	if err != nil {
+		t.Fatalf("CheckSoundness() error = %v", err)
+	}
+	if !report.Sound() {
+		t.Errorf("Sound() = false, report = %+v", report)
+	}
+	if got := len(report.Graph.Nodes); got != 6 {
+		t.Errorf("graph has %d markings, want 6", got)
+	}
+}
+
+func TestCheckSoundness_Deadlock(t *testing.T) {
+	def := workflow.NewBuilder("order").
+		Place("new", "a", "b", "c", "done").
+		FinalPlace("done").
+		Transition("split").From("new").To("a", "b").
+		Transition("left").From("a").To("c").
+		Transition("finish").From("b", "c").To("done").
+		Transition("skip").From("b").To("c").
+		MustBuild()
+
+	report, err := workflow.CheckSoundness(def, workflow.ReachabilityOptions{})
+	if err != nil {
+		t.Fatalf("CheckSoundness() error = %v", err)
+	}
+	if report.Sound() {
+		t.Fatal("Sound() = true, want false")
+	}
+	if report.OptionToComplete {
+		t.Error("OptionToComplete = true, want false")
+	}
+	if !report.ProperCompletion {
+		t.Errorf("ProperCompletion = false, improper completions = %+v", report.ImproperCompletions)
+	}
+	if len(report.Deadlocks) == 0 {
+		t.Fatal("no deadlock reported")
+	}
+	// Skipping b leaves two tokens in c and nothing can join them
+	deadlock := report.Deadlocks[0]
+	if deadlock.Marking.String() != "[c:2]" {
+		t.Errorf("deadlock marking = %s, want [c:2]", deadlock.Marking)
+	}
+	if got := strings.Join(deadlock.Path, ","); got != "split,left,skip" {
+		t.Errorf("deadlock path = %s, want split,left,skip", got)
+	}
+}
+
+func TestCheckSoundness_ImproperCompletionAndDeadTransition(t *testing.T) {
+	def := workflow.NewBuilder("order").
+		Place("new", "a", "b", "done", "orphan").
+		FinalPlace("done").
+		Transition("split").From("new").To("a", "b").
+		Transition("finish").From("a").To("done").
+		Transition("cleanup").From("b").To("done").
+		Transition("never").From("orphan").To("done").
+		MustBuild()
+
+	report, err := workflow.CheckSoundness(def, workflow.ReachabilityOptions{})
+	if err != nil {
+		t.Fatalf("CheckSoundness() error = %v", err)
+	}
+	if report.ProperCompletion {
+		t.Error("ProperCompletion = true, want false")
+	}
+	if len(report.DeadTransitions) != 1 || report.DeadTransitions[0] != "never" {
+		t.Errorf("DeadTransitions = %v, want [never]", report.DeadTransitions)
+	}
+}
+
+func TestCheckSoundness_Unbounded(t *testing.T) {
+	def := workflow.NewBuilder("generator").
+		Place("start", "tokens", "done").
+		FinalPlace("done").
+		Transition("produce").From("start").To("start", "tokens").
+		Transition("stop").From("start").To("done").
+		MustBuild()
+
+	report, err := workflow.CheckSoundness(def, workflow.ReachabilityOptions{})
+	if err != nil {
+		t.Fatalf("CheckSoundness() error = %v", err)
+	}
+	if report.Bounded {
+		t.Error("Bounded = true, want false")
+	}
+	if len(report.UnboundedPlaces) != 1 || report.UnboundedPlaces[0] != "tokens" {
+		t.Errorf("UnboundedPlaces = %v, want [tokens]", report.UnboundedPlaces)
+	}
+}
+
+func TestBuildReachabilityGraph_StateLimit(t *testing.T) {
+	def := workflow.NewBuilder("chain").
+		Place("a", "b", "c", "d").
+		Transition("ab").From("a").To("b").
+		Transition("bc").From("b").To("c").
+		Transition("cd").From("c").To("d").
+		MustBuild()
+
+	g, err := workflow.BuildReachabilityGraph(def, workflow.ReachabilityOptions{MaxStates: 2})
+	if !errors.Is(err, workflow.ErrStateLimitExceeded) {
+		t.Fatalf("BuildReachabilityGraph() error = %v, want ErrStateLimitExceeded", err)
+	}
+	if g == nil || g.Complete {
+		t.Errorf("graph = %+v, want a partial graph", g)
+	}
+}
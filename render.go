@@ -0,0 +1,32 @@
+package workflow
+
+import "fmt"
+
+// RenderOptions holds the options shared by the diagram renderers
+type RenderOptions struct {
+	// Highlight lists additional places to draw as current places
+	Highlight []Place
+	// Metadata adds place and transition metadata to the rendering
+	Metadata bool
+	// Counts shows the number of instances in each place, such as the
+	// counts of a Graph
+	Counts map[Place]int
+}
+
+// highlighted returns the current places and the places to highlight
+func (o RenderOptions) highlighted(current []Place) map[Place]bool {
+	return placeSet(append(append([]Place{}, current...), o.Highlight...))
+}
+
+// instanceCount returns the number of instances in a place as text, and
+// whether the place has a count
+func (o RenderOptions) instanceCount(place Place) (string, bool) {
+	count, ok := o.Counts[place]
+	if !ok {
+		return "", false
+	}
+	if count == 1 {
+		return "1 instance", true
+	}
+	return fmt.Sprintf("%d instances", count), true
+}
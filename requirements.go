@@ -0,0 +1,119 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContextValidator validates a workflow context value required by a place
+type ContextValidator func(value interface{}) error
+
+// PlaceRequirement declares a context key that must be set before entering a place
+type PlaceRequirement struct {
+	Key string
+	// Validator optionally validates the value once it is present
+	Validator ContextValidator
+	// Type is the expected value type when the requirement was declared with WithContextType
+	Type FieldType
+}
+
+// RequirementViolation describes an unmet place requirement
+type RequirementViolation struct {
+	Place  Place
+	Key    string
+	Reason string
+}
+
+// RequirementsError is returned when the workflow context does not satisfy
+// the requirements of the places a transition leads to
+type RequirementsError struct {
+	Transition string
+	Violations []RequirementViolation
+}
+
+// Error implements the error interface
+func (e *RequirementsError) Error() string {
+	reasons := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		reasons[i] = fmt.Sprintf("%s.%s: %s", v.Place, v.Key, v.Reason)
+	}
+	return fmt.Sprintf("requirements not met for transition '%s': %s", e.Transition, strings.Join(reasons, "; "))
+}
+
+// Unwrap returns ErrRequirementsNotMet so callers can use errors.Is
+func (e *RequirementsError) Unwrap() error {
+	return ErrRequirementsNotMet
+}
+
+// ContextOfType returns a validator that checks the value has the given type
+func ContextOfType(fieldType FieldType) ContextValidator {
+	return func(value interface{}) error {
+		if !fieldType.accepts(value) {
+			return fmt.Errorf("must be of type %s, got %T", fieldType, value)
+		}
+		return nil
+	}
+}
+
+// WithRequiredContext declares context keys that must be set to enter the place
+func WithRequiredContext(place Place, keys ...string) DefinitionOption {
+	return func(d *Definition) {
+		for _, key := range keys {
+			d.addRequirement(place, PlaceRequirement{Key: key})
+		}
+	}
+}
+
+// WithContextValidator declares a context key that must be set to enter the place
+// and whose value must pass the validator
+func WithContextValidator(place Place, key string, validator ContextValidator) DefinitionOption {
+	return func(d *Definition) {
+		d.addRequirement(place, PlaceRequirement{Key: key, Validator: validator})
+	}
+}
+
+// WithContextType declares a context key that must be set to enter the place
+// and whose value must have the given type
+func WithContextType(place Place, key string, fieldType FieldType) DefinitionOption {
+	return func(d *Definition) {
+		d.addRequirement(place, PlaceRequirement{Key: key, Validator: ContextOfType(fieldType), Type: fieldType})
+	}
+}
+
+// addRequirement registers a place requirement
+func (d *Definition) addRequirement(place Place, requirement PlaceRequirement) {
+	if d.requirements == nil {
+		d.requirements = make(map[Place][]PlaceRequirement)
+	}
+	d.requirements[place] = append(d.requirements[place], requirement)
+}
+
+// Requirements returns the context requirements to enter the given place
+func (d *Definition) Requirements(place Place) []PlaceRequirement {
+	requirements := make([]PlaceRequirement, len(d.requirements[place]))
+	copy(requirements, d.requirements[place])
+	return requirements
+}
+
+// checkRequirements validates the context against the requirements of the target places
+func (d *Definition) checkRequirements(transition *Transition, context map[string]interface{}) error {
+	var violations []RequirementViolation
+	for _, place := range transition.To() {
+		for _, req := range d.requirements[place] {
+			value, ok := context[req.Key]
+			if !ok || value == nil {
+				violations = append(violations, RequirementViolation{Place: place, Key: req.Key, Reason: "is required"})
+				continue
+			}
+			if req.Validator != nil {
+				if err := req.Validator(value); err != nil {
+					violations = append(violations, RequirementViolation{Place: place, Key: req.Key, Reason: err.Error()})
+				}
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return &RequirementsError{Transition: transition.Name(), Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package workflow_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+func TestWorkflow_PlaceRequirements(t *testing.T) {
+	def, err := workflow.NewDefinition(
+		[]workflow.Place{"draft", "published"},
+		[]workflow.Transition{*workflow.MustNewTransition("publish", []workflow.Place{"draft"}, []workflow.Place{"published"})},
+		workflow.WithRequiredContext("published", "title", "content"),
+		workflow.WithContextValidator("published", "word_count", workflow.ContextOfType(workflow.FieldInteger)),
+	)
+	if err != nil {
+		t.Fatalf("failed to create definition: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		context        map[string]interface{}
+		wantViolations []string
+	}{
+		{
+			name:           "missing and invalid values",
+			context:        map[string]interface{}{"title": "Hello", "word_count": "many"},
+			wantViolations: []string{"content", "word_count"},
+		},
+		{
+			name:    "requirements met",
+			context: map[string]interface{}{"title": "Hello", "content": "World", "word_count": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf, err := workflow.NewWorkflow("article", def, "draft")
+			if err != nil {
+				t.Fatalf("failed to create workflow: %v", err)
+			}
+			for key, value := range tt.context {
+				wf.SetContext(key, value)
+			}
+
+			err = wf.Apply([]workflow.Place{"published"})
+			if tt.wantViolations == nil {
+				if err != nil {
+					t.Errorf("Apply() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, workflow.ErrRequirementsNotMet) {
+				t.Fatalf("Apply() error = %v, want ErrRequirementsNotMet", err)
+			}
+			var reqErr *workflow.RequirementsError
+			if !errors.As(err, &reqErr) {
+				t.Fatalf("Apply() error = %T, want *RequirementsError", err)
+			}
+			if len(reqErr.Violations) != len(tt.wantViolations) {
+				t.Fatalf("violations = %+v, want keys %v", reqErr.Violations, tt.wantViolations)
+			}
+			for i, key := range tt.wantViolations {
+				if reqErr.Violations[i].Key != key {
+					t.Errorf("violation %d key = %s, want %s", i, reqErr.Violations[i].Key, key)
+				}
+			}
+		})
+	}
+}
+
+func TestNewDefinition_RequirementsUnknownPlace(t *testing.T) {
+	_, err := workflow.NewDefinition(
+		[]workflow.Place{"draft"},
+		[]workflow.Transition{},
+		workflow.WithRequiredContext("published", "title"),
+	)
+	if err == nil {
+		t.Fatal("NewDefinition() error = nil, want error")
+	}
+	if want := "place 'published' in context requirements is not defined in workflow places"; err.Error() != want {
+		t.Errorf("NewDefinition() error = %v, want %v", err, want)
+	}
+}
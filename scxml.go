@@ -0,0 +1,477 @@
+package workflow
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// scxmlNamespace is the namespace of SCXML documents
+const scxmlNamespace = "http://www.w3.org/2005/07/scxml"
+
+// scxmlIgnored lists the SCXML elements that have no effect on the definition
+var scxmlIgnored = map[string]bool{
+	"datamodel": true,
+	"onentry":   true,
+	"onexit":    true,
+	"script":    true,
+	"donedata":  true,
+}
+
+// scxmlImporter converts an SCXML document to a definition
+type scxmlImporter struct {
+	root        *xmlNode
+	byID        map[string]*xmlNode
+	states      []*xmlNode
+	transitions []*xmlNode
+	parents     map[*xmlNode]*xmlNode
+	initials    map[*xmlNode]string
+	unsupported []UnsupportedFeature
+}
+
+// ImportSCXML imports a W3C SCXML state chart. Atomic and final states become
+// places, and compound states are flattened: entering one enters its initial
+// state, and its transitions apply to each of its states. The states of a
+// parallel region are marked together, and transitions of the parallel
+// element join them. A transition with several events becomes one transition
+// per event, and cond attributes are parsed as guard expressions, where In()
+// tests whether a state is marked. Conditions the expression engine cannot
+// evaluate, such as ECMAScript, block their transition and are reported as
+// unsupported. Top-level final states become final places. History states and
+// invocations are rejected with a *LoadError wrapping ErrUnsupportedElement.
+func ImportSCXML(data []byte) (*Definition, []UnsupportedFeature, error) {
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if root.name != "scxml" {
+		return nil, nil, root.errorf("expected an scxml element, got %q", root.name)
+	}
+	im := &scxmlImporter{
+		root:     root,
+		byID:     make(map[string]*xmlNode),
+		parents:  make(map[*xmlNode]*xmlNode),
+		initials: make(map[*xmlNode]string),
+	}
+	if err := im.collect(root); err != nil {
+		return nil, nil, err
+	}
+	definition, err := im.definition()
+	if err != nil {
+		return nil, nil, err
+	}
+	return definition, im.unsupported, nil
+}
+
+// collect indexes the states and transitions below node
+func (im *scxmlImporter) collect(node *xmlNode) error {
+	for _, child := range node.children {
+		switch {
+		case isSCXMLState(child):
+			id := child.attrs["id"]
+			if id == "" {
+				return child.errorf("%s has no id", child.name)
+			}
+			if _, ok := im.byID[id]; ok {
+				return child.errorf("duplicate state '%s'", id)
+			}
+			if node.name == "parallel" && !isSCXMLAtomic(child) {
+				return child.errorf("nested states in parallel regions are not supported")
+			}
+			im.byID[id] = child
+			im.parents[child] = node
+			im.states = append(im.states, child)
+			if err := im.collect(child); err != nil {
+				return err
+			}
+		case child.name == "transition":
+			if node == im.root {
+				return child.errorf("transition must be inside a state")
+			}
+			im.parents[child] = node
+			im.transitions = append(im.transitions, child)
+		case child.name == "initial":
+			if node.name != "state" {
+				return child.errorf("initial must be inside a compound state")
+			}
+			if t := child.child("transition"); t != nil {
+				im.initials[node] = t.attrs["target"]
+			}
+		case scxmlIgnored[child.name]:
+		default:
+			return child.errorf("%w %q", ErrUnsupportedElement, child.name)
+		}
+	}
+	return nil
+}
+
+// definition builds the definition from the collected states and transitions
+func (im *scxmlImporter) definition() (*Definition, error) {
+	var places, final []Place
+	for _, state := range im.states {
+		if !isSCXMLAtomic(state) {
+			continue
+		}
+		places = append(places, Place(state.attrs["id"]))
+		if state.name == "final" && im.parents[state] == im.root {
+			final = append(final, Place(state.attrs["id"]))
+		}
+	}
+
+	initial, err := im.entry(im.root, im.root.attrs["initial"])
+	if err != nil {
+		return nil, err
+	}
+
+	var transitions []Transition
+	for _, node := range im.transitions {
+		targets := strings.Fields(node.attrs["target"])
+		if len(targets) == 0 {
+			// Targetless transitions do not change the marking
+			continue
+		}
+		var to []Place
+		for _, target := range targets {
+			state, ok := im.byID[target]
+			if !ok {
+				return nil, node.errorf("transition refers to unknown state '%s'", target)
+			}
+			places, err := im.entry(state, "")
+			if err != nil {
+				return nil, err
+			}
+			to = append(to, places...)
+		}
+		to = uniquePlaces(to)
+
+		var guard Constraint
+		if cond := node.attrs["cond"]; cond != "" {
+			guard = im.cond(node, cond)
+		}
+
+		parent := im.parents[node]
+		var sources [][]Place
+		switch {
+		case isSCXMLAtomic(parent):
+			sources = [][]Place{{Place(parent.attrs["id"])}}
+		case parent.name == "parallel":
+			sources = [][]Place{im.atoms(parent)}
+		default:
+			for _, place := range im.atoms(parent) {
+				sources = append(sources, []Place{place})
+			}
+		}
+
+		events := strings.Fields(node.attrs["event"])
+		if len(events) == 0 {
+			events = []string{"to_" + strings.Join(targets, "_")}
+		}
+		for _, from := range sources {
+			for _, event := range events {
+				t, err := NewTransition(event, from, to)
+				if err != nil {
+					return nil, node.errorf("%v", err)
+				}
+				if guard != nil {
+					t.AddConstraint(guard)
+				}
+				transitions = append(transitions, *t)
+			}
+		}
+	}
+
+	definition, err := NewDefinition(places, transitions,
+		WithName(im.root.attrs["name"]),
+		WithInitialPlaces(initial...),
+		WithFinalPlaces(final...),
+	)
+	if err != nil {
+		return nil, &LoadError{Err: err}
+	}
+	return definition, nil
+}
+
+// cond translates a transition condition
+func (im *scxmlImporter) cond(node *xmlNode, source string) Constraint {
+	constraint, reason := translateGuard(source, "SCXML condition")
+	if reason != "" {
+		im.unsupported = append(im.unsupported, UnsupportedFeature{
+			Workflow: im.root.attrs["name"], Line: node.line, Feature: "cond",
+			Detail: fmt.Sprintf("condition %q %s; the transition is blocked", source, reason),
+		})
+	}
+	return constraint
+}
+
+// entry returns the places marked when entering a state. initial overrides
+// the initial states of a compound state or of the document.
+func (im *scxmlImporter) entry(state *xmlNode, initial string) ([]Place, error) {
+	switch {
+	case isSCXMLAtomic(state):
+		return []Place{Place(state.attrs["id"])}, nil
+	case state.name == "parallel":
+		var places []Place
+		for _, child := range scxmlChildStates(state) {
+			entered, err := im.entry(child, "")
+			if err != nil {
+				return nil, err
+			}
+			places = append(places, entered...)
+		}
+		return places, nil
+	}
+
+	if initial == "" {
+		initial = state.attrs["initial"]
+	}
+	if initial == "" {
+		initial = im.initials[state]
+	}
+	if initial == "" {
+		children := scxmlChildStates(state)
+		if len(children) == 0 {
+			return nil, nil
+		}
+		return im.entry(children[0], "")
+	}
+	var places []Place
+	for _, id := range strings.Fields(initial) {
+		target, ok := im.byID[id]
+		if !ok {
+			return nil, state.errorf("initial state '%s' is not defined", id)
+		}
+		entered, err := im.entry(target, "")
+		if err != nil {
+			return nil, err
+		}
+		places = append(places, entered...)
+	}
+	return uniquePlaces(places), nil
+}
+
+// atoms returns the places below a state
+func (im *scxmlImporter) atoms(state *xmlNode) []Place {
+	if isSCXMLAtomic(state) {
+		return []Place{Place(state.attrs["id"])}
+	}
+	var places []Place
+	for _, child := range scxmlChildStates(state) {
+		places = append(places, im.atoms(child)...)
+	}
+	return places
+}
+
+// isSCXMLState reports whether the element is a state
+func isSCXMLState(node *xmlNode) bool {
+	return node.name == "state" || node.name == "parallel" || node.name == "final"
+}
+
+// isSCXMLAtomic reports whether the element is a state without child states
+func isSCXMLAtomic(node *xmlNode) bool {
+	return node.name == "final" || node.name == "state" && len(scxmlChildStates(node)) == 0
+}
+
+// scxmlChildStates returns the child states of a state
+func scxmlChildStates(node *xmlNode) []*xmlNode {
+	var states []*xmlNode
+	for _, child := range node.children {
+		if isSCXMLState(child) {
+			states = append(states, child)
+		}
+	}
+	return states
+}
+
+// uniquePlaces returns the places without duplicates, in order
+func uniquePlaces(places []Place) []Place {
+	seen := make(map[Place]bool)
+	result := places[:0]
+	for _, place := range places {
+		if !seen[place] {
+			seen[place] = true
+			result = append(result, place)
+		}
+	}
+	return result
+}
+
+// scxmlDocumentXML is the exported form of an SCXML document
+type scxmlDocumentXML struct {
+	XMLName   xml.Name `xml:"scxml"`
+	Namespace string   `xml:"xmlns,attr"`
+	Version   string   `xml:"version,attr"`
+	Name      string   `xml:"name,attr,omitempty"`
+	Initial   string   `xml:"initial,attr,omitempty"`
+	States    []scxmlElementXML
+}
+
+// scxmlElementXML is the exported form of a state or transition
+type scxmlElementXML struct {
+	XMLName  xml.Name
+	ID       string `xml:"id,attr,omitempty"`
+	Event    string `xml:"event,attr,omitempty"`
+	Cond     string `xml:"cond,attr,omitempty"`
+	Target   string `xml:"target,attr,omitempty"`
+	Children []scxmlElementXML
+}
+
+// ExportSCXML exports a definition as a W3C SCXML state chart. Places become
+// states, and final places without outgoing transitions final states.
+// Transition names become event names and guard expressions cond attributes.
+// Places marked together by a fork or consumed together by a join are grouped
+// in a parallel region, whose element holds the joining transitions. Exporting
+// fails for constraints other than guard expressions, for places belonging to
+// several groups, and for transitions or initial places leaving or entering a
+// single place of a group: in SCXML they would exit or enter the whole
+// parallel region.
+func ExportSCXML(definition *Definition) ([]byte, error) {
+	ids := make(xmlIDs)
+	placeIDs := make(map[Place]string)
+	for _, place := range definition.places {
+		placeIDs[place] = ids.unique(string(place))
+	}
+
+	// Group the places forked or joined together in parallel regions
+	var groups [][]Place
+	var groupIDs []string
+	groupOf := make(map[Place]int)
+	group := func(t *Transition, places []Place) (int, error) {
+		if i, ok := groupOf[places[0]]; ok {
+			if !samePlaces(groups[i], places) {
+				return 0, fmt.Errorf("transition '%s' forks or joins places of another parallel region", t.Name())
+			}
+			return i, nil
+		}
+		for _, place := range places {
+			if _, ok := groupOf[place]; ok {
+				return 0, fmt.Errorf("transition '%s' forks or joins places of another parallel region", t.Name())
+			}
+			groupOf[place] = len(groups)
+		}
+		groups = append(groups, places)
+		groupIDs = append(groupIDs, ids.unique(t.Name()+"_parallel"))
+		return len(groups) - 1, nil
+	}
+
+	outgoing := make(map[Place]bool)
+	stateTransitions := make(map[Place][]scxmlElementXML)
+	groupTransitions := make(map[int][]scxmlElementXML)
+	for i := range definition.transitions {
+		t := &definition.transitions[i]
+		var guards []string
+		for _, c := range t.constraints {
+			switch c := c.(type) {
+			case *ExpressionConstraint:
+				guards = append(guards, c.Expression())
+			case *foreignGuard:
+				guards = append(guards, c.source)
+			default:
+				return nil, fmt.Errorf("transition '%s' has a constraint that cannot be exported to SCXML", t.Name())
+			}
+		}
+		transition := scxmlElementXML{XMLName: xml.Name{Local: "transition"}, Event: t.Name(), Cond: joinGuards(guards, "&&")}
+		if to := t.To(); len(to) == 1 {
+			transition.Target = placeIDs[to[0]]
+		} else {
+			g, err := group(t, to)
+			if err != nil {
+				return nil, err
+			}
+			transition.Target = groupIDs[g]
+		}
+		if from := t.From(); len(from) == 1 {
+			stateTransitions[from[0]] = append(stateTransitions[from[0]], transition)
+			outgoing[from[0]] = true
+		} else {
+			g, err := group(t, from)
+			if err != nil {
+				return nil, err
+			}
+			groupTransitions[g] = append(groupTransitions[g], transition)
+			for _, place := range from {
+				outgoing[place] = true
+			}
+		}
+	}
+
+	// A transition out of a state of a parallel region exits the whole region,
+	// and a transition into one enters all its states
+	for _, t := range definition.transitions {
+		if from := t.From(); len(from) == 1 {
+			if g, ok := groupOf[from[0]]; ok {
+				return nil, fmt.Errorf("transition '%s' leaves place '%s' without the other places of parallel region '%s'", t.Name(), from[0], groupIDs[g])
+			}
+		}
+		if to := t.To(); len(to) == 1 {
+			if g, ok := groupOf[to[0]]; ok {
+				return nil, fmt.Errorf("transition '%s' enters place '%s' without the other places of parallel region '%s'", t.Name(), to[0], groupIDs[g])
+			}
+		}
+	}
+	initialSet := placeSet(definition.initialPlaces)
+	for _, place := range definition.initialPlaces {
+		if g, ok := groupOf[place]; ok {
+			for _, member := range groups[g] {
+				if !initialSet[member] {
+					return nil, fmt.Errorf("initial place '%s' is marked without the other places of parallel region '%s'", place, groupIDs[g])
+				}
+			}
+		}
+	}
+
+	final := placeSet(definition.finalPlaces)
+	state := func(place Place) scxmlElementXML {
+		name := "state"
+		if final[place] && !outgoing[place] {
+			name = "final"
+		}
+		return scxmlElementXML{XMLName: xml.Name{Local: name}, ID: placeIDs[place], Children: stateTransitions[place]}
+	}
+
+	// Parallel regions are written at the position of their first place
+	doc := scxmlDocumentXML{Namespace: scxmlNamespace, Version: "1.0", Name: definition.name}
+	written := make(map[int]bool)
+	for _, place := range definition.places {
+		g, ok := groupOf[place]
+		if !ok {
+			doc.States = append(doc.States, state(place))
+			continue
+		}
+		if written[g] {
+			continue
+		}
+		written[g] = true
+		parallel := scxmlElementXML{XMLName: xml.Name{Local: "parallel"}, ID: groupIDs[g]}
+		parallel.Children = append(parallel.Children, groupTransitions[g]...)
+		for _, member := range groups[g] {
+			parallel.Children = append(parallel.Children, state(member))
+		}
+		doc.States = append(doc.States, parallel)
+	}
+
+	var initial []string
+	for _, place := range definition.initialPlaces {
+		initial = append(initial, placeIDs[place])
+	}
+	doc.Initial = strings.Join(initial, " ")
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(xml.Header), data...), '\n'), nil
+}
+
+// samePlaces reports whether two lists hold the same places
+func samePlaces(a, b []Place) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := placeSet(a)
+	for _, place := range b {
+		if !set[place] {
+			return false
+		}
+	}
+	return true
+}
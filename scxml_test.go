@@ -0,0 +1,262 @@
+package workflow_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+const orderSCXML = `<?xml version="1.0" encoding="UTF-8"?>
+<scxml xmlns="http://www.w3.org/2005/07/scxml" version="1.0" name="order" initial="open">
+  <datamodel>
+    <data id="amount" expr="0" />
+  </datamodel>
+  <state id="open" initial="new">
+    <transition event="cancel" target="cancelled" />
+    <state id="new">
+      <transition event="pay" cond="context.amount &gt; 0" target="fulfilment" />
+    </state>
+    <state id="on_hold">
+      <onentry><log expr="'held'" /></onentry>
+    </state>
+  </state>
+  <parallel id="fulfilment">
+    <transition event="ship" cond="In('packed')" target="shipped" />
+    <state id="charged">
+      <transition event="refund" />
+    </state>
+    <state id="packed" />
+  </parallel>
+  <final id="shipped" />
+  <final id="cancelled" />
+</scxml>
+`
+
+func TestImportSCXML(t *testing.T) {
+	def, unsupported, err := workflow.ImportSCXML([]byte(orderSCXML))
+	if err != nil {
+		t.Fatalf("ImportSCXML() error = %v", err)
+	}
+	if len(unsupported) != 0 {
+		t.Errorf("unsupported = %v", unsupported)
+	}
+	if def.Name() != "order" {
+		t.Errorf("name = %q", def.Name())
+	}
+	if got := def.AllPlaces(); len(got) != 6 {
+		t.Errorf("places = %v", got)
+	}
+	if got := def.InitialPlaces(); len(got) != 1 || got[0] != "new" {
+		t.Errorf("initial places = %v", got)
+	}
+	if got := def.FinalPlaces(); len(got) != 2 {
+		t.Errorf("final places = %v", got)
+	}
+
+	var names []string
+	for _, tr := range def.AllTransitions() {
+		names = append(names, tr.Name()+":"+string(tr.From()[0])+"->"+strings.Join(placeStrings(tr.To()), "+"))
+	}
+	want := "cancel:new->cancelled,cancel:on_hold->cancelled,pay:new->charged+packed,ship:charged->shipped"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+	if ship := def.Transition("ship"); len(ship.From()) != 2 {
+		t.Errorf("ship should join, got %v", ship.From())
+	}
+
+	wf, err := workflow.NewWorkflow("o1", def, "new")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	wf.SetContext("amount", 0)
+	if err := wf.Can([]workflow.Place{"charged", "packed"}); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("expected the condition to block, got %v", err)
+	}
+	wf.SetContext("amount", 10)
+	if err := wf.Apply([]workflow.Place{"charged", "packed"}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	// In() tests whether a place is marked
+	if err := wf.Apply([]workflow.Place{"shipped"}); err != nil {
+		t.Errorf("Apply(shipped) error = %v", err)
+	}
+}
+
+func TestSCXML_RoundTrip(t *testing.T) {
+	def, _, err := workflow.ImportSCXML([]byte(orderSCXML))
+	if err != nil {
+		t.Fatalf("ImportSCXML() error = %v", err)
+	}
+	data, err := workflow.ExportSCXML(def)
+	if err != nil {
+		t.Fatalf("ExportSCXML() error = %v", err)
+	}
+	for _, want := range []string{
+		`<scxml xmlns="http://www.w3.org/2005/07/scxml" version="1.0" name="order" initial="new">`,
+		`<transition event="pay" cond="context.amount &gt; 0" target="pay_parallel"></transition>`,
+		`<parallel id="pay_parallel">`,
+		`<final id="shipped"></final>`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("export does not contain %s:\n%s", want, data)
+		}
+	}
+
+	imported, _, err := workflow.ImportSCXML(data)
+	if err != nil {
+		t.Fatalf("ImportSCXML() of exported data error = %v\n%s", err, data)
+	}
+	if len(imported.AllTransitions()) != len(def.AllTransitions()) {
+		t.Errorf("transitions changed after round trip:\n%s", data)
+	}
+	again, err := workflow.ExportSCXML(imported)
+	if err != nil {
+		t.Fatalf("ExportSCXML() error = %v", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("export is not stable:\n%s\n---\n%s", data, again)
+	}
+}
+
+func TestImportSCXML_UnsupportedConditions(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<scxml name="door" initial="closed">
+  <state id="closed">
+    <transition event="open" cond="_event.data.code === 42" target="opened" />
+    <transition event="force" cond="x &lt;" target="opened" />
+    <transition event="knock" cond="context.visitors &gt; 0" target="opened" />
+  </state>
+  <state id="opened" />
+</scxml>
+`
+	def, unsupported, err := workflow.ImportSCXML([]byte(data))
+	if err != nil {
+		t.Fatalf("ImportSCXML() error = %v", err)
+	}
+	if len(unsupported) != 2 || unsupported[0].Line != 4 || unsupported[1].Line != 5 || unsupported[0].Workflow != "door" {
+		t.Fatalf("unsupported = %v", unsupported)
+	}
+
+	wf, err := workflow.NewWorkflow("d1", def, "closed")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	if enabled, _ := wf.EnabledTransitions(); len(enabled) != 3 {
+		t.Errorf("enabled transitions = %v", enabled)
+	}
+	if err := wf.Apply([]workflow.Place{"opened"}); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("untranslated conditions should block, got %v", err)
+	}
+
+	exported, err := workflow.ExportSCXML(def)
+	if err != nil {
+		t.Fatalf("ExportSCXML() error = %v", err)
+	}
+	if !strings.Contains(string(exported), `cond="_event.data.code === 42"`) {
+		t.Errorf("untranslated conditions should be exported:\n%s", exported)
+	}
+}
+
+func TestImportSCXML_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		line    int
+		message string
+	}{
+		{
+			name:    "history",
+			body:    "<state id=\"a\">\n<history id=\"h\" />\n</state>",
+			line:    3,
+			message: `unsupported element "history"`,
+		},
+		{
+			name:    "unknown target",
+			body:    "<state id=\"a\">\n<transition event=\"go\" target=\"b\" />\n</state>",
+			line:    3,
+			message: "transition refers to unknown state 'b'",
+		},
+		{
+			name:    "nested parallel state",
+			body:    "<parallel id=\"p\">\n<state id=\"a\"><state id=\"b\" /></state>\n</parallel>",
+			line:    3,
+			message: "nested states in parallel regions are not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "<?xml version=\"1.0\"?>\n<scxml>" + tt.body + "</scxml>\n"
+			_, _, err := workflow.ImportSCXML([]byte(data))
+			var loadErr *workflow.LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("expected a *LoadError, got %v", err)
+			}
+			if loadErr.Line != tt.line || !strings.Contains(loadErr.Error(), tt.message) {
+				t.Errorf("error = %v, want line %d containing %q", loadErr, tt.line, tt.message)
+			}
+		})
+	}
+}
+
+func TestExportSCXML_UnsupportedRegions(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition *workflow.Definition
+		message    string
+	}{
+		{
+			name: "overlapping regions",
+			definition: workflow.NewBuilder("overlap").
+				Place("a", "b", "c", "d").
+				Transition("split").From("a").To("b", "c").
+				Transition("other").From("a").To("c", "d").
+				MustBuild(),
+			message: "forks or joins places of another parallel region",
+		},
+		{
+			name: "transition leaving one place of a region",
+			definition: workflow.NewBuilder("leave").
+				Place("a", "b", "c", "d").
+				Transition("split").From("a").To("b", "c").
+				Transition("escape").From("b").To("d").
+				MustBuild(),
+			message: "transition 'escape' leaves place 'b'",
+		},
+		{
+			name: "transition entering one place of a region",
+			definition: workflow.NewBuilder("enter").
+				Place("a", "b", "c", "d").
+				Transition("split").From("a").To("b", "c").
+				Transition("merge").From("b", "c").To("d").
+				Transition("back").From("d").To("b").
+				MustBuild(),
+			message: "transition 'back' enters place 'b'",
+		},
+		{
+			name: "initial place in a region",
+			definition: workflow.NewBuilder("initial").
+				Place("b", "c", "d").
+				Transition("merge").From("b", "c").To("d").
+				MustBuild(),
+			message: "initial place 'b' is marked without the other places",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := workflow.ExportSCXML(tt.definition); err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("ExportSCXML() error = %v, want %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func placeStrings(places []workflow.Place) []string {
+	result := make([]string, len(places))
+	for i, place := range places {
+		result[i] = string(place)
+	}
+	return result
+}
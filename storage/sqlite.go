@@ -4,9 +4,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/euphoria-laxis/workflow"
+	"github.com/euphoria-laxis/workflow/internal/sqlschema"
 )
 
 // SQLiteStorage provides a persistent storage implementation using SQLite.
@@ -21,6 +24,10 @@ type SQLiteStorage struct {
 	idColumn    string
 	stateColumn string
 
+	// Columns recording the definition each instance was saved with.
+	definitionNameColumn    string
+	definitionVersionColumn string
+
 	// CustomFields maps a context key to a database column name and its type.
 	// Example: {"document_id": "document_id_col TEXT", "approver": "approver_col TEXT"}
 	customFields map[string]string
@@ -53,6 +60,16 @@ func WithStateColumn(name string) Option {
 	}
 }
 
+// WithDefinitionColumns sets the names of the columns used to store the definition
+// name and version of each workflow instance.
+// Default: "definition_name" and "definition_version".
+func WithDefinitionColumns(nameColumn, versionColumn string) Option {
+	return func(s *SQLiteStorage) {
+		s.definitionNameColumn = nameColumn
+		s.definitionVersionColumn = versionColumn
+	}
+}
+
 // WithCustomFields defines the schema for additional application-specific data to be stored.
 // The map key is the key used in the workflow's context map.
 // The map value is the full SQL column definition (e.g., "title TEXT", "amount INTEGER NOT NULL").
@@ -69,11 +86,13 @@ func NewSQLiteStorage(db *sql.DB, opts ...Option) (*SQLiteStorage, error) {
 	}
 
 	s := &SQLiteStorage{
-		db:           db,
-		table:        "workflow_states",
-		idColumn:     "id",
-		stateColumn:  "state",
-		customFields: make(map[string]string),
+		db:                      db,
+		table:                   "workflow_states",
+		idColumn:                "id",
+		stateColumn:             "state",
+		definitionNameColumn:    "definition_name",
+		definitionVersionColumn: "definition_version",
+		customFields:            make(map[string]string),
 	}
 
 	for _, opt := range opts {
@@ -89,25 +108,87 @@ func (s *SQLiteStorage) GenerateSchema() string {
 	columns := []string{
 		fmt.Sprintf("%s TEXT PRIMARY KEY", s.idColumn),
 		fmt.Sprintf("%s TEXT NOT NULL", s.stateColumn),
+		fmt.Sprintf("%s TEXT", s.definitionNameColumn),
+		fmt.Sprintf("%s TEXT", s.definitionVersionColumn),
 	}
 
-	for _, colDef := range s.customFields {
-		columns = append(columns, colDef)
+	for _, key := range s.customFieldKeys() {
+		columns = append(columns, s.customFields[key])
 	}
 
 	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", s.table, strings.Join(columns, ", "))
 }
 
-// Initialize executes the schema generated by GenerateSchema.
+// Initialize executes the schema generated by GenerateSchema, then adds the
+// columns of the schema missing from an existing table, such as the definition
+// columns of a table created by an earlier version.
 // It's a convenience helper for development and testing. For production,
 // it's recommended to use a proper migration tool.
 func Initialize(db *sql.DB, schema string) error {
-	_, err := db.Exec(schema)
-	return err
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	table, columns, ok := parseSchema(schema)
+	if !ok {
+		return nil
+	}
+	return sqlschema.AddMissingColumns(db, table, columns)
+}
+
+var createTablePattern = regexp.MustCompile(`(?is)^\s*CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\S+)\s*\((.*)\)\s*;?\s*$`)
+
+// parseSchema returns the table name and column definitions of a `CREATE TABLE`
+// statement. Table constraints are left out.
+func parseSchema(schema string) (string, []string, bool) {
+	match := createTablePattern.FindStringSubmatch(schema)
+	if match == nil {
+		return "", nil, false
+	}
+
+	// Split on the commas outside parentheses, as in "amount DECIMAL(10, 2)"
+	var columns []string
+	depth, start := 0, 0
+	body := match[2] + ","
+	for i, r := range body {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth > 0 {
+				continue
+			}
+			column := strings.TrimSpace(body[start:i])
+			start = i + 1
+			fields := strings.Fields(column)
+			if len(fields) == 0 {
+				continue
+			}
+			switch strings.ToUpper(fields[0]) {
+			case "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "CONSTRAINT":
+				continue
+			}
+			columns = append(columns, column)
+		}
+	}
+	return match[1], columns, true
 }
 
 // SaveState saves the workflow's current places and any configured custom fields from its context.
+// The definition recorded for an existing instance is kept.
 func (s *SQLiteStorage) SaveState(id string, places []workflow.Place, context map[string]interface{}) error {
+	return s.save(id, nil, places, context)
+}
+
+// SaveVersionedState saves the workflow state like SaveState and records the definition
+// name and version the instance uses.
+func (s *SQLiteStorage) SaveVersionedState(id string, ref workflow.DefinitionRef, places []workflow.Place, context map[string]interface{}) error {
+	return s.save(id, &ref, places, context)
+}
+
+// save upserts a workflow state, updating the definition columns only when ref is set
+func (s *SQLiteStorage) save(id string, ref *workflow.DefinitionRef, places []workflow.Place, context map[string]interface{}) error {
 	stateJSON, err := json.Marshal(places)
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
@@ -117,8 +198,14 @@ func (s *SQLiteStorage) SaveState(id string, places []workflow.Place, context ma
 	values := []interface{}{id, stateJSON}
 	placeholders := []string{"?", "?"}
 
-	for key, colDef := range s.customFields {
-		colName := strings.Fields(colDef)[0]
+	if ref != nil {
+		columns = append(columns, s.definitionNameColumn, s.definitionVersionColumn)
+		values = append(values, ref.Name, ref.Version)
+		placeholders = append(placeholders, "?", "?")
+	}
+
+	for _, key := range s.customFieldKeys() {
+		colName := strings.Fields(s.customFields[key])[0]
 		columns = append(columns, colName)
 		placeholders = append(placeholders, "?")
 
@@ -129,12 +216,18 @@ func (s *SQLiteStorage) SaveState(id string, places []workflow.Place, context ma
 		}
 	}
 
-	// Using "REPLACE INTO" which is a SQLite-specific convenience.
-	// It's equivalent to an INSERT or, if a row with the same primary key exists, a DELETE followed by an INSERT.
-	query := fmt.Sprintf("REPLACE INTO %s (%s) VALUES (%s);",
+	// Upsert so that columns not written here, such as the definition of an
+	// existing instance, are preserved.
+	updates := make([]string, 0, len(columns)-1)
+	for _, col := range columns[1:] {
+		updates = append(updates, fmt.Sprintf("%s = excluded.%s", col, col))
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT(%s) DO UPDATE SET %s;",
 		s.table,
 		strings.Join(columns, ", "),
 		strings.Join(placeholders, ", "),
+		s.idColumn,
+		strings.Join(updates, ", "),
 	)
 
 	_, err = s.db.Exec(query, values...)
@@ -143,9 +236,18 @@ func (s *SQLiteStorage) SaveState(id string, places []workflow.Place, context ma
 
 // LoadState loads the workflow's places and all configured custom fields into the context map.
 func (s *SQLiteStorage) LoadState(id string) ([]workflow.Place, map[string]interface{}, error) {
-	columns := []string{s.stateColumn}
-	for _, colDef := range s.customFields {
-		colName := strings.Fields(colDef)[0]
+	_, places, context, err := s.LoadVersionedState(id)
+	return places, context, err
+}
+
+// LoadVersionedState loads the workflow state like LoadState together with the definition
+// name and version recorded for the instance, which are empty when none was saved.
+func (s *SQLiteStorage) LoadVersionedState(id string) (workflow.DefinitionRef, []workflow.Place, map[string]interface{}, error) {
+	var ref workflow.DefinitionRef
+	customFieldKeys := s.customFieldKeys()
+	columns := []string{s.stateColumn, s.definitionNameColumn, s.definitionVersionColumn}
+	for _, key := range customFieldKeys {
+		colName := strings.Fields(s.customFields[key])[0]
 		columns = append(columns, colName)
 	}
 
@@ -158,17 +260,21 @@ func (s *SQLiteStorage) LoadState(id string) ([]workflow.Place, map[string]inter
 	row := s.db.QueryRow(query, id)
 
 	// Prepare to scan into a slice of interface{} pointers
+	var definitionName, definitionVersion sql.NullString
 	scanArgs := make([]interface{}, len(columns))
-	for i := range columns {
+	scanArgs[0] = new(interface{})
+	scanArgs[1] = &definitionName
+	scanArgs[2] = &definitionVersion
+	for i := 3; i < len(columns); i++ {
 		scanArgs[i] = new(interface{})
 	}
 
 	err := row.Scan(scanArgs...)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, nil, fmt.Errorf("workflow with id %s not found", id)
+			return ref, nil, nil, fmt.Errorf("workflow with id %s not found", id)
 		}
-		return nil, nil, fmt.Errorf("failed to load state: %w", err)
+		return ref, nil, nil, fmt.Errorf("failed to load state: %w", err)
 	}
 
 	// Process results
@@ -176,28 +282,62 @@ func (s *SQLiteStorage) LoadState(id string) ([]workflow.Place, map[string]inter
 	if rawState, ok := (*scanArgs[0].(*interface{})).([]byte); ok {
 		stateJSON = rawState
 	} else {
-		return nil, nil, fmt.Errorf("unexpected type for state column")
+		return ref, nil, nil, fmt.Errorf("unexpected type for state column")
 	}
 
 	var places []workflow.Place
 	if err := json.Unmarshal(stateJSON, &places); err != nil {
-		return nil, nil, fmt.Errorf("failed to unmarshal state: %w", err)
+		return ref, nil, nil, fmt.Errorf("failed to unmarshal state: %w", err)
 	}
+	ref.Name = definitionName.String
+	ref.Version = definitionVersion.String
 
 	context := make(map[string]interface{})
-	customFieldKeys := make([]string, 0, len(s.customFields))
-	for k := range s.customFields {
-		customFieldKeys = append(customFieldKeys, k)
-	}
-
 	for i, key := range customFieldKeys {
-		val := *(scanArgs[i+1].(*interface{}))
+		val := *(scanArgs[i+3].(*interface{}))
 		// SQLite may return int64 for INTEGER columns, etc.
 		// The application layer will need to handle type assertions.
 		context[key] = val
 	}
 
-	return places, context, nil
+	return ref, places, context, nil
+}
+
+// ListInstances returns the IDs of the workflows saved with the given definition name and version.
+func (s *SQLiteStorage) ListInstances(ref workflow.DefinitionRef) ([]string, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? AND %s = ? ORDER BY %s",
+		s.idColumn,
+		s.table,
+		s.definitionNameColumn,
+		s.definitionVersionColumn,
+		s.idColumn,
+	)
+	rows, err := s.db.Query(query, ref.Name, ref.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// customFieldKeys returns the custom field keys in a stable order, so that
+// the columns of a query line up with the scanned values.
+func (s *SQLiteStorage) customFieldKeys() []string {
+	keys := make([]string, 0, len(s.customFields))
+	for k := range s.customFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // DeleteState removes a workflow's state from the database.
@@ -99,3 +99,96 @@ func TestSQLiteStorage_DeleteState(t *testing.T) {
 		t.Errorf("expected error when loading deleted state")
 	}
 }
+
+func TestSQLiteStorage_VersionedState(t *testing.T) {
+	db := setupTestDB(t)
+	s, err := NewSQLiteStorage(db)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	if err := Initialize(db, s.GenerateSchema()); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
+	}
+	var _ workflow.VersionedStorage = s
+
+	v1 := workflow.DefinitionRef{Name: "article", Version: "1"}
+	if err := s.SaveVersionedState("wf1", v1, []workflow.Place{"draft"}, nil); err != nil {
+		t.Fatalf("failed to save state: %v", err)
+	}
+	if err := s.SaveState("wf2", []workflow.Place{"draft"}, nil); err != nil {
+		t.Fatalf("failed to save state: %v", err)
+	}
+
+	// Saving without a reference keeps the recorded definition
+	if err := s.SaveState("wf1", []workflow.Place{"review"}, nil); err != nil {
+		t.Fatalf("failed to save state: %v", err)
+	}
+	ref, places, _, err := s.LoadVersionedState("wf1")
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if ref != v1 || len(places) != 1 || places[0] != "review" {
+		t.Errorf("unexpected state: %v %v", ref, places)
+	}
+
+	ref, _, _, err = s.LoadVersionedState("wf2")
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if ref != (workflow.DefinitionRef{}) {
+		t.Errorf("expected an empty ref, got %v", ref)
+	}
+
+	ids, err := s.ListInstances(v1)
+	if err != nil {
+		t.Fatalf("failed to list instances: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != "wf1" {
+		t.Errorf("unexpected instances: %v", ids)
+	}
+}
+
+func TestSQLiteStorage_MigratesOldSchema(t *testing.T) {
+	db := setupTestDB(t)
+	if _, err := db.Exec(`CREATE TABLE workflow_states (id TEXT PRIMARY KEY, state TEXT NOT NULL, title TEXT)`); err != nil {
+		t.Fatalf("failed to create old table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO workflow_states (id, state, title) VALUES (?, ?, ?)`, "wf1", []byte(`["draft"]`), "Old"); err != nil {
+		t.Fatalf("failed to insert old state: %v", err)
+	}
+
+	s, err := NewSQLiteStorage(db, WithCustomFields(map[string]string{
+		"title":  "title TEXT",
+		"amount": "amount DECIMAL(10, 2)",
+	}))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	if err := Initialize(db, s.GenerateSchema()); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
+	}
+	// Initializing an up to date table changes nothing
+	if err := Initialize(db, s.GenerateSchema()); err != nil {
+		t.Fatalf("failed to initialize schema twice: %v", err)
+	}
+
+	places, context, err := s.LoadState("wf1")
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if len(places) != 1 || places[0] != "draft" || context["title"] != "Old" || context["amount"] != nil {
+		t.Errorf("unexpected state: %v %v", places, context)
+	}
+
+	v1 := workflow.DefinitionRef{Name: "article", Version: "1"}
+	if err := s.SaveVersionedState("wf1", v1, places, context); err != nil {
+		t.Fatalf("failed to save state: %v", err)
+	}
+	ref, _, _, err := s.LoadVersionedState("wf1")
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if ref != v1 {
+		t.Errorf("unexpected ref: %v", ref)
+	}
+}
@@ -0,0 +1,417 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SVGOptions configures SVG rendering
+type SVGOptions struct {
+	// RenderOptions sets the places to highlight. Counts are overlaid as a
+	// badge on each place, and Metadata adds place and transition metadata
+	// below the labels.
+	RenderOptions
+}
+
+// Sizes of the SVG drawing, in pixels
+const (
+	svgMargin      = 20
+	svgLayerGap    = 60
+	svgSlotHeight  = 100
+	svgPlaceRadius = 22
+	svgCharWidth   = 7
+	svgLineHeight  = 14
+	svgTitleHeight = 30
+)
+
+// svgNodeKind is the kind of a node of the layout
+type svgNodeKind int
+
+const (
+	svgStart svgNodeKind = iota
+	svgPlace
+	svgTransition
+	svgBar
+)
+
+// svgNode is a node of the layout, positioned by its center
+type svgNode struct {
+	kind  svgNodeKind
+	place Place
+	// name is the name of a transition
+	name   string
+	lines  []string
+	width  int
+	height int
+	layer  int
+	order  int
+	x, y   int
+}
+
+// svgEdge is an arc between two nodes of the layout
+type svgEdge struct {
+	from, to int
+	// back is set for arcs closing a cycle, drawn against the layer order
+	back bool
+}
+
+// SVG renders the definition as a standalone SVG image, without external
+// tools. Places are drawn as circles and transitions as boxes, or as bars
+// for forks and joins, arranged in layers from left to right.
+func (d *Definition) SVG(opts SVGOptions) string {
+	return d.svg(opts, d.initialPlaces, nil)
+}
+
+// SVG renders the workflow as a standalone SVG image, marking its current
+// places with a token
+func (w *Workflow) SVG(opts SVGOptions) string {
+	return w.Definition().svg(opts, []Place{w.InitialPlace()}, w.CurrentPlaces())
+}
+
+// svg renders the definition with the given initial and current places
+func (d *Definition) svg(opts SVGOptions, initial, current []Place) string {
+	nodes, edges := d.svgGraph(opts, initial)
+	width, height := svgLayout(nodes, edges)
+	top := svgMargin
+	title, hasTitle := metadataString(d.metadata, MetadataLabel)
+	if !hasTitle {
+		title = d.name
+	}
+	if title != "" {
+		top += svgTitleHeight
+	}
+	for i := range nodes {
+		nodes[i].x += svgMargin
+		nodes[i].y += top
+	}
+	width += 2 * svgMargin
+	height += top + svgMargin
+	// Back arcs loop below the nodes and can reach further down than them
+	for _, edge := range edges {
+		if edge.back {
+			height = max(height, svgBackArcDrop(nodes[edge.from], nodes[edge.to])+svgMargin)
+		}
+	}
+
+	var svg strings.Builder
+	svg.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height))
+	svg.WriteString("  <defs>\n")
+	svg.WriteString(`    <marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="7" markerHeight="7" orient="auto"><path d="M 0 0 L 10 5 L 0 10 z" fill="#333"/></marker>` + "\n")
+	svg.WriteString("  </defs>\n")
+	svg.WriteString(fmt.Sprintf(`  <rect width="%d" height="%d" fill="white"/>`+"\n", width, height))
+	if title != "" {
+		svg.WriteString(fmt.Sprintf(`  <text x="%d" y="%d" font-size="16" font-weight="bold">%s</text>`+"\n", svgMargin, svgMargin+16, svgEscape(title)))
+	}
+
+	for _, edge := range edges {
+		svg.WriteString(fmt.Sprintf(`  <path d="%s" fill="none" stroke="#333" marker-end="url(#arrow)"/>`+"\n", svgEdgePath(nodes[edge.from], nodes[edge.to], edge.back)))
+	}
+
+	highlighted := opts.highlighted(current)
+	final := placeSet(d.finalPlaces)
+	for _, node := range nodes {
+		switch node.kind {
+		case svgStart:
+			svg.WriteString(fmt.Sprintf(`  <circle cx="%d" cy="%d" r="5" fill="#333"/>`+"\n", node.x, node.y))
+		case svgPlace:
+			fill := "white"
+			if color, ok := metadataString(d.placeMetadata[node.place], MetadataColor); ok {
+				fill = color
+			}
+			stroke := 1
+			if highlighted[node.place] {
+				stroke = 3
+			}
+			svg.WriteString(fmt.Sprintf(`  <g class="place" data-place="%s">`+"\n", svgEscape(string(node.place))))
+			svg.WriteString(fmt.Sprintf(`    <circle cx="%d" cy="%d" r="%d" fill="%s" stroke="#333" stroke-width="%d"/>`+"\n", node.x, node.y, svgPlaceRadius, svgEscape(fill), stroke))
+			if final[node.place] {
+				svg.WriteString(fmt.Sprintf(`    <circle cx="%d" cy="%d" r="%d" fill="none" stroke="#333"/>`+"\n", node.x, node.y, svgPlaceRadius-4))
+			}
+			if highlighted[node.place] {
+				svg.WriteString(fmt.Sprintf(`    <circle cx="%d" cy="%d" r="6" fill="#333"/>`+"\n", node.x, node.y))
+			}
+			svgText(&svg, node.lines, node.x, node.y+svgPlaceRadius+svgLineHeight)
+			if count, ok := opts.Counts[node.place]; ok {
+				bx, by := node.x+svgPlaceRadius*7/10, node.y-svgPlaceRadius*7/10
+				svg.WriteString(fmt.Sprintf(`    <circle cx="%d" cy="%d" r="10" fill="#d33"/>`+"\n", bx, by))
+				svg.WriteString(fmt.Sprintf(`    <text x="%d" y="%d" text-anchor="middle" font-size="10" fill="white">%d</text>`+"\n", bx, by+4, count))
+			}
+			svg.WriteString("  </g>\n")
+		case svgTransition:
+			svg.WriteString(fmt.Sprintf(`  <g class="transition" data-transition="%s">`+"\n", svgEscape(node.name)))
+			svg.WriteString(fmt.Sprintf(`    <rect x="%d" y="%d" width="%d" height="%d" rx="3" fill="#f4f4f4" stroke="#333"/>`+"\n", node.x-node.width/2, node.y-node.height/2, node.width, node.height))
+			svgText(&svg, node.lines, node.x, node.y+4)
+			svg.WriteString("  </g>\n")
+		case svgBar:
+			svg.WriteString(fmt.Sprintf(`  <g class="transition" data-transition="%s">`+"\n", svgEscape(node.name)))
+			svg.WriteString(fmt.Sprintf(`    <rect x="%d" y="%d" width="8" height="%d" fill="#333"/>`+"\n", node.x-4, node.y-node.height/2, node.height))
+			svgText(&svg, node.lines, node.x, node.y-node.height/2-6-svgLineHeight*(len(node.lines)-1))
+			svg.WriteString("  </g>\n")
+		}
+	}
+	svg.WriteString("</svg>\n")
+	return svg.String()
+}
+
+// svgGraph returns the nodes and arcs of the definition: a start node when
+// there are initial places, then the places and the transitions
+func (d *Definition) svgGraph(opts SVGOptions, initial []Place) ([]svgNode, []svgEdge) {
+	var nodes []svgNode
+	var edges []svgEdge
+	index := make(map[Place]int)
+	start := -1
+	if len(initial) > 0 {
+		start = 0
+		nodes = append(nodes, svgNode{kind: svgStart, width: 10, height: 10})
+	}
+	for _, place := range d.places {
+		index[place] = len(nodes)
+		lines := svgLines(string(place), d.placeMetadata[place], opts.Metadata)
+		nodes = append(nodes, svgNode{
+			kind:   svgPlace,
+			place:  place,
+			lines:  lines,
+			width:  max(2*svgPlaceRadius, svgTextWidth(lines)),
+			height: 2 * svgPlaceRadius,
+		})
+	}
+	for _, place := range initial {
+		if i, ok := index[place]; ok {
+			edges = append(edges, svgEdge{from: start, to: i})
+		}
+	}
+	for _, t := range d.transitions {
+		lines := svgLines(t.Name(), t.metadata, opts.Metadata)
+		node := svgNode{kind: svgTransition, name: t.Name(), lines: lines, width: max(40, svgTextWidth(lines)+16), height: 12 + svgLineHeight*len(lines)}
+		if len(t.From()) > 1 || len(t.To()) > 1 {
+			node = svgNode{kind: svgBar, name: t.Name(), lines: lines, width: max(8, svgTextWidth(lines)), height: max(40, 30*max(len(t.From()), len(t.To())))}
+		}
+		i := len(nodes)
+		nodes = append(nodes, node)
+		for _, place := range t.From() {
+			edges = append(edges, svgEdge{from: index[place], to: i})
+		}
+		for _, place := range t.To() {
+			edges = append(edges, svgEdge{from: i, to: index[place]})
+		}
+	}
+	return nodes, edges
+}
+
+// svgLayout assigns layers and positions to the nodes and returns the size of
+// the drawing. Arcs closing cycles are found by a depth-first search from the
+// first node and marked as back arcs, nodes are layered by the longest path
+// over the remaining arcs, and nodes are ordered within their layer by the
+// average position of their neighbours.
+func svgLayout(nodes []svgNode, edges []svgEdge) (int, int) {
+	out := make([][]int, len(nodes))
+	for i, edge := range edges {
+		out[edge.from] = append(out[edge.from], i)
+	}
+
+	// Mark back arcs
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make([]int, len(nodes))
+	var visit func(int)
+	visit = func(n int) {
+		state[n] = active
+		for _, e := range out[n] {
+			switch state[edges[e].to] {
+			case unvisited:
+				visit(edges[e].to)
+			case active:
+				edges[e].back = true
+			}
+		}
+		state[n] = done
+	}
+	for n := range nodes {
+		if state[n] == unvisited {
+			visit(n)
+		}
+	}
+
+	// Longest path layering over forward arcs, in topological order
+	indegree := make([]int, len(nodes))
+	for _, edge := range edges {
+		if !edge.back {
+			indegree[edge.to]++
+		}
+	}
+	var queue []int
+	for n := range nodes {
+		if indegree[n] == 0 {
+			queue = append(queue, n)
+		}
+	}
+	layerCount := 0
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, e := range out[n] {
+			if edges[e].back {
+				continue
+			}
+			to := edges[e].to
+			nodes[to].layer = max(nodes[to].layer, nodes[n].layer+1)
+			if indegree[to]--; indegree[to] == 0 {
+				queue = append(queue, to)
+			}
+		}
+		layerCount = max(layerCount, nodes[n].layer+1)
+	}
+	layers := make([][]int, layerCount)
+	for n := range nodes {
+		layers[nodes[n].layer] = append(layers[nodes[n].layer], n)
+	}
+
+	// Order the nodes of each layer by the barycenter of their neighbours in
+	// the previous layers, then in the next layers
+	neighbours := make([][]int, len(nodes))
+	for _, edge := range edges {
+		neighbours[edge.from] = append(neighbours[edge.from], edge.to)
+		neighbours[edge.to] = append(neighbours[edge.to], edge.from)
+	}
+	for _, layer := range layers {
+		for i, n := range layer {
+			nodes[n].order = i
+		}
+	}
+	sortLayer := func(layer []int, before bool) {
+		keys := make(map[int]float64, len(layer))
+		for _, n := range layer {
+			sum, count := 0, 0
+			for _, m := range neighbours[n] {
+				if (before && nodes[m].layer < nodes[n].layer) || (!before && nodes[m].layer > nodes[n].layer) {
+					sum += nodes[m].order
+					count++
+				}
+			}
+			keys[n] = float64(nodes[n].order)
+			if count > 0 {
+				keys[n] = float64(sum) / float64(count)
+			}
+		}
+		sort.SliceStable(layer, func(i, j int) bool { return keys[layer[i]] < keys[layer[j]] })
+		for i, n := range layer {
+			nodes[n].order = i
+		}
+	}
+	for iteration := 0; iteration < 4; iteration++ {
+		for l := 1; l < len(layers); l++ {
+			sortLayer(layers[l], true)
+		}
+		for l := len(layers) - 2; l >= 0; l-- {
+			sortLayer(layers[l], false)
+		}
+	}
+
+	// Place the layers from left to right, centering each layer vertically
+	maxNodes := 0
+	for _, layer := range layers {
+		maxNodes = max(maxNodes, len(layer))
+	}
+	height := maxNodes * svgSlotHeight
+	x := 0
+	for l, layer := range layers {
+		layerWidth := 0
+		for _, n := range layer {
+			layerWidth = max(layerWidth, nodes[n].width)
+		}
+		if l > 0 {
+			x += svgLayerGap
+		}
+		offset := (height - len(layer)*svgSlotHeight) / 2
+		for i, n := range layer {
+			nodes[n].x = x + layerWidth/2
+			nodes[n].y = offset + i*svgSlotHeight + svgSlotHeight/2
+		}
+		x += layerWidth
+	}
+	return x, height
+}
+
+// svgEdgePath returns the path of an arc. Forward arcs leave the right side of
+// a node for the left side of the next one, and back arcs loop below both.
+func svgEdgePath(from, to svgNode, back bool) string {
+	if back {
+		x1, y1 := from.x, from.y+from.height/2
+		x2, y2 := to.x, to.y+to.height/2
+		drop := svgBackArcDrop(from, to)
+		return fmt.Sprintf("M %d %d C %d %d %d %d %d %d", x1, y1, x1, drop, x2, drop, x2, y2)
+	}
+	x1, y1 := from.x+svgNodeHalfWidth(from), from.y
+	x2, y2 := to.x-svgNodeHalfWidth(to), to.y
+	mid := (x1 + x2) / 2
+	return fmt.Sprintf("M %d %d C %d %d %d %d %d %d", x1, y1, mid, y1, mid, y2, x2, y2)
+}
+
+// svgBackArcDrop returns the y coordinate of the control points of a back
+// arc, which bounds how far the arc dips below the nodes
+func svgBackArcDrop(from, to svgNode) int {
+	return max(from.y+from.height/2, to.y+to.height/2) + 30 + absInt(from.x-to.x)/8
+}
+
+// svgNodeHalfWidth returns half the width of the shape of a node, which can
+// be narrower than the space taken by its label
+func svgNodeHalfWidth(node svgNode) int {
+	switch node.kind {
+	case svgStart:
+		return 5
+	case svgPlace:
+		return svgPlaceRadius
+	case svgBar:
+		return 4
+	}
+	return node.width / 2
+}
+
+// svgLines returns the label lines of a node, using the label metadata when
+// set and listing the remaining metadata when requested
+func svgLines(name string, metadata map[string]interface{}, withMetadata bool) []string {
+	label := name
+	if l, ok := metadataString(metadata, MetadataLabel); ok {
+		label = l
+	}
+	lines := []string{label}
+	if withMetadata {
+		lines = append(lines, metadataLines(metadata)...)
+	}
+	return lines
+}
+
+// svgText writes centered lines of text, the first one at y
+func svgText(svg *strings.Builder, lines []string, x, y int) {
+	for i, line := range lines {
+		svg.WriteString(fmt.Sprintf(`    <text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", x, y+i*svgLineHeight, svgEscape(line)))
+	}
+}
+
+// svgTextWidth estimates the width of the widest line
+func svgTextWidth(lines []string) int {
+	width := 0
+	for _, line := range lines {
+		width = max(width, len([]rune(line))*svgCharWidth)
+	}
+	return width
+}
+
+// svgEscape escapes text and attribute values
+func svgEscape(s string) string {
+	return svgEscaper.Replace(s)
+}
+
+var svgEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// absInt returns the absolute value of a
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,160 @@
+package workflow_test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+// svgPlace is a place group of a rendered SVG image
+type svgPlace struct {
+	Class   string `xml:"class,attr"`
+	Name    string `xml:"data-place,attr"`
+	Circles []struct {
+		CX          int    `xml:"cx,attr"`
+		Fill        string `xml:"fill,attr"`
+		StrokeWidth string `xml:"stroke-width,attr"`
+	} `xml:"circle"`
+	Texts []string `xml:"text"`
+}
+
+// parseSVG checks that the image is well-formed and returns its place groups
+func parseSVG(t *testing.T, image string) map[string]svgPlace {
+	t.Helper()
+	var doc struct {
+		XMLName xml.Name   `xml:"svg"`
+		Groups  []svgPlace `xml:"g"`
+	}
+	if err := xml.Unmarshal([]byte(image), &doc); err != nil {
+		t.Fatalf("invalid SVG: %v\n%s", err, image)
+	}
+	places := make(map[string]svgPlace)
+	for _, group := range doc.Groups {
+		if group.Class == "place" {
+			places[group.Name] = group
+		}
+	}
+	return places
+}
+
+func TestDefinition_SVG(t *testing.T) {
+	image := orderDiagramDefinition.SVG(workflow.SVGOptions{RenderOptions: workflow.RenderOptions{
+		Counts:   map[workflow.Place]int{"paid": 12},
+		Metadata: true,
+	}})
+	places := parseSVG(t, image)
+	if len(places) != 4 {
+		t.Fatalf("expected 4 places, got %d:\n%s", len(places), image)
+	}
+
+	// Layers go from left to right
+	if !(places["new"].Circles[0].CX < places["paid"].Circles[0].CX && places["paid"].Circles[0].CX < places["shipped"].Circles[0].CX) {
+		t.Errorf("places are not laid out in order:\n%s", image)
+	}
+	if places["packed"].Circles[0].CX != places["paid"].Circles[0].CX {
+		t.Errorf("forked places should share a layer:\n%s", image)
+	}
+
+	if got := places["paid"].Texts; len(got) < 2 || got[len(got)-1] != "12" {
+		t.Errorf("paid should show its count, got %v", got)
+	}
+	if got := len(places["shipped"].Circles); got != 2 {
+		t.Errorf("final place should be drawn with 2 circles, got %d", got)
+	}
+	for _, want := range []string{
+		`<text x="20" y="36" font-size="16" font-weight="bold">order</text>`,
+		`<g class="transition" data-transition="pay">`,
+		`<text x="218" y="114" text-anchor="middle">cost: 2</text>`,
+	} {
+		if !strings.Contains(image, want) {
+			t.Errorf("SVG does not contain %s:\n%s", want, image)
+		}
+	}
+}
+
+func TestWorkflow_SVG(t *testing.T) {
+	def := workflow.NewBuilder("loop").
+		Place("draft", "review", "done").
+		Transition("submit").From("draft").To("review").
+		Transition("reject").From("review").To("draft").
+		Transition("approve").From("review").To("done").
+		MustBuild()
+	wf, err := workflow.NewWorkflow("w1", def, "draft")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	if err := wf.Apply([]workflow.Place{"review"}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	image := wf.SVG(workflow.SVGOptions{RenderOptions: workflow.RenderOptions{Highlight: []workflow.Place{"done"}}})
+	places := parseSVG(t, image)
+	for name, marked := range map[string]bool{"draft": false, "review": true, "done": true} {
+		circles := places[name].Circles
+		if got := circles[0].StrokeWidth == "3"; got != marked {
+			t.Errorf("place %s highlighted = %v, want %v", name, got, marked)
+		}
+	}
+	if got := len(places["review"].Circles); got != 2 {
+		t.Errorf("current place should hold a token, got %d circles", got)
+	}
+
+	// Every arc is drawn, including the reject arc closing the cycle
+	if strings.Count(image, `marker-end="url(#arrow)"`) != 7 {
+		t.Errorf("expected 7 arcs:\n%s", image)
+	}
+	if wf.SVG(workflow.SVGOptions{}) != wf.SVG(workflow.SVGOptions{}) {
+		t.Error("SVG output is not deterministic")
+	}
+	if !strings.HasPrefix(image, `<svg xmlns="http://www.w3.org/2000/svg" width="`) {
+		t.Errorf("unexpected header:\n%s", image)
+	}
+	if _, err := strconv.Atoi(strings.Split(strings.TrimPrefix(image, `<svg xmlns="http://www.w3.org/2000/svg" width="`), `"`)[0]); err != nil {
+		t.Errorf("invalid width: %v", err)
+	}
+}
+
+func TestDefinition_SVGBackArcExtent(t *testing.T) {
+	builder := workflow.NewBuilder("chain").Place("p0", "p1", "p2", "p3", "p4", "p5", "p6", "p7")
+	for i := 0; i < 7; i++ {
+		builder.Transition(fmt.Sprintf("t%d", i)).From(workflow.Place(fmt.Sprintf("p%d", i))).To(workflow.Place(fmt.Sprintf("p%d", i+1)))
+	}
+	def := builder.Transition("restart").From("p7").To("p0").MustBuild()
+	image := def.SVG(workflow.SVGOptions{})
+
+	var doc struct {
+		ViewBox string `xml:"viewBox,attr"`
+		Paths   []struct {
+			D string `xml:"d,attr"`
+		} `xml:"path"`
+	}
+	if err := xml.Unmarshal([]byte(image), &doc); err != nil {
+		t.Fatalf("invalid SVG: %v\n%s", err, image)
+	}
+	box := strings.Fields(doc.ViewBox)
+	if len(box) != 4 {
+		t.Fatalf("invalid viewBox %q", doc.ViewBox)
+	}
+	width, _ := strconv.Atoi(box[2])
+	height, _ := strconv.Atoi(box[3])
+
+	// Every point of every arc, control points included, lies inside the image
+	for _, path := range doc.Paths {
+		var coords []int
+		for _, field := range strings.Fields(path.D) {
+			if n, err := strconv.Atoi(field); err == nil {
+				coords = append(coords, n)
+			}
+		}
+		for i := 0; i+1 < len(coords); i += 2 {
+			if x, y := coords[i], coords[i+1]; x < 0 || x > width || y < 0 || y > height {
+				t.Errorf("path %q leaves the viewBox %q", path.D, doc.ViewBox)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,654 @@
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SymfonyType is the type of a Symfony workflow
+type SymfonyType string
+
+const (
+	// SymfonyWorkflowType is a Petri net workflow, in which several places can be marked
+	SymfonyWorkflowType SymfonyType = "workflow"
+	// SymfonyStateMachine is a state machine, in which a single place is marked
+	SymfonyStateMachine SymfonyType = "state_machine"
+)
+
+// SymfonyMarkingStore is the marking store configuration of a Symfony workflow
+type SymfonyMarkingStore struct {
+	Type     string
+	Property string
+}
+
+// SymfonyWorkflow is a workflow of a Symfony framework.workflows configuration.
+// The Symfony specific settings are kept so that the workflow can be exported back.
+type SymfonyWorkflow struct {
+	Definition   *Definition
+	Type         SymfonyType
+	MarkingStore *SymfonyMarkingStore
+	Supports     []string
+}
+
+// UnsupportedFeature describes a feature that could not be converted
+type UnsupportedFeature struct {
+	Workflow string
+	// Line is the line of the feature in the imported file, or 0 on export
+	Line    int
+	Feature string
+	Detail  string
+}
+
+// String returns a readable description of the feature
+func (f UnsupportedFeature) String() string {
+	location := f.Workflow
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s (line %d)", f.Workflow, f.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, f.Feature, f.Detail)
+}
+
+// SymfonyImport is the result of importing a Symfony configuration
+type SymfonyImport struct {
+	Workflows []*SymfonyWorkflow
+	// Unsupported lists the settings that were ignored or could not be translated
+	Unsupported []UnsupportedFeature
+}
+
+// Workflow returns the imported workflow with the given name
+func (i *SymfonyImport) Workflow(name string) (*SymfonyWorkflow, bool) {
+	for _, w := range i.Workflows {
+		if w.Definition.Name() == name {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+// ImportSymfony reads the workflows of a Symfony configuration. The data may
+// contain a framework.workflows section, a workflows section or the workflows
+// directly. Guards are translated to guard expressions when they only use the
+// variables and functions of the expression language; other guards block their
+// transition and are reported as unsupported, like every ignored setting.
+func ImportSymfony(data []byte) (*SymfonyImport, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, &LoadError{Err: err}
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil, &LoadError{Err: fmt.Errorf("empty configuration")}
+	}
+
+	node := root.Content[0]
+	for _, key := range []string{"framework", "workflows"} {
+		if node.Kind != yaml.MappingNode {
+			break
+		}
+		if value := mappingValue(node, key); value != nil {
+			node = value
+		}
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, yamlErrorf(node, "expected a mapping of workflows")
+	}
+
+	result := &SymfonyImport{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		s := &symfonyImporter{name: node.Content[i].Value, result: result}
+		w, err := s.workflow(node.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		result.Workflows = append(result.Workflows, w)
+	}
+	return result, nil
+}
+
+// mappingValue returns the value of a key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// symfonyImporter converts a single Symfony workflow
+type symfonyImporter struct {
+	name   string
+	result *SymfonyImport
+	places map[Place]bool
+}
+
+func (s *symfonyImporter) unsupported(node *yaml.Node, feature, detail string) {
+	s.result.Unsupported = append(s.result.Unsupported, UnsupportedFeature{
+		Workflow: s.name, Line: node.Line, Feature: feature, Detail: detail,
+	})
+}
+
+func (s *symfonyImporter) workflow(node *yaml.Node) (*SymfonyWorkflow, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, yamlErrorf(node, "workflow %s must be a mapping", s.name)
+	}
+	w := &SymfonyWorkflow{Type: SymfonyWorkflowType}
+	opts := []DefinitionOption{WithName(s.name)}
+	var places []Place
+	var transitions []Transition
+	var initial []Place
+	var initialNode *yaml.Node
+	var err error
+
+	// The type and places are needed to read the transitions, whatever the key order
+	if value := mappingValue(node, "type"); value != nil {
+		switch t := SymfonyType(value.Value); t {
+		case SymfonyWorkflowType, SymfonyStateMachine:
+			w.Type = t
+		default:
+			return nil, yamlErrorf(value, "unknown workflow type %q", value.Value)
+		}
+	}
+	s.places = make(map[Place]bool)
+	if value := mappingValue(node, "places"); value != nil {
+		var placeOpts []DefinitionOption
+		if places, placeOpts, err = s.placeNodes(value); err != nil {
+			return nil, err
+		}
+		opts = append(opts, placeOpts...)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "type", "places":
+		case "marking_store":
+			if w.MarkingStore, err = s.markingStore(value); err != nil {
+				return nil, err
+			}
+		case "supports":
+			if w.Supports, err = yamlScalarList(value); err != nil {
+				return nil, err
+			}
+		case "initial_marking", "initial_place":
+			names, err := yamlScalarList(value)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range names {
+				initial = append(initial, Place(name))
+			}
+			initialNode = value
+		case "metadata":
+			meta, err := yamlMetadata(value)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithMetadata(meta))
+		case "transitions":
+			if transitions, err = s.transitions(value, w.Type); err != nil {
+				return nil, err
+			}
+		case "audit_trail", "events_to_dispatch", "support_strategy", "definition_validators", "enabled":
+			s.unsupported(key, key.Value, "setting is ignored")
+		default:
+			s.unsupported(key, key.Value, "unknown setting is ignored")
+		}
+	}
+
+	for _, place := range initial {
+		if !s.places[place] {
+			return nil, yamlErrorf(initialNode, "initial place '%s' is not defined in workflow places", place)
+		}
+	}
+	if len(initial) > 0 {
+		opts = append(opts, WithInitialPlaces(initial...))
+	}
+
+	definition, err := NewDefinition(places, transitions, opts...)
+	if err != nil {
+		return nil, &LoadError{Line: node.Line, Column: node.Column, Err: err}
+	}
+	w.Definition = definition
+	return w, nil
+}
+
+func (s *symfonyImporter) markingStore(node *yaml.Node) (*SymfonyMarkingStore, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, yamlErrorf(node, "marking_store must be a mapping")
+	}
+	store := &SymfonyMarkingStore{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "type":
+			store.Type = value.Value
+		case "property":
+			store.Property = value.Value
+		default:
+			s.unsupported(key, "marking_store."+key.Value, "setting is ignored")
+		}
+	}
+	return store, nil
+}
+
+// placeNodes decodes places given as a list of names, a list of mappings or a mapping by name
+func (s *symfonyImporter) placeNodes(node *yaml.Node) ([]Place, []DefinitionOption, error) {
+	var places []Place
+	var opts []DefinitionOption
+
+	add := func(nameNode, valueNode *yaml.Node) error {
+		place := Place(nameNode.Value)
+		if place == "" {
+			return yamlErrorf(nameNode, "place name cannot be empty")
+		}
+		if s.places[place] {
+			return yamlErrorf(nameNode, "duplicate place '%s'", place)
+		}
+		s.places[place] = true
+		places = append(places, place)
+		if valueNode == nil || valueNode.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(valueNode.Content); i += 2 {
+			key, value := valueNode.Content[i], valueNode.Content[i+1]
+			switch key.Value {
+			case "name":
+			case "metadata":
+				meta, err := yamlMetadata(value)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, WithPlaceMetadata(place, meta))
+			default:
+				s.unsupported(key, "places."+key.Value, "setting is ignored")
+			}
+		}
+		return nil
+	}
+
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind == yaml.ScalarNode {
+				if err := add(item, nil); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			nameNode := mappingValue(item, "name")
+			if nameNode == nil {
+				return nil, nil, yamlErrorf(item, "missing field \"name\"")
+			}
+			if err := add(nameNode, item); err != nil {
+				return nil, nil, err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := add(node.Content[i], node.Content[i+1]); err != nil {
+				return nil, nil, err
+			}
+		}
+	default:
+		return nil, nil, yamlErrorf(node, "places must be a list or a mapping")
+	}
+	return places, opts, nil
+}
+
+// transitions decodes transitions given as a list with names or a mapping by name.
+// State machine transitions with several source places are split into one
+// transition per source, as Symfony does.
+func (s *symfonyImporter) transitions(node *yaml.Node, workflowType SymfonyType) ([]Transition, error) {
+	type entry struct {
+		name  string
+		node  *yaml.Node
+		value *yaml.Node
+	}
+	var entries []entry
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			nameNode := mappingValue(item, "name")
+			if item.Kind != yaml.MappingNode || nameNode == nil {
+				return nil, yamlErrorf(item, "transition must be a mapping with a name")
+			}
+			entries = append(entries, entry{name: nameNode.Value, node: nameNode, value: item})
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			entries = append(entries, entry{name: node.Content[i].Value, node: node.Content[i], value: node.Content[i+1]})
+		}
+	default:
+		return nil, yamlErrorf(node, "transitions must be a list or a mapping")
+	}
+
+	var transitions []Transition
+	for _, e := range entries {
+		if e.value.Kind != yaml.MappingNode {
+			return nil, yamlErrorf(e.value, "transition %s must be a mapping", e.name)
+		}
+		var from, to []Place
+		var fromNode, toNode *yaml.Node
+		meta := map[string]interface{}{}
+		var constraint Constraint
+		for i := 0; i+1 < len(e.value.Content); i += 2 {
+			key, value := e.value.Content[i], e.value.Content[i+1]
+			switch key.Value {
+			case "name":
+			case "from", "to":
+				names, err := yamlScalarList(value)
+				if err != nil {
+					return nil, err
+				}
+				for _, name := range names {
+					if !s.places[Place(name)] {
+						return nil, yamlErrorf(value, "place '%s' in transition '%s' is not defined in workflow places", name, e.name)
+					}
+					if key.Value == "from" {
+						from = append(from, Place(name))
+					} else {
+						to = append(to, Place(name))
+					}
+				}
+				if key.Value == "from" {
+					fromNode = value
+				} else {
+					toNode = value
+				}
+			case "guard":
+				constraint = s.guard(value, e.name)
+			case "metadata":
+				var err error
+				if meta, err = yamlMetadata(value); err != nil {
+					return nil, err
+				}
+			default:
+				s.unsupported(key, "transitions."+key.Value, "setting is ignored")
+			}
+		}
+		if fromNode == nil || toNode == nil {
+			return nil, yamlErrorf(e.node, "transition %s must have from and to places", e.name)
+		}
+
+		sources := [][]Place{from}
+		if workflowType == SymfonyStateMachine && len(from) > 1 {
+			sources = nil
+			for _, place := range from {
+				sources = append(sources, []Place{place})
+			}
+		}
+		for _, source := range sources {
+			t, err := NewTransition(e.name, source, to)
+			if err != nil {
+				return nil, yamlErrorf(e.node, "%v", err)
+			}
+			for key, value := range meta {
+				t.SetMetadata(key, value)
+			}
+			if constraint != nil {
+				t.AddConstraint(constraint)
+			}
+			transitions = append(transitions, *t)
+		}
+	}
+	return transitions, nil
+}
+
+// guard translates a Symfony guard expression
+func (s *symfonyImporter) guard(node *yaml.Node, transition string) Constraint {
+	constraint, reason := translateGuard(node.Value, "Symfony guard")
+	if reason != "" {
+		s.unsupported(node, "guard", fmt.Sprintf("guard %q of transition '%s' %s; the transition is blocked",
+			node.Value, transition, reason))
+	}
+	return constraint
+}
+
+// ExportSymfony writes workflows as a Symfony framework.workflows configuration.
+// Features without a Symfony equivalent are left out and reported.
+func ExportSymfony(workflows ...*SymfonyWorkflow) ([]byte, []UnsupportedFeature, error) {
+	var unsupported []UnsupportedFeature
+	report := func(workflow, feature, detail string) {
+		unsupported = append(unsupported, UnsupportedFeature{Workflow: workflow, Feature: feature, Detail: detail})
+	}
+
+	list := &yaml.Node{Kind: yaml.MappingNode}
+	for _, w := range workflows {
+		d := w.Definition
+		name := d.Name()
+		if name == "" {
+			return nil, nil, fmt.Errorf("workflow definitions must be named to be exported")
+		}
+		workflowType := w.Type
+		if workflowType == "" {
+			workflowType = SymfonyWorkflowType
+		}
+
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		appendPair(node, "type", scalarNode(string(workflowType)))
+		if w.MarkingStore != nil {
+			store := &yaml.Node{Kind: yaml.MappingNode}
+			if w.MarkingStore.Type != "" {
+				appendPair(store, "type", scalarNode(w.MarkingStore.Type))
+			}
+			if w.MarkingStore.Property != "" {
+				appendPair(store, "property", scalarNode(w.MarkingStore.Property))
+			}
+			appendPair(node, "marking_store", store)
+		}
+		if len(w.Supports) > 0 {
+			appendPair(node, "supports", stringsNode(w.Supports))
+		}
+		if initial := d.InitialPlaces(); len(initial) > 0 {
+			appendPair(node, "initial_marking", placesNode(initial))
+		}
+		if len(d.metadata) > 0 {
+			meta, err := valueNode(d.AllMetadata())
+			if err != nil {
+				return nil, nil, err
+			}
+			appendPair(node, "metadata", meta)
+		}
+
+		places := &yaml.Node{Kind: yaml.MappingNode}
+		for _, place := range d.places {
+			value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "~"}
+			if len(d.placeMetadata[place]) > 0 {
+				meta, err := valueNode(d.AllPlaceMetadata(place))
+				if err != nil {
+					return nil, nil, err
+				}
+				value = &yaml.Node{Kind: yaml.MappingNode}
+				appendPair(value, "metadata", meta)
+			}
+			if len(d.requirements[place]) > 0 {
+				report(name, "requires", fmt.Sprintf("context requirements of place '%s' are not exported", place))
+			}
+			appendPair(places, string(place), value)
+		}
+		appendPair(node, "places", places)
+
+		transitions, err := symfonyTransitions(d, workflowType, func(feature, detail string) { report(name, feature, detail) })
+		if err != nil {
+			return nil, nil, err
+		}
+		appendPair(node, "transitions", transitions)
+
+		if len(d.finalPlaces) > 0 {
+			report(name, "final_places", "final places are not exported")
+		}
+		if len(d.invariants) > 0 {
+			report(name, "invariants", "invariants are not exported")
+		}
+		if len(d.listeners) > 0 {
+			report(name, "listeners", "listeners are not exported")
+		}
+		appendPair(list, name, node)
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	framework := &yaml.Node{Kind: yaml.MappingNode}
+	appendPair(framework, "workflows", list)
+	appendPair(root, "framework", framework)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(4)
+	if err := enc.Encode(root); err != nil {
+		return nil, nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), unsupported, nil
+}
+
+// symfonyTransitions builds the transitions mapping, merging state machine
+// transitions that were split per source place
+func symfonyTransitions(d *Definition, workflowType SymfonyType, report func(feature, detail string)) (*yaml.Node, error) {
+	type merged struct {
+		t    *Transition
+		from []Place
+	}
+	var order []*merged
+	byKey := make(map[string]*merged)
+	names := make(map[string]int)
+	for i := range d.transitions {
+		t := &d.transitions[i]
+		key := ""
+		if workflowType == SymfonyStateMachine && len(t.from) == 1 {
+			// Transitions sharing a name, targets, guard and metadata come from a single Symfony transition
+			key = fmt.Sprintf("%s\x00%s\x00%s\x00%v", t.name, joinPlaces(t.to), symfonyGuardSource(t), t.metadata)
+		}
+		if m, ok := byKey[key]; ok && key != "" {
+			m.from = append(m.from, t.from...)
+			continue
+		}
+		m := &merged{t: t, from: append([]Place(nil), t.from...)}
+		order = append(order, m)
+		if key != "" {
+			byKey[key] = m
+		}
+		names[t.name]++
+	}
+
+	// Use the mapping form unless transition names are repeated
+	useList := false
+	for _, count := range names {
+		if count > 1 {
+			useList = true
+		}
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	if useList {
+		node.Kind = yaml.SequenceNode
+	}
+	for _, m := range order {
+		t := m.t
+		value := &yaml.Node{Kind: yaml.MappingNode}
+		if useList {
+			appendPair(value, "name", scalarNode(t.name))
+		}
+		appendPair(value, "from", placesNode(m.from))
+		appendPair(value, "to", placesNode(t.to))
+		if guard := symfonyGuardSource(t); guard != "" {
+			appendPair(value, "guard", scalarNode(guard))
+		}
+		for _, c := range t.constraints {
+			switch c.(type) {
+			case *ExpressionConstraint, *foreignGuard:
+			default:
+				report("constraints", fmt.Sprintf("constraints of transition '%s' are not exported", t.name))
+			}
+		}
+		if t.inputSchema != nil {
+			report("input", fmt.Sprintf("input schema of transition '%s' is not exported", t.name))
+		}
+		if len(t.metadata) > 0 {
+			meta, err := valueNode(t.metadata)
+			if err != nil {
+				return nil, err
+			}
+			appendPair(value, "metadata", meta)
+		}
+		if useList {
+			node.Content = append(node.Content, value)
+		} else {
+			appendPair(node, t.name, value)
+		}
+	}
+	return node, nil
+}
+
+// symfonyGuardSource returns the guard expression of a transition, joining several guards with "and"
+func symfonyGuardSource(t *Transition) string {
+	var guards []string
+	for _, c := range t.constraints {
+		switch c := c.(type) {
+		case *ExpressionConstraint:
+			guards = append(guards, c.Expression())
+		case *foreignGuard:
+			guards = append(guards, c.source)
+		}
+	}
+	return joinGuards(guards, "and")
+}
+
+// appendPair appends a key and its value to a mapping node
+func appendPair(node *yaml.Node, key string, value *yaml.Node) {
+	node.Content = append(node.Content, scalarNode(key), value)
+}
+
+// scalarNode returns a string scalar node
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// stringsNode returns a flow sequence of strings
+func stringsNode(values []string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+	for _, value := range values {
+		node.Content = append(node.Content, scalarNode(value))
+	}
+	return node
+}
+
+// placesNode returns a single place as a scalar and several places as a flow sequence
+func placesNode(places []Place) *yaml.Node {
+	if len(places) == 1 {
+		return scalarNode(string(places[0]))
+	}
+	values := make([]string, len(places))
+	for i, place := range places {
+		values[i] = string(place)
+	}
+	return stringsNode(values)
+}
+
+// valueNode encodes a value as a node with sorted mapping keys
+func valueNode(value interface{}) (*yaml.Node, error) {
+	if m, ok := value.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, key := range keys {
+			v, err := valueNode(m[key])
+			if err != nil {
+				return nil, err
+			}
+			appendPair(node, key, v)
+		}
+		return node, nil
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
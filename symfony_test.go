@@ -0,0 +1,184 @@
+package workflow_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+const symfonyYAML = `framework:
+    workflows:
+        blog_publishing:
+            type: 'workflow'
+            audit_trail:
+                enabled: true
+            marking_store:
+                type: 'method'
+                property: 'currentPlace'
+            supports:
+                - App\Entity\BlogPost
+            initial_marking: draft
+            metadata:
+                title: 'Blog Publishing Workflow'
+            places:
+                draft: ~
+                reviewed:
+                    metadata:
+                        max_committed_hours: 8
+                rejected: ~
+                published: ~
+            transitions:
+                to_review:
+                    from: draft
+                    to: reviewed
+                    metadata:
+                        priority: 0.5
+                publish:
+                    from: reviewed
+                    to: published
+                    guard: "context.words > 100"
+                reject:
+                    from: reviewed
+                    to: rejected
+                    guard: "is_granted('ROLE_ADMIN')"
+        pull_request:
+            type: state_machine
+            places: [start, coding, review, merged]
+            initial_marking: start
+            transitions:
+                - name: submit
+                  from: start
+                  to: review
+                - name: update
+                  from: [coding, review]
+                  to: review
+                - name: merge
+                  from: review
+                  to: merged
+`
+
+func TestImportSymfony(t *testing.T) {
+	result, err := workflow.ImportSymfony([]byte(symfonyYAML))
+	if err != nil {
+		t.Fatalf("ImportSymfony() error = %v", err)
+	}
+	if len(result.Workflows) != 2 {
+		t.Fatalf("expected 2 workflows, got %d", len(result.Workflows))
+	}
+
+	blog, ok := result.Workflow("blog_publishing")
+	if !ok {
+		t.Fatal("blog_publishing not imported")
+	}
+	def := blog.Definition
+	if blog.Type != workflow.SymfonyWorkflowType || blog.MarkingStore.Property != "currentPlace" || blog.Supports[0] != `App\Entity\BlogPost` {
+		t.Errorf("unexpected settings %+v", blog)
+	}
+	if initial := def.InitialPlaces(); len(initial) != 1 || initial[0] != "draft" {
+		t.Errorf("initial places = %v", initial)
+	}
+	if title, _ := def.Metadata("title"); title != "Blog Publishing Workflow" {
+		t.Errorf("title = %v", title)
+	}
+	if hours, _ := def.PlaceMetadata("reviewed", "max_committed_hours"); hours != 8 {
+		t.Errorf("max_committed_hours = %v", hours)
+	}
+
+	var features []string
+	for _, f := range result.Unsupported {
+		features = append(features, f.Feature)
+	}
+	if strings.Join(features, ",") != "audit_trail,guard" {
+		t.Errorf("unsupported features = %v", result.Unsupported)
+	}
+
+	wf, err := workflow.NewWorkflow("post", def, "draft")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	if err := wf.Apply([]workflow.Place{"reviewed"}); err != nil {
+		t.Fatalf("Apply(reviewed) error = %v", err)
+	}
+	// Untranslated guards block their transition
+	if err := wf.Can([]workflow.Place{"rejected"}); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("expected the untranslated guard to block, got %v", err)
+	}
+	wf.SetContext("words", 50)
+	if err := wf.Can([]workflow.Place{"published"}); !errors.Is(err, workflow.ErrTransitionNotAllowed) {
+		t.Errorf("expected the guard to block, got %v", err)
+	}
+	wf.SetContext("words", 500)
+	if err := wf.Can([]workflow.Place{"published"}); err != nil {
+		t.Errorf("Can(published) error = %v", err)
+	}
+
+	pr, _ := result.Workflow("pull_request")
+	if pr.Type != workflow.SymfonyStateMachine {
+		t.Errorf("type = %v", pr.Type)
+	}
+	// State machine transitions with several sources are split
+	if got := len(pr.Definition.AllTransitions()); got != 4 {
+		t.Errorf("expected 4 transitions, got %d", got)
+	}
+}
+
+func TestSymfony_RoundTrip(t *testing.T) {
+	result, err := workflow.ImportSymfony([]byte(symfonyYAML))
+	if err != nil {
+		t.Fatalf("ImportSymfony() error = %v", err)
+	}
+	data, unsupported, err := workflow.ExportSymfony(result.Workflows...)
+	if err != nil {
+		t.Fatalf("ExportSymfony() error = %v", err)
+	}
+	if len(unsupported) != 0 {
+		t.Errorf("unexpected unsupported features on export: %v", unsupported)
+	}
+
+	again, err := workflow.ImportSymfony(data)
+	if err != nil {
+		t.Fatalf("ImportSymfony() of exported data error = %v\n%s", err, data)
+	}
+	for i, w := range result.Workflows {
+		if again.Workflows[i].Definition.Fingerprint() != w.Definition.Fingerprint() {
+			t.Errorf("%s changed after round trip:\n%s", w.Definition.Name(), data)
+		}
+	}
+	if !strings.Contains(string(data), "from: [coding, review]") {
+		t.Errorf("split state machine transitions were not merged:\n%s", data)
+	}
+}
+
+func TestExportSymfony_Unsupported(t *testing.T) {
+	def, err := workflow.NewBuilder("article").
+		Place("draft", "review").
+		FinalPlace("review").
+		Transition("submit").From("draft").To("review").
+		Input(workflow.InputField{Name: "comment", Type: workflow.FieldString}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	_, unsupported, err := workflow.ExportSymfony(&workflow.SymfonyWorkflow{Definition: def})
+	if err != nil {
+		t.Fatalf("ExportSymfony() error = %v", err)
+	}
+	var features []string
+	for _, f := range unsupported {
+		features = append(features, f.Feature)
+	}
+	if strings.Join(features, ",") != "input,final_places" {
+		t.Errorf("unsupported features = %v", features)
+	}
+}
+
+func TestImportSymfony_Errors(t *testing.T) {
+	data := "workflows:\n  w:\n    places: [a]\n    transitions:\n      t:\n        from: a\n        to: b\n"
+	_, err := workflow.ImportSymfony([]byte(data))
+	var loadErr *workflow.LoadError
+	if !errors.As(err, &loadErr) || loadErr.Line != 7 {
+		t.Errorf("expected a load error on line 7, got %v", err)
+	}
+}
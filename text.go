@@ -0,0 +1,192 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextOptions configures text rendering
+type TextOptions struct {
+	// ASCII only uses ASCII characters, for terminals and logs without Unicode
+	ASCII bool
+	// Color highlights the current places with ANSI escape codes
+	Color bool
+	// RenderOptions sets the places to highlight and the instance counts,
+	// listed with the flags of each place. Metadata lists the place metadata
+	// below each place.
+	RenderOptions
+}
+
+// textSymbols are the characters used to draw a text rendering
+type textSymbols struct {
+	place, current, final, currentFinal string
+	branch, last, pipe                  string
+	arrow, fork, forkBranch, forkLast   string
+	loop, rule                          string
+}
+
+var (
+	unicodeSymbols = textSymbols{
+		place: "○", current: "●", final: "◎", currentFinal: "◉",
+		branch: "├─ ", last: "└─ ", pipe: "│  ",
+		arrow: " ─▶ ", fork: " ─┬─▶ ", forkBranch: "  ├─▶ ", forkLast: "  └─▶ ",
+		loop: " ↺", rule: "──",
+	}
+	asciiSymbols = textSymbols{
+		place: "o", current: "*", final: "O", currentFinal: "@",
+		branch: "|- ", last: "`- ", pipe: "|  ",
+		arrow: " --> ", fork: " -+-> ", forkBranch: "  |-> ", forkLast: "  `-> ",
+		loop: " (loop)", rule: "--",
+	}
+)
+
+// Text renders the definition as text for terminals and logs. Places are
+// listed by layer, from the initial places, each followed by the transitions
+// leaving it. Forks branch to each of their places, joins are listed under
+// their first input place, and transitions going back to an earlier layer
+// are marked as loops.
+func (d *Definition) Text(opts TextOptions) string {
+	return d.text(opts, d.initialPlaces, nil)
+}
+
+// Text renders the workflow as text for terminals and logs, marking its
+// current places
+func (w *Workflow) Text(opts TextOptions) string {
+	return w.Definition().text(opts, []Place{w.InitialPlace()}, w.CurrentPlaces())
+}
+
+// text renders the definition with the given initial and current places
+func (d *Definition) text(opts TextOptions, initial, current []Place) string {
+	symbols := unicodeSymbols
+	if opts.ASCII {
+		symbols = asciiSymbols
+	}
+	highlighted := opts.highlighted(current)
+	final := placeSet(d.finalPlaces)
+	initialSet := placeSet(initial)
+
+	// Reuse the SVG layout to group the places in layers
+	nodes, edges := d.svgGraph(SVGOptions{}, initial)
+	svgLayout(nodes, edges)
+	layerOf := make(map[Place]int)
+	var places []svgNode
+	for _, node := range nodes {
+		if node.kind == svgPlace {
+			layerOf[node.place] = node.layer
+			places = append(places, node)
+		}
+	}
+	sort.SliceStable(places, func(i, j int) bool {
+		if places[i].layer != places[j].layer {
+			return places[i].layer < places[j].layer
+		}
+		return places[i].order < places[j].order
+	})
+
+	// Each transition is listed under its first input place
+	leaving := make(map[Place][]Transition)
+	for _, t := range d.transitions {
+		leaving[t.From()[0]] = append(leaving[t.From()[0]], t)
+	}
+
+	var text strings.Builder
+	title, ok := metadataString(d.metadata, MetadataLabel)
+	if !ok {
+		title = d.name
+	}
+	if title != "" {
+		text.WriteString(title + "\n")
+	}
+
+	layer, rank := -1, 0
+	for _, node := range places {
+		place := node.place
+		if node.layer != layer {
+			layer = node.layer
+			rank++
+			if text.Len() > 0 {
+				text.WriteString("\n")
+			}
+			text.WriteString(fmt.Sprintf("%s layer %d %s\n", symbols.rule, rank, symbols.rule))
+		}
+
+		symbol := symbols.place
+		switch {
+		case highlighted[place] && final[place]:
+			symbol = symbols.currentFinal
+		case highlighted[place]:
+			symbol = symbols.current
+		case final[place]:
+			symbol = symbols.final
+		}
+		line := symbol + " " + string(place)
+		if label, ok := metadataString(d.placeMetadata[place], MetadataLabel); ok && label != string(place) {
+			line += fmt.Sprintf(" %q", label)
+		}
+		var flags []string
+		if initialSet[place] {
+			flags = append(flags, "initial")
+		}
+		if final[place] {
+			flags = append(flags, "final")
+		}
+		if highlighted[place] {
+			flags = append(flags, "current")
+		}
+		if count, ok := opts.instanceCount(place); ok {
+			flags = append(flags, count)
+		}
+		if len(flags) > 0 {
+			line += " (" + strings.Join(flags, ", ") + ")"
+		}
+		if opts.Color && highlighted[place] {
+			line = "\x1b[1;32m" + line + "\x1b[0m"
+		}
+		text.WriteString(line + "\n")
+
+		if opts.Metadata {
+			for _, meta := range metadataLines(d.placeMetadata[place]) {
+				text.WriteString("    " + meta + "\n")
+			}
+		}
+
+		transitions := leaving[place]
+		for i, t := range transitions {
+			branch, pipe := symbols.branch, symbols.pipe
+			if i == len(transitions)-1 {
+				branch, pipe = symbols.last, "   "
+			}
+			target := func(to Place) string {
+				s := string(to)
+				if layerOf[to] <= layer {
+					s += symbols.loop
+				}
+				return s
+			}
+			join := ""
+			if len(t.From()) > 1 {
+				join = fmt.Sprintf("  (join: %s)", joinPlaces(t.From()))
+			}
+			if len(t.To()) == 1 {
+				text.WriteString("  " + branch + t.Name() + symbols.arrow + target(t.To()[0]) + join + "\n")
+				continue
+			}
+			indent := strings.Repeat(" ", utf8.RuneCountInString(t.Name()))
+			for j, to := range t.To() {
+				switch j {
+				case 0:
+					text.WriteString("  " + branch + t.Name() + symbols.fork + target(to) + join + "\n")
+				case len(t.To()) - 1:
+					text.WriteString("  " + pipe + indent + symbols.forkLast + target(to) + "\n")
+				default:
+					text.WriteString("  " + pipe + indent + symbols.forkBranch + target(to) + "\n")
+				}
+			}
+		}
+	}
+
+	text.WriteString(fmt.Sprintf("\n%s current  %s place  %s final\n", symbols.current, symbols.place, symbols.final))
+	return text.String()
+}
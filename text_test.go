@@ -0,0 +1,106 @@
+package workflow_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+var orderTextDefinition = workflow.NewBuilder("order").
+	Place("new", "paid", "packed", "shipped", "cancelled").
+	InitialPlace("new").
+	FinalPlace("shipped", "cancelled").
+	PlaceMetadata("paid", workflow.MetadataLabel, "Paid").
+	PlaceMetadata("paid", "sla", "1h").
+	Transition("pay").From("new").To("paid", "packed").
+	Transition("ship").From("paid", "packed").To("shipped").
+	Transition("cancel").From("new").To("cancelled").
+	Transition("retry").From("paid").To("new").
+	MustBuild()
+
+func TestDefinition_Text(t *testing.T) {
+	tests := []struct {
+		name string
+		opts workflow.TextOptions
+		want string
+	}{
+		{
+			name: "unicode",
+			opts: workflow.TextOptions{},
+			want: `order
+
+── layer 1 ──
+○ new (initial)
+  ├─ pay ─┬─▶ paid
+  │       └─▶ packed
+  └─ cancel ─▶ cancelled
+
+── layer 2 ──
+○ paid "Paid"
+  ├─ ship ─▶ shipped  (join: paid, packed)
+  └─ retry ─▶ new ↺
+○ packed
+◎ cancelled (final)
+
+── layer 3 ──
+◎ shipped (final)
+
+● current  ○ place  ◎ final
+`,
+		},
+		{
+			name: "ascii with metadata",
+			opts: workflow.TextOptions{ASCII: true, RenderOptions: workflow.RenderOptions{Metadata: true, Highlight: []workflow.Place{"shipped"}, Counts: map[workflow.Place]int{"shipped": 4}}},
+			want: `order
+
+-- layer 1 --
+o new (initial)
+  |- pay -+-> paid
+  |       ` + "`" + `-> packed
+  ` + "`" + `- cancel --> cancelled
+
+-- layer 2 --
+o paid "Paid"
+    sla: 1h
+  |- ship --> shipped  (join: paid, packed)
+  ` + "`" + `- retry --> new (loop)
+o packed
+O cancelled (final)
+
+-- layer 3 --
+@ shipped (final, current, 4 instances)
+
+* current  o place  O final
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderTextDefinition.Text(tt.opts); got != tt.want {
+				t.Errorf("Text() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkflow_Text(t *testing.T) {
+	wf, err := workflow.NewWorkflow("o1", orderTextDefinition, "new")
+	if err != nil {
+		t.Fatalf("NewWorkflow() error = %v", err)
+	}
+	if err := wf.Apply([]workflow.Place{"paid", "packed"}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	got := wf.Text(workflow.TextOptions{Color: true})
+	for _, want := range []string{
+		"○ new (initial)\n",
+		"\x1b[1;32m● paid \"Paid\" (current)\x1b[0m\n",
+		"\x1b[1;32m● packed (current)\x1b[0m\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Text() does not contain %q:\n%s", want, got)
+		}
+	}
+}
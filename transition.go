@@ -11,6 +11,7 @@ type Transition struct {
 	to          []Place
 	metadata    map[string]interface{}
 	constraints []Constraint
+	inputSchema *InputSchema
 }
 
 // Constraint represents a validation constraint for a transition
@@ -18,6 +19,14 @@ type Constraint interface {
 	Validate(Event) error
 }
 
+// ConstraintFunc adapts a function to the Constraint interface
+type ConstraintFunc func(Event) error
+
+// Validate implements Constraint
+func (f ConstraintFunc) Validate(event Event) error {
+	return f(event)
+}
+
 // NewTransition creates a new transition
 func NewTransition(name string, from []Place, to []Place) (*Transition, error) {
 	if name == "" {
@@ -96,6 +105,27 @@ func (t *Transition) Metadata(key string) (interface{}, bool) {
 	return value, ok
 }
 
+// SetInputSchema sets the schema of the payload accepted by the transition
+func (t *Transition) SetInputSchema(schema *InputSchema) {
+	t.inputSchema = schema
+}
+
+// InputSchema returns the schema of the payload accepted by the transition, or nil
+func (t *Transition) InputSchema() *InputSchema {
+	return t.inputSchema
+}
+
+// validatePayload validates the payload against the input schema (internal method)
+func (t *Transition) validatePayload(payload map[string]interface{}) error {
+	if t.inputSchema == nil {
+		return nil
+	}
+	if violations := t.inputSchema.Validate(payload); len(violations) > 0 {
+		return &PayloadError{Transition: t.name, Violations: violations}
+	}
+	return nil
+}
+
 // validate validates the transition against all constraints (internal method)
 func (t *Transition) validate(event Event) error {
 	for _, constraint := range t.constraints {
@@ -106,6 +136,26 @@ func (t *Transition) validate(event Event) error {
 	return nil
 }
 
+// clone returns a deep copy of the transition, so that definitions do not share
+// slices and metadata with the transitions they were created from
+func (t *Transition) clone() Transition {
+	c := Transition{
+		name:        t.name,
+		from:        append([]Place(nil), t.from...),
+		to:          append([]Place(nil), t.to...),
+		metadata:    copyMetadata(t.metadata),
+		constraints: append([]Constraint(nil), t.constraints...),
+	}
+	if t.inputSchema != nil {
+		c.inputSchema = &InputSchema{Fields: make([]InputField, len(t.inputSchema.Fields))}
+		for i, f := range t.inputSchema.Fields {
+			f.Enum = append([]interface{}(nil), f.Enum...)
+			c.inputSchema.Fields[i] = f
+		}
+	}
+	return c
+}
+
 // MustNewTransition is a helper that creates a new transition and panics on error.
 // This is useful for defining transitions in a declarative way.
 func MustNewTransition(name string, from []Place, to []Place) *Transition {
@@ -0,0 +1,250 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// DefaultSubjectKey is the context key under which a TypedManager persists the encoded subject
+const DefaultSubjectKey = "subject"
+
+// TypedWorkflow binds a workflow instance to a typed domain subject
+type TypedWorkflow[T any] struct {
+	*Workflow
+
+	subject T
+	mu      sync.RWMutex
+}
+
+// TypedEvent wraps a workflow event and exposes the typed subject
+type TypedEvent[T any] struct {
+	Event
+	subject T
+}
+
+// Subject returns the subject of the workflow that fired the event
+func (e *TypedEvent[T]) Subject() T {
+	return e.subject
+}
+
+// TypedGuardEvent wraps a guard event and exposes the typed subject
+type TypedGuardEvent[T any] struct {
+	*GuardEvent
+	subject T
+}
+
+// Subject returns the subject of the workflow that fired the guard event
+func (e *TypedGuardEvent[T]) Subject() T {
+	return e.subject
+}
+
+// TypedEventListener is a function that handles typed workflow events
+type TypedEventListener[T any] func(*TypedEvent[T]) error
+
+// TypedGuardEventListener is a function that handles typed guard events
+type TypedGuardEventListener[T any] func(*TypedGuardEvent[T]) error
+
+// NewTypedWorkflow creates a new workflow instance bound to the given subject
+func NewTypedWorkflow[T any](name string, definition *Definition, initialPlace Place, subject T) (*TypedWorkflow[T], error) {
+	wf, err := NewWorkflow(name, definition, initialPlace)
+	if err != nil {
+		return nil, err
+	}
+	return WrapWorkflow(wf, subject), nil
+}
+
+// WrapWorkflow binds an existing workflow instance to the given subject
+func WrapWorkflow[T any](wf *Workflow, subject T) *TypedWorkflow[T] {
+	return &TypedWorkflow[T]{
+		Workflow: wf,
+		subject:  subject,
+	}
+}
+
+// Subject returns the subject bound to the workflow
+func (w *TypedWorkflow[T]) Subject() T {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.subject
+}
+
+// SetSubject replaces the subject bound to the workflow
+func (w *TypedWorkflow[T]) SetSubject(subject T) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subject = subject
+}
+
+// AddEventListener adds a typed event listener for a specific event type
+func (w *TypedWorkflow[T]) AddEventListener(eventType EventType, listener TypedEventListener[T]) {
+	w.Workflow.AddEventListener(eventType, func(event Event) error {
+		return listener(&TypedEvent[T]{Event: event, subject: w.Subject()})
+	})
+}
+
+// AddGuardEventListener adds a typed guard event listener
+func (w *TypedWorkflow[T]) AddGuardEventListener(listener TypedGuardEventListener[T]) {
+	w.Workflow.AddGuardEventListener(func(event *GuardEvent) error {
+		return listener(&TypedGuardEvent[T]{GuardEvent: event, subject: w.Subject()})
+	})
+}
+
+// Codec encodes and decodes workflow subjects for persistence
+type Codec[T any] interface {
+	// Encode serializes the subject
+	Encode(subject T) ([]byte, error)
+	// Decode deserializes a subject previously produced by Encode
+	Decode(data []byte) (T, error)
+}
+
+// JSONCodec is a Codec that serializes subjects as JSON
+type JSONCodec[T any] struct{}
+
+// Encode implements Codec
+func (JSONCodec[T]) Encode(subject T) ([]byte, error) {
+	return json.Marshal(subject)
+}
+
+// Decode implements Codec
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var subject T
+	err := json.Unmarshal(data, &subject)
+	return subject, err
+}
+
+// TypedManager handles typed workflow instances and persists their subjects
+// through a codec. The encoded subject is stored in the workflow context under
+// the subject key, so storage backends must persist that key (for example as a
+// custom field of SQLiteStorage).
+type TypedManager[T any] struct {
+	*Manager
+
+	codec      Codec[T]
+	subjectKey string
+	workflows  map[string]*TypedWorkflow[T]
+	mu         sync.RWMutex
+}
+
+// NewTypedManager creates a typed manager on top of an existing manager
+func NewTypedManager[T any](manager *Manager, codec Codec[T]) *TypedManager[T] {
+	if codec == nil {
+		codec = JSONCodec[T]{}
+	}
+	return &TypedManager[T]{
+		Manager:    manager,
+		codec:      codec,
+		subjectKey: DefaultSubjectKey,
+		workflows:  make(map[string]*TypedWorkflow[T]),
+	}
+}
+
+// SetSubjectKey sets the context key used to persist the encoded subject
+func (m *TypedManager[T]) SetSubjectKey(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subjectKey = key
+}
+
+// SubjectKey returns the context key used to persist the encoded subject
+func (m *TypedManager[T]) SubjectKey() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.subjectKey
+}
+
+// CreateWorkflow creates a new typed workflow instance and saves it to storage
+func (m *TypedManager[T]) CreateWorkflow(id string, definition *Definition, initialPlace Place, subject T) (*TypedWorkflow[T], error) {
+	wf, err := NewTypedWorkflow(id, definition, initialPlace, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workflow: %w", err)
+	}
+	wf.SetManager(m.Manager)
+
+	if err := m.SaveWorkflow(id, wf); err != nil {
+		return nil, fmt.Errorf("failed to save initial state: %w", err)
+	}
+
+	m.registry.AddWorkflow(wf.Workflow)
+	m.mu.Lock()
+	m.workflows[id] = wf
+	m.mu.Unlock()
+	return wf, nil
+}
+
+// SaveWorkflow encodes the subject into the workflow context and saves the state to storage
+func (m *TypedManager[T]) SaveWorkflow(id string, wf *TypedWorkflow[T]) error {
+	data, err := m.codec.Encode(wf.Subject())
+	if err != nil {
+		return fmt.Errorf("failed to encode subject: %w", err)
+	}
+	wf.SetContext(m.SubjectKey(), string(data))
+	return m.Manager.SaveWorkflow(id, wf.Workflow)
+}
+
+// LoadWorkflow loads a typed workflow instance and decodes its subject from storage
+func (m *TypedManager[T]) LoadWorkflow(id string, definition *Definition) (*TypedWorkflow[T], error) {
+	m.mu.RLock()
+	wf, ok := m.workflows[id]
+	m.mu.RUnlock()
+	if ok {
+		return wf, nil
+	}
+
+	untyped, err := m.Manager.LoadWorkflow(id, definition)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := untyped.Context(m.SubjectKey())
+	if !ok || raw == nil {
+		return nil, fmt.Errorf("workflow %s has no stored subject", id)
+	}
+	var data []byte
+	switch v := raw.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return nil, fmt.Errorf("unexpected type %T for stored subject", raw)
+	}
+	subject, err := m.codec.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode subject: %w", err)
+	}
+
+	wf = WrapWorkflow(untyped, subject)
+	m.mu.Lock()
+	m.workflows[id] = wf
+	m.mu.Unlock()
+	return wf, nil
+}
+
+// GetWorkflow gets a typed workflow instance from memory or loads it from storage
+func (m *TypedManager[T]) GetWorkflow(id string, definition *Definition) (*TypedWorkflow[T], error) {
+	return m.LoadWorkflow(id, definition)
+}
+
+// DeleteWorkflow removes a typed workflow instance and its state
+func (m *TypedManager[T]) DeleteWorkflow(id string) error {
+	m.mu.Lock()
+	delete(m.workflows, id)
+	m.mu.Unlock()
+	return m.Manager.DeleteWorkflow(id)
+}
+
+// Migrate migrates stored instances like Manager.Migrate and drops the migrated
+// typed instances from memory so that they are reloaded with the new version
+func (m *TypedManager[T]) Migrate(from, to DefinitionRef, mapping PlaceMapping, opts MigrationOptions) (*MigrationReport, error) {
+	report, err := m.Manager.Migrate(from, to, mapping, opts)
+	if err != nil || report.DryRun {
+		return report, err
+	}
+	m.mu.Lock()
+	for _, migration := range report.Migrated {
+		delete(m.workflows, migration.ID)
+	}
+	m.mu.Unlock()
+	return report, nil
+}
@@ -0,0 +1,162 @@
+package workflow_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+type testOrder struct {
+	ID     string  `json:"id"`
+	Amount float64 `json:"amount"`
+}
+
+// memoryStorage implements the Storage interface in memory for testing
+type memoryStorage struct {
+	states   map[string][]workflow.Place
+	contexts map[string]map[string]interface{}
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		states:   make(map[string][]workflow.Place),
+		contexts: make(map[string]map[string]interface{}),
+	}
+}
+
+func (m *memoryStorage) LoadState(id string) ([]workflow.Place, map[string]interface{}, error) {
+	places, ok := m.states[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("workflow not found")
+	}
+	ctx := m.contexts[id]
+	if ctx == nil {
+		ctx = map[string]interface{}{}
+	}
+	return places, ctx, nil
+}
+
+func (m *memoryStorage) SaveState(id string, places []workflow.Place, context map[string]interface{}) error {
+	m.states[id] = places
+	if context == nil {
+		context = map[string]interface{}{}
+	}
+	m.contexts[id] = context
+	return nil
+}
+
+func (m *memoryStorage) DeleteState(id string) error {
+	delete(m.states, id)
+	delete(m.contexts, id)
+	return nil
+}
+
+var typedOrderDefinition = workflow.NewBuilder("order").
+	Place("new", "paid").
+	Transition("pay").From("new").To("paid").
+	MustBuild()
+
+func TestTypedWorkflow_Events(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    *testOrder
+		wantErr  error
+		wantSeen string
+	}{
+		{name: "allowed", order: &testOrder{ID: "1", Amount: 10}, wantSeen: "1"},
+		{name: "blocked by guard", order: &testOrder{ID: "2"}, wantErr: workflow.ErrTransitionNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf, err := workflow.NewTypedWorkflow("order-"+tt.order.ID, typedOrderDefinition, "new", tt.order)
+			if err != nil {
+				t.Fatalf("failed to create workflow: %v", err)
+			}
+
+			var seen string
+			wf.AddEventListener(workflow.EventAfterTransition, func(event *workflow.TypedEvent[*testOrder]) error {
+				seen = event.Subject().ID
+				return nil
+			})
+			wf.AddGuardEventListener(func(event *workflow.TypedGuardEvent[*testOrder]) error {
+				if event.Subject().Amount <= 0 {
+					event.SetBlocking(true)
+				}
+				return nil
+			})
+
+			if err := wf.Apply([]workflow.Place{"paid"}); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if seen != tt.wantSeen {
+				t.Errorf("listener subject ID = %q, want %q", seen, tt.wantSeen)
+			}
+		})
+	}
+}
+
+func TestTypedManager_LoadWorkflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		save    func(t *testing.T, storage *memoryStorage)
+		want    testOrder
+		wantErr string
+	}{
+		{
+			name: "saved subject",
+			save: func(t *testing.T, storage *memoryStorage) {
+				manager := workflow.NewTypedManager[testOrder](workflow.NewManager(workflow.NewRegistry(), storage), workflow.JSONCodec[testOrder]{})
+				wf, err := manager.CreateWorkflow("order-1", typedOrderDefinition, "new", testOrder{ID: "1", Amount: 42})
+				if err != nil {
+					t.Fatalf("CreateWorkflow() error = %v", err)
+				}
+				if err := wf.Apply([]workflow.Place{"paid"}); err != nil {
+					t.Fatalf("Apply() error = %v", err)
+				}
+				wf.SetSubject(testOrder{ID: "1", Amount: 50})
+				if err := manager.SaveWorkflow("order-1", wf); err != nil {
+					t.Fatalf("SaveWorkflow() error = %v", err)
+				}
+			},
+			want: testOrder{ID: "1", Amount: 50},
+		},
+		{
+			name: "no stored subject",
+			save: func(t *testing.T, storage *memoryStorage) {
+				if err := storage.SaveState("order-1", []workflow.Place{"new"}, nil); err != nil {
+					t.Fatalf("SaveState() error = %v", err)
+				}
+			},
+			wantErr: fmt.Sprintf("workflow %s has no stored subject", "order-1"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newMemoryStorage()
+			tt.save(t, storage)
+
+			// Load through a fresh manager to force decoding from storage
+			manager := workflow.NewTypedManager[testOrder](workflow.NewManager(workflow.NewRegistry(), storage), nil)
+			loaded, err := manager.LoadWorkflow("order-1", typedOrderDefinition)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("LoadWorkflow() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadWorkflow() error = %v", err)
+			}
+			if got := loaded.Subject(); got != tt.want {
+				t.Errorf("Subject() = %+v, want %+v", got, tt.want)
+			}
+			if places := loaded.CurrentPlaces(); len(places) != 1 || places[0] != "paid" {
+				t.Errorf("CurrentPlaces() = %v, want [paid]", places)
+			}
+		})
+	}
+}
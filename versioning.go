@@ -0,0 +1,235 @@
+package workflow
+
+import "fmt"
+
+// DefinitionRef identifies a version of a named definition
+type DefinitionRef struct {
+	Name    string
+	Version string
+}
+
+// String returns the reference in the form "name@version"
+func (r DefinitionRef) String() string {
+	return r.Name + "@" + r.Version
+}
+
+// VersionedStorage is implemented by storages that record which definition
+// version each workflow instance was created with
+type VersionedStorage interface {
+	Storage
+
+	// LoadVersionedState loads the workflow state together with its definition reference.
+	// The reference is empty for instances saved without one.
+	LoadVersionedState(id string) (ref DefinitionRef, places []Place, context map[string]interface{}, err error)
+
+	// SaveVersionedState saves the workflow state together with its definition reference.
+	SaveVersionedState(id string, ref DefinitionRef, places []Place, context map[string]interface{}) error
+
+	// ListInstances returns the IDs of the workflows stored with the given definition reference.
+	ListInstances(ref DefinitionRef) ([]string, error)
+}
+
+// WithVersion sets an explicit version for the definition. Without it, the
+// definition fingerprint is used as its version.
+func WithVersion(version string) DefinitionOption {
+	return func(d *Definition) {
+		d.version = version
+	}
+}
+
+// Version returns the version of the definition
+func (d *Definition) Version() string {
+	if d.version != "" {
+		return d.version
+	}
+	return d.fingerprint
+}
+
+// Ref returns the reference identifying this version of the definition
+func (d *Definition) Ref() DefinitionRef {
+	return DefinitionRef{Name: d.name, Version: d.Version()}
+}
+
+// RegisterDefinition registers a definition version with the manager so that
+// instances stored with it are loaded with the right definition. The definition
+// must be named; registering different content under an existing version fails.
+func (m *Manager) RegisterDefinition(definition *Definition) error {
+	if definition == nil {
+		return fmt.Errorf("definition cannot be nil")
+	}
+	if definition.Name() == "" {
+		return fmt.Errorf("definition must have a name to be registered")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.registerDefinition(definition)
+}
+
+// registerDefinition registers a definition; the caller must hold m.mu
+func (m *Manager) registerDefinition(definition *Definition) error {
+	ref := definition.Ref()
+	for _, existing := range m.definitions[ref.Name] {
+		if existing.Version() != ref.Version {
+			continue
+		}
+		if existing.Fingerprint() != definition.Fingerprint() {
+			return fmt.Errorf("definition %s is already registered with different content", ref)
+		}
+		return nil
+	}
+	if m.definitions == nil {
+		m.definitions = make(map[string][]*Definition)
+	}
+	m.definitions[ref.Name] = append(m.definitions[ref.Name], definition)
+	return nil
+}
+
+// Definition returns a registered definition version
+func (m *Manager) Definition(ref DefinitionRef) (*Definition, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, definition := range m.definitions[ref.Name] {
+		if definition.Version() == ref.Version {
+			return definition, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrDefinitionNotFound, ref)
+}
+
+// Definitions returns the registered versions of a named definition, in registration order
+func (m *Manager) Definitions(name string) []*Definition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*Definition(nil), m.definitions[name]...)
+}
+
+// PlaceMapping maps places of a definition version to places of another version.
+// A place mapped to an empty list is dropped from the marking. Places without an
+// entry keep their name when the target version defines them.
+type PlaceMapping map[Place][]Place
+
+// MigrationOptions configures a migration
+type MigrationOptions struct {
+	// DryRun computes the migration report without saving anything
+	DryRun bool
+	// IDs restricts the migration to the given instances. Defaults to every
+	// instance stored with the source version.
+	IDs []string
+}
+
+// InstanceMigration describes the migration of a single workflow instance
+type InstanceMigration struct {
+	ID   string
+	From []Place
+	To   []Place
+	Err  error
+}
+
+// MigrationReport describes the outcome of a migration
+type MigrationReport struct {
+	From     DefinitionRef
+	To       DefinitionRef
+	DryRun   bool
+	Migrated []InstanceMigration
+	Failed   []InstanceMigration
+}
+
+// Migrate moves workflow instances from one registered definition version to
+// another, rewriting their markings with the place mapping. Instances whose
+// marking cannot be mapped are reported as failed and left untouched. The
+// storage must implement VersionedStorage.
+func (m *Manager) Migrate(from, to DefinitionRef, mapping PlaceMapping, opts MigrationOptions) (*MigrationReport, error) {
+	storage, ok := m.storage.(VersionedStorage)
+	if !ok {
+		return nil, fmt.Errorf("storage %T does not record definition versions", m.storage)
+	}
+	source, err := m.Definition(from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := m.Definition(to)
+	if err != nil {
+		return nil, err
+	}
+	for place, targets := range mapping {
+		if !source.Place(place) {
+			return nil, fmt.Errorf("mapped place '%s' is not defined in %s", place, from)
+		}
+		for _, t := range targets {
+			if !target.Place(t) {
+				return nil, fmt.Errorf("place '%s' mapped from '%s' is not defined in %s", t, place, to)
+			}
+		}
+	}
+
+	ids := opts.IDs
+	if len(ids) == 0 {
+		if ids, err = storage.ListInstances(from); err != nil {
+			return nil, fmt.Errorf("failed to list instances: %w", err)
+		}
+	}
+
+	report := &MigrationReport{From: from, To: to, DryRun: opts.DryRun}
+	for _, id := range ids {
+		migration := InstanceMigration{ID: id}
+		migration.Err = func() error {
+			ref, places, context, err := storage.LoadVersionedState(id)
+			if err != nil {
+				return fmt.Errorf("failed to load workflow state: %w", err)
+			}
+			migration.From = places
+			if ref != from {
+				return fmt.Errorf("workflow is stored with definition %s", ref)
+			}
+			migrated, err := mapPlaces(places, mapping, target)
+			if err != nil {
+				return err
+			}
+			migration.To = migrated
+			if err := target.checkInvariants(migrated); err != nil {
+				return err
+			}
+			if opts.DryRun {
+				return nil
+			}
+			if err := storage.SaveVersionedState(id, to, migrated, context); err != nil {
+				return fmt.Errorf("failed to save workflow state: %w", err)
+			}
+			// Drop the cached instance so that it is reloaded with the new version
+			m.registry.RemoveWorkflow(id)
+			return nil
+		}()
+		if migration.Err != nil {
+			report.Failed = append(report.Failed, migration)
+		} else {
+			report.Migrated = append(report.Migrated, migration)
+		}
+	}
+	return report, nil
+}
+
+// mapPlaces rewrites a marking with the place mapping
+func mapPlaces(places []Place, mapping PlaceMapping, target *Definition) ([]Place, error) {
+	seen := make(map[Place]bool)
+	var result []Place
+	for _, place := range places {
+		targets, ok := mapping[place]
+		if !ok {
+			if !target.Place(place) {
+				return nil, fmt.Errorf("place '%s' has no mapping", place)
+			}
+			targets = []Place{place}
+		}
+		for _, t := range targets {
+			if !seen[t] {
+				seen[t] = true
+				result = append(result, t)
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("migrated marking is empty")
+	}
+	return result, nil
+}
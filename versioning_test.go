@@ -0,0 +1,213 @@
+package workflow_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/euphoria-laxis/workflow"
+)
+
+// versionedStorage implements the VersionedStorage interface in memory for testing
+type versionedStorage struct {
+	*memoryStorage
+	refs map[string]workflow.DefinitionRef
+}
+
+func newVersionedStorage() *versionedStorage {
+	return &versionedStorage{memoryStorage: newMemoryStorage(), refs: make(map[string]workflow.DefinitionRef)}
+}
+
+func (m *versionedStorage) LoadVersionedState(id string) (workflow.DefinitionRef, []workflow.Place, map[string]interface{}, error) {
+	places, ctx, err := m.LoadState(id)
+	return m.refs[id], places, ctx, err
+}
+
+func (m *versionedStorage) SaveVersionedState(id string, ref workflow.DefinitionRef, places []workflow.Place, context map[string]interface{}) error {
+	m.refs[id] = ref
+	return m.SaveState(id, places, context)
+}
+
+func (m *versionedStorage) ListInstances(ref workflow.DefinitionRef) ([]string, error) {
+	var ids []string
+	for id, r := range m.refs {
+		if r == ref {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+var (
+	articleV1 = workflow.NewBuilder("article").
+			Option(workflow.WithVersion("1")).
+			Place("draft", "review", "published").
+			Transition("submit").From("draft").To("review").
+			Transition("publish").From("review").To("published").
+			MustBuild()
+	articleV2 = workflow.NewBuilder("article").
+			Option(workflow.WithVersion("2")).
+			Place("draft", "legal_review", "editorial_review", "published").
+			Transition("submit").From("draft").To("legal_review", "editorial_review").
+			Transition("publish").From("legal_review", "editorial_review").To("published").
+			MustBuild()
+)
+
+func TestDefinition_Version(t *testing.T) {
+	unversioned, err := workflow.NewDefinition([]workflow.Place{"a"}, nil, workflow.WithName("plain"))
+	if err != nil {
+		t.Fatalf("NewDefinition() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		definition *workflow.Definition
+		want       workflow.DefinitionRef
+	}{
+		{
+			name:       "explicit version",
+			definition: articleV1,
+			want:       workflow.DefinitionRef{Name: "article", Version: "1"},
+		},
+		{
+			name:       "fingerprint as version",
+			definition: unversioned,
+			want:       workflow.DefinitionRef{Name: "plain", Version: unversioned.Fingerprint()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.definition.Ref(); got != tt.want {
+				t.Errorf("Ref() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_RegisterDefinition(t *testing.T) {
+	manager := workflow.NewManager(workflow.NewRegistry(), newVersionedStorage())
+
+	for _, def := range []*workflow.Definition{articleV1, articleV2, articleV1} {
+		if err := manager.RegisterDefinition(def); err != nil {
+			t.Fatalf("RegisterDefinition() error = %v", err)
+		}
+	}
+	if got := len(manager.Definitions("article")); got != 2 {
+		t.Errorf("expected 2 registered versions, got %d", got)
+	}
+	if _, err := manager.Definition(workflow.DefinitionRef{Name: "article", Version: "3"}); !errors.Is(err, workflow.ErrDefinitionNotFound) {
+		t.Errorf("expected ErrDefinitionNotFound, got %v", err)
+	}
+
+	conflicting, _ := workflow.NewDefinition([]workflow.Place{"draft"}, nil, workflow.WithName("article"), workflow.WithVersion("1"))
+	unnamed, _ := workflow.NewDefinition([]workflow.Place{"draft"}, nil)
+	tests := []struct {
+		name       string
+		definition *workflow.Definition
+	}{
+		{name: "different content under an existing version", definition: conflicting},
+		{name: "unnamed definition", definition: unnamed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.RegisterDefinition(tt.definition); err == nil {
+				t.Error("RegisterDefinition() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestManager_LoadWorkflowWithStoredVersion(t *testing.T) {
+	storage := newVersionedStorage()
+
+	manager := workflow.NewManager(workflow.NewRegistry(), storage)
+	if _, err := manager.CreateWorkflow("a1", articleV1, "draft"); err != nil {
+		t.Fatalf("CreateWorkflow() error = %v", err)
+	}
+	if storage.refs["a1"] != articleV1.Ref() {
+		t.Fatalf("stored ref = %v, want %v", storage.refs["a1"], articleV1.Ref())
+	}
+
+	// A fresh manager loads the instance with the version it was created with
+	manager = workflow.NewManager(workflow.NewRegistry(), storage)
+	manager.RegisterDefinition(articleV1)
+	wf, err := manager.LoadWorkflow("a1", articleV2)
+	if err != nil {
+		t.Fatalf("LoadWorkflow() error = %v", err)
+	}
+	if wf.Definition() != articleV1 {
+		t.Errorf("loaded with %v, want %v", wf.Definition().Ref(), articleV1.Ref())
+	}
+
+	// Unknown versions are reported
+	manager = workflow.NewManager(workflow.NewRegistry(), storage)
+	if _, err := manager.LoadWorkflow("a1", articleV2); !errors.Is(err, workflow.ErrDefinitionNotFound) {
+		t.Errorf("expected ErrDefinitionNotFound, got %v", err)
+	}
+}
+
+func TestManager_Migrate(t *testing.T) {
+	storage := newVersionedStorage()
+	manager := workflow.NewManager(workflow.NewRegistry(), storage)
+	manager.RegisterDefinition(articleV2)
+
+	for _, id := range []string{"a1", "a2", "a3"} {
+		wf, err := manager.CreateWorkflow(id, articleV1, "draft")
+		if err != nil {
+			t.Fatalf("CreateWorkflow() error = %v", err)
+		}
+		if id != "a1" {
+			if err := wf.Apply([]workflow.Place{"review"}); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			manager.SaveWorkflow(id, wf)
+		}
+	}
+	// a3 holds a place that the mapping cannot handle
+	storage.states["a3"] = []workflow.Place{"unknown"}
+
+	mapping := workflow.PlaceMapping{"review": {"legal_review", "editorial_review"}}
+
+	report, err := manager.Migrate(articleV1.Ref(), articleV2.Ref(), mapping, workflow.MigrationOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if len(report.Migrated) != 2 || len(report.Failed) != 1 || report.Failed[0].ID != "a3" {
+		t.Fatalf("unexpected dry run report: %+v", report)
+	}
+	if storage.refs["a2"] != articleV1.Ref() {
+		t.Error("dry run must not save anything")
+	}
+
+	report, err = manager.Migrate(articleV1.Ref(), articleV2.Ref(), mapping, workflow.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if len(report.Migrated) != 2 {
+		t.Fatalf("expected 2 migrated instances, got %+v", report)
+	}
+	if storage.refs["a2"] != articleV2.Ref() {
+		t.Errorf("stored ref = %v, want %v", storage.refs["a2"], articleV2.Ref())
+	}
+
+	wf, err := manager.GetWorkflow("a2", articleV1)
+	if err != nil {
+		t.Fatalf("GetWorkflow() error = %v", err)
+	}
+	if wf.Definition() != articleV2 {
+		t.Errorf("migrated workflow loaded with %v, want %v", wf.Definition().Ref(), articleV2.Ref())
+	}
+	if err := wf.Apply([]workflow.Place{"published"}); err != nil {
+		t.Errorf("Apply() on migrated workflow error = %v", err)
+	}
+
+	if _, err := manager.Migrate(articleV1.Ref(), articleV2.Ref(), workflow.PlaceMapping{"review": {"missing"}}, workflow.MigrationOptions{}); err == nil {
+		t.Error("expected an error for a mapping to an unknown place")
+	}
+	if _, err := workflow.NewManager(workflow.NewRegistry(), newMemoryStorage()).Migrate(articleV1.Ref(), articleV2.Ref(), mapping, workflow.MigrationOptions{}); err == nil {
+		t.Error("expected an error for a storage without versions")
+	}
+}
@@ -14,6 +14,7 @@ type Workflow struct {
 	marking      Marking
 	listeners    map[EventType][]interface{}
 	context      map[string]interface{}
+	markingStore MarkingStore
 
 	manager *Manager // pointer to manager, may be nil
 	mu      sync.RWMutex
@@ -33,8 +34,22 @@ type Storage interface {
 	DeleteState(id string) error
 }
 
-// NewWorkflow constructor
+// NewWorkflow constructor. The initial marking must satisfy the invariants
+// of the definition.
 func NewWorkflow(name string, definition *Definition, initialPlace Place) (*Workflow, error) {
+	wf, err := newWorkflow(name, definition, initialPlace)
+	if err != nil {
+		return nil, err
+	}
+	if err := definition.checkInvariants(wf.marking.Places()); err != nil {
+		return nil, err
+	}
+	return wf, nil
+}
+
+// newWorkflow creates a workflow without checking its initial marking, for
+// callers that replace the marking with places loaded from elsewhere
+func newWorkflow(name string, definition *Definition, initialPlace Place) (*Workflow, error) {
 	if name == "" {
 		return nil, fmt.Errorf("workflow name cannot be empty")
 	}
@@ -60,6 +75,38 @@ func NewWorkflow(name string, definition *Definition, initialPlace Place) (*Work
 	}, nil
 }
 
+// NewWorkflowWithMarkingStore creates a workflow whose marking is read from and
+// written back to the given marking store. If the store holds no places yet,
+// it is initialized with the initial place.
+func NewWorkflowWithMarkingStore(name string, definition *Definition, initialPlace Place, store MarkingStore) (*Workflow, error) {
+	if store == nil {
+		return nil, fmt.Errorf("marking store cannot be nil")
+	}
+
+	wf, err := newWorkflow(name, definition, initialPlace)
+	if err != nil {
+		return nil, err
+	}
+
+	places, err := store.Places()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read marking: %w", err)
+	}
+	if len(places) == 0 {
+		places = []Place{initialPlace}
+		if err := store.SetPlaces(places); err != nil {
+			return nil, fmt.Errorf("failed to write marking: %w", err)
+		}
+	}
+	if err := definition.checkStoredPlaces(places); err != nil {
+		return nil, err
+	}
+
+	wf.marking.SetPlaces(places)
+	wf.markingStore = store
+	return wf, nil
+}
+
 // Name returns the workflow name
 func (w *Workflow) Name() string {
 	w.mu.RLock()
@@ -110,6 +157,53 @@ func (w *Workflow) Context(key string) (interface{}, bool) {
 	return value, ok
 }
 
+// contextSnapshot returns a copy of the workflow context
+func (w *Workflow) contextSnapshot() map[string]interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return copyMetadata(w.context)
+}
+
+// MarkingStore returns the marking store backing the workflow, or nil
+func (w *Workflow) MarkingStore() MarkingStore {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.markingStore
+}
+
+// syncMarking reloads the marking from the marking store, if any
+func (w *Workflow) syncMarking() error {
+	w.mu.RLock()
+	store := w.markingStore
+	w.mu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	places, err := store.Places()
+	if err != nil {
+		return fmt.Errorf("failed to read marking: %w", err)
+	}
+	if err := w.definition.checkStoredPlaces(places); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.marking.SetPlaces(places)
+	w.mu.Unlock()
+	return nil
+}
+
+// checkStoredPlaces validates places read from a marking store against the
+// places and invariants of the definition
+func (d *Definition) checkStoredPlaces(places []Place) error {
+	for _, place := range places {
+		if !d.Place(place) {
+			return fmt.Errorf("place %s in marking store is not defined in the workflow", place)
+		}
+	}
+	return d.checkInvariants(places)
+}
+
 // SetManager sets the manager pointer for this workflow
 func (w *Workflow) SetManager(m *Manager) {
 	w.mu.Lock()
@@ -123,8 +217,8 @@ func (w *Workflow) fireEvent(event Event) error {
 	eventType := event.Type()
 
 	// 1. Definition listeners
-	if w.definition != nil && w.definition.Listeners != nil {
-		for _, l := range w.definition.Listeners[eventType] {
+	if w.definition != nil {
+		for _, l := range w.definition.eventListeners(eventType) {
 			switch eventType {
 			case EventGuard:
 				if gl, ok := l.(GuardEventListener); ok {
@@ -190,8 +284,38 @@ func (w *Workflow) Can(to []Place) error {
 	return w.CanWithContext(context.Background(), to)
 }
 
-// CanWithContext checks if transition to target places is possible with a context
+// CanWithContext checks if transition to target places is possible with a context.
+// The transition input payload is not validated, see CanWithPayload.
 func (w *Workflow) CanWithContext(ctx context.Context, to []Place) error {
+	return w.can(ctx, leadsTo(to), to, nil, false)
+}
+
+// CanWithPayload checks if transition to target places is possible with the given input payload
+func (w *Workflow) CanWithPayload(ctx context.Context, to []Place, payload map[string]interface{}) error {
+	return w.can(ctx, leadsTo(to), to, payload, true)
+}
+
+// transitionMatch selects the transition to check or apply
+type transitionMatch func(t *Transition) bool
+
+// leadsTo matches the transitions leading to the target places, in order
+func leadsTo(to []Place) transitionMatch {
+	return func(t *Transition) bool {
+		if len(t.To()) != len(to) {
+			return false
+		}
+		for i := range t.To() {
+			if t.To()[i] != to[i] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// can checks if the first enabled transition selected by match is possible,
+// optionally validating the payload
+func (w *Workflow) can(ctx context.Context, match transitionMatch, to []Place, payload map[string]interface{}, validatePayload bool) error {
 	w.mu.RLock()
 	definition := w.definition
 	w.mu.RUnlock()
@@ -213,34 +337,41 @@ func (w *Workflow) CanWithContext(ctx context.Context, to []Place) error {
 		return err
 	}
 
-	// Check if any enabled transition leads to the target places
+	// Check the first enabled transition selected by match
 	for _, t := range enabled {
-		if len(t.To()) == len(to) {
-			matches := true
-			for i := range t.To() {
-				if t.To()[i] != to[i] {
-					matches = false
-					break
+		if match(&t) {
+			// Validate the input payload before any guard runs
+			if validatePayload {
+				if err = t.validatePayload(payload); err != nil {
+					return err
 				}
 			}
-			if matches {
-				// Create guard event for validation
-				event := NewGuardEvent(ctx, &t, w.marking.Places(), to, w)
 
-				// First, validate transition constraints
-				if err = t.validate(event); err != nil {
-					return err
-				}
+			// Check the context requirements of the target places
+			w.mu.RLock()
+			err = definition.checkRequirements(&t, w.context)
+			w.mu.RUnlock()
+			if err != nil {
+				return err
+			}
 
-				// Then, fire guard event listeners
-				if err = w.fireEvent(event); err != nil {
-					return err
-				}
-				if event.IsBlocking() {
-					return ErrTransitionNotAllowed
-				}
-				return nil
+			// Create guard event for validation
+			event := NewGuardEvent(ctx, &t, w.marking.Places(), to, w)
+			event.payload = payload
+
+			// First, validate transition constraints
+			if err = t.validate(event); err != nil {
+				return err
+			}
+
+			// Then, fire guard event listeners
+			if err = w.fireEvent(event); err != nil {
+				return err
+			}
+			if event.IsBlocking() {
+				return ErrTransitionNotAllowed
 			}
+			return nil
 		}
 	}
 
@@ -254,6 +385,18 @@ func (w *Workflow) Apply(targetPlaces []Place) error {
 
 // ApplyWithContext applies a transition to the workflow with a context
 func (w *Workflow) ApplyWithContext(ctx context.Context, targetPlaces []Place) error {
+	return w.ApplyWithPayload(ctx, targetPlaces, nil)
+}
+
+// ApplyWithPayload applies a transition to the workflow with a context and an input payload.
+// The payload is validated against the transition input schema before guards run
+// and is exposed to listeners through Event.Payload.
+func (w *Workflow) ApplyWithPayload(ctx context.Context, targetPlaces []Place, payload map[string]interface{}) error {
+	return w.apply(ctx, leadsTo(targetPlaces), targetPlaces, payload)
+}
+
+// apply applies the first enabled transition selected by match
+func (w *Workflow) apply(ctx context.Context, match transitionMatch, targetPlaces []Place, payload map[string]interface{}) error {
 	// Validate target places first (before locking)
 	for _, place := range targetPlaces {
 		if !w.definition.Place(place) {
@@ -262,7 +405,7 @@ func (w *Workflow) ApplyWithContext(ctx context.Context, targetPlaces []Place) e
 	}
 
 	// Check if the transition is allowed (before locking)
-	if err := w.CanWithContext(ctx, targetPlaces); err != nil {
+	if err := w.can(ctx, match, targetPlaces, payload, true); err != nil {
 		return err
 	}
 
@@ -275,7 +418,7 @@ func (w *Workflow) ApplyWithContext(ctx context.Context, targetPlaces []Place) e
 	currentPlaces := w.marking.Places()
 
 	// Check each transition
-	for _, t := range w.definition.Transitions {
+	for _, t := range w.definition.transitions {
 		// Check if all 'from' places are in current places
 		allFromPlacesPresent := true
 		for _, fromPlace := range t.From() {
@@ -292,20 +435,12 @@ func (w *Workflow) ApplyWithContext(ctx context.Context, targetPlaces []Place) e
 			}
 		}
 
-		// Check if all 'to' places match
-		if allFromPlacesPresent && len(t.To()) == len(targetPlaces) {
-			matches := true
-			for i := range t.To() {
-				if t.To()[i] != targetPlaces[i] {
-					matches = false
-					break
-				}
-			}
-			if matches {
-				from = t.From()
-				transition = &t
-				break
-			}
+		// Check if the transition is the one to apply
+		if allFromPlacesPresent && match(&t) {
+			from = t.From()
+			c := t.clone()
+			transition = &c
+			break
 		}
 	}
 
@@ -316,6 +451,7 @@ func (w *Workflow) ApplyWithContext(ctx context.Context, targetPlaces []Place) e
 	// Fire before transition event (unlock before calling listeners)
 	w.mu.Unlock()
 	event := NewEvent(ctx, EventBeforeTransition, transition, from, targetPlaces, w)
+	event.payload = payload
 	if err := w.fireEvent(event); err != nil {
 		w.mu.Lock()
 		return err
@@ -339,11 +475,25 @@ func (w *Workflow) ApplyWithContext(ctx context.Context, targetPlaces []Place) e
 
 	// Add the target places to marking
 	newPlaces = append(newPlaces, targetPlaces...)
+
+	// Reject markings that violate an invariant, leaving the marking untouched
+	if err := w.definition.checkInvariants(newPlaces); err != nil {
+		return err
+	}
 	w.marking.SetPlaces(newPlaces)
 
+	// Write the marking back to the subject, restoring it on failure
+	if w.markingStore != nil {
+		if err := w.markingStore.SetPlaces(newPlaces); err != nil {
+			w.marking.SetPlaces(currentPlaces)
+			return fmt.Errorf("failed to write marking: %w", err)
+		}
+	}
+
 	// Fire after transition event (unlock before calling listeners)
 	w.mu.Unlock()
 	event = NewEvent(ctx, EventAfterTransition, transition, from, targetPlaces, w)
+	event.payload = payload
 	if err := w.fireEvent(event); err != nil {
 		w.mu.Lock()
 		return err
@@ -355,13 +505,16 @@ func (w *Workflow) ApplyWithContext(ctx context.Context, targetPlaces []Place) e
 
 // EnabledTransitions returns all transitions that can be applied in the current place
 func (w *Workflow) EnabledTransitions() ([]Transition, error) {
+	if err := w.syncMarking(); err != nil {
+		return nil, err
+	}
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 	var enabled []Transition
 	currentPlaces := w.marking.Places()
 
 	// Check each transition
-	for _, trans := range w.definition.Transitions {
+	for _, trans := range w.definition.transitions {
 		// Check if all 'from' places are in current places
 		allFromPlacesPresent := true
 		for _, fromPlace := range trans.From() {
@@ -379,7 +532,7 @@ func (w *Workflow) EnabledTransitions() ([]Transition, error) {
 		}
 
 		if allFromPlacesPresent {
-			enabled = append(enabled, trans)
+			enabled = append(enabled, trans.clone())
 		}
 	}
 	return enabled, nil
@@ -387,6 +540,8 @@ func (w *Workflow) EnabledTransitions() ([]Transition, error) {
 
 // CurrentPlaces returns the current places of the workflow
 func (w *Workflow) CurrentPlaces() []Place {
+	// A failed read keeps the last known marking
+	_ = w.syncMarking()
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 	return w.marking.Places()
@@ -401,6 +556,8 @@ func (w *Workflow) Definition() *Definition {
 
 // Marking returns the current marking of the workflow
 func (w *Workflow) Marking() Marking {
+	// A failed read keeps the last known marking
+	_ = w.syncMarking()
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 	return w.marking
@@ -413,6 +570,14 @@ func (w *Workflow) SetMarking(marking Marking) error {
 	if marking == nil {
 		return fmt.Errorf("marking cannot be nil")
 	}
+	if err := w.definition.checkInvariants(marking.Places()); err != nil {
+		return err
+	}
+	if w.markingStore != nil {
+		if err := w.markingStore.SetPlaces(marking.Places()); err != nil {
+			return fmt.Errorf("failed to write marking: %w", err)
+		}
+	}
 	w.marking = marking
 	return nil
 }
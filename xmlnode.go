@@ -0,0 +1,85 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlNode is an XML element with the position of its start tag, used by the
+// importers to report errors by line
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlNode
+	text     string
+	line     int
+	column   int
+}
+
+// parseXML reads the root element of an XML document. Attributes and elements
+// are keyed by their local name.
+func parseXML(data []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line, column := dec.InputPos()
+			return nil, &LoadError{Line: line, Column: column, Err: err}
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			line, column := dec.InputPos()
+			node := &xmlNode{name: t.Name.Local, attrs: make(map[string]string), line: line, column: column}
+			for _, attr := range t.Attr {
+				if attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" {
+					node.attrs[attr.Name.Local] = attr.Value
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, &LoadError{Err: fmt.Errorf("empty document")}
+	}
+	return root, nil
+}
+
+// errorf returns a *LoadError located at the element
+func (n *xmlNode) errorf(format string, args ...interface{}) error {
+	return &LoadError{Line: n.line, Column: n.column, Err: fmt.Errorf(format, args...)}
+}
+
+// child returns the first child element with the given name
+func (n *xmlNode) child(name string) *xmlNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// content returns the text of the element without surrounding whitespace
+func (n *xmlNode) content() string {
+	return strings.TrimSpace(n.text)
+}